@@ -0,0 +1,168 @@
+package scim
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/elimity-com/scim/schema"
+)
+
+// projectAttributes returns a copy of the given values that only contains the attributes that need to be returned.
+// Attributes are only returned if they are part of the included selection, unless the selection is nil. The requested
+// flag indicates that the parent attribute was requested explicitly, which also returns attributes that are only
+// returned on request.
+func projectAttributes(values map[string]interface{}, attributes schema.Attributes, extensions []schema.Schema, include attributeSelection, requested bool, exclude attributeSelection) map[string]interface{} {
+	projection := make(map[string]interface{})
+	for k, v := range values {
+		var (
+			name          = strings.ToLower(k)
+			returned      = "default"
+			subAttributes schema.Attributes
+			subExtensions bool
+		)
+		if attr, ok := attributes.ContainsAttribute(k); ok {
+			returned = attr.Returned()
+			subAttributes = attr.SubAttributes()
+		} else {
+			for _, extension := range extensions {
+				if strings.EqualFold(k, extension.ID) {
+					subAttributes = extension.Attributes
+					subExtensions = true
+					break
+				}
+			}
+		}
+		if name == "schemas" || name == schema.CommonAttributeID {
+			returned = "always"
+		}
+
+		includeSub, included := include[name]
+		excludeSub, excluded := exclude[name]
+
+		switch returned {
+		case "never":
+			continue
+		case "always":
+		case "request":
+			if !requested && !included {
+				continue
+			}
+		default:
+			if include != nil && !included || excluded && excludeSub == nil {
+				continue
+			}
+		}
+
+		if subAttributes == nil && !subExtensions {
+			projection[k] = v
+			continue
+		}
+		if !included {
+			includeSub = nil
+		}
+		value, ok := projectValue(v, subAttributes, includeSub, requested || included && includeSub == nil, excludeSub)
+		if ok {
+			projection[k] = value
+		}
+	}
+	return projection
+}
+
+// projectValue projects the given value of a complex attribute. Returns false if none of the selected sub-attributes
+// are present.
+func projectValue(value interface{}, subAttributes schema.Attributes, include attributeSelection, requested bool, exclude attributeSelection) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		projection := projectAttributes(v, subAttributes, nil, include, requested, exclude)
+		return projection, include == nil || len(projection) != 0
+	case ResourceAttributes:
+		return projectValue(map[string]interface{}(v), subAttributes, include, requested, exclude)
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			if projection, ok := projectValue(e, subAttributes, include, requested, exclude); ok {
+				values = append(values, projection)
+			}
+		}
+		return values, include == nil || len(values) != 0
+	default:
+		return value, true
+	}
+}
+
+// attributeProjection selects the attributes of a resource that are returned in a response based on the "attributes"
+// and "excludedAttributes" parameters and the "returned" characteristic of the attributes, as defined in RFC 7644,
+// Section 3.9.
+type attributeProjection struct {
+	attributes schema.Attributes
+	extensions []schema.Schema
+	include    attributeSelection
+	exclude    attributeSelection
+}
+
+// newAttributeProjection creates a projection for the given resource type. Both lists of attributes contain paths
+// in the format of the "attributes" and "excludedAttributes" query parameters. The list of included attributes is nil
+// if all the attributes that are returned by default are requested.
+func newAttributeProjection(r *http.Request, t ResourceType, attributes, excludedAttributes []string) attributeProjection {
+	p := attributeProjection{
+		attributes: append(schema.CommonAttributes(), t.schemaWithCommon().Attributes...),
+		extensions: t.getSchemaExtensions(r),
+		exclude:    make(attributeSelection),
+	}
+	if len(attributes) != 0 {
+		p.include = make(attributeSelection)
+		for _, path := range attributes {
+			p.include.add(p.split(t.Schema.ID, path)...)
+		}
+	}
+	for _, path := range excludedAttributes {
+		p.exclude.add(p.split(t.Schema.ID, path)...)
+	}
+	return p
+}
+
+// project returns a copy of the given resource attributes that only contains the attributes that need to be returned.
+func (p attributeProjection) project(attributes ResourceAttributes) ResourceAttributes {
+	return projectAttributes(attributes, p.attributes, p.extensions, p.include, false, p.exclude)
+}
+
+// split splits the given attribute path in the names of the (sub-)attributes it refers to. The first element is the
+// URI of the schema extension if the attribute is part of an extension.
+func (p attributeProjection) split(id, path string) []string {
+	path = strings.TrimSpace(path)
+	for _, extension := range p.extensions {
+		if strings.EqualFold(path, extension.ID) {
+			return []string{extension.ID}
+		}
+		if prefix := extension.ID + ":"; len(path) > len(prefix) && strings.EqualFold(path[:len(prefix)], prefix) {
+			return append([]string{extension.ID}, strings.Split(path[len(prefix):], ".")...)
+		}
+	}
+	if prefix := id + ":"; len(path) > len(prefix) && strings.EqualFold(path[:len(prefix)], prefix) {
+		path = path[len(prefix):]
+	}
+	return strings.Split(path, ".")
+}
+
+// attributeSelection is a set of (case insensitive) attribute names, mapped to the selected sub-attributes. A nil set of
+// sub-attributes indicates that the attribute is selected as a whole.
+type attributeSelection map[string]attributeSelection
+
+// add adds the attribute with the given path to the selection.
+func (s attributeSelection) add(path ...string) {
+	name := strings.ToLower(path[0])
+	selection, ok := s[name]
+	if len(path) == 1 {
+		s[name] = nil
+		return
+	}
+	if ok && selection == nil {
+		// The attribute is already selected as a whole.
+		return
+	}
+	if selection == nil {
+		selection = make(attributeSelection)
+		s[name] = selection
+	}
+	selection.add(path[1:]...)
+}
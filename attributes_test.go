@@ -0,0 +1,157 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elimity-com/scim/schema"
+)
+
+func TestAttributeProjection(t *testing.T) {
+	extension := schema.Schema{
+		ID: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+		Attributes: []schema.CoreAttribute{
+			schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+				Name: "employeeNumber",
+			})),
+			schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+				Name: "costCenter",
+			})),
+		},
+	}
+	resourceType := ResourceType{
+		Schema: schema.Schema{
+			ID: "urn:ietf:params:scim:schemas:core:2.0:User",
+			Attributes: []schema.CoreAttribute{
+				schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+					Name:     "userName",
+					Returned: schema.AttributeReturnedAlways(),
+				})),
+				schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+					Name:     "password",
+					Returned: schema.AttributeReturnedNever(),
+				})),
+				schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+					Name:     "secret",
+					Returned: schema.AttributeReturnedRequest(),
+				})),
+				schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+					Name: "displayName",
+				})),
+				schema.ComplexCoreAttribute(schema.ComplexParams{
+					Name:        "emails",
+					MultiValued: true,
+					SubAttributes: []schema.SimpleParams{
+						schema.SimpleStringParams(schema.StringParams{
+							Name: "value",
+						}),
+						schema.SimpleStringParams(schema.StringParams{
+							Name: "type",
+						}),
+						schema.SimpleStringParams(schema.StringParams{
+							Name:     "token",
+							Returned: schema.AttributeReturnedRequest(),
+						}),
+					},
+				}),
+			},
+		},
+		SchemaExtensions: []SchemaExtension{
+			{Schema: extension},
+		},
+	}
+	newResource := func() ResourceAttributes {
+		return ResourceAttributes{
+			"id":          "0001",
+			"schemas":     []interface{}{resourceType.Schema.ID, extension.ID},
+			"userName":    "alice",
+			"password":    "hunter2",
+			"secret":      "s3cr3t",
+			"displayName": "Alice",
+			"emails": []interface{}{
+				map[string]interface{}{"value": "alice@example.com", "type": "work", "token": "x"},
+			},
+			extension.ID: map[string]interface{}{
+				"employeeNumber": "42",
+				"costCenter":     "4130",
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		name               string
+		attributes         []string
+		excludedAttributes []string
+		expected           string
+	}{
+		{
+			name:     "default",
+			expected: `{"displayName":"Alice","emails":[{"type":"work","value":"alice@example.com"}],"id":"0001","schemas":["urn:ietf:params:scim:schemas:core:2.0:User","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"costCenter":"4130","employeeNumber":"42"},"userName":"alice"}`,
+		},
+		{
+			name:       "attributes",
+			attributes: []string{"DisplayName", "secret", "password", "emails.value"},
+			expected:   `{"displayName":"Alice","emails":[{"value":"alice@example.com"}],"id":"0001","schemas":["urn:ietf:params:scim:schemas:core:2.0:User","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],"secret":"s3cr3t","userName":"alice"}`,
+		},
+		{
+			name:       "attributes with request sub-attribute",
+			attributes: []string{"emails"},
+			expected:   `{"emails":[{"token":"x","type":"work","value":"alice@example.com"}],"id":"0001","schemas":["urn:ietf:params:scim:schemas:core:2.0:User","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],"userName":"alice"}`,
+		},
+		{
+			name:       "attributes with extension",
+			attributes: []string{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber", "urn:ietf:params:scim:schemas:core:2.0:User:displayName"},
+			expected:   `{"displayName":"Alice","id":"0001","schemas":["urn:ietf:params:scim:schemas:core:2.0:User","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+		},
+		{
+			name:               "excludedAttributes",
+			excludedAttributes: []string{"userName", "emails.type", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User", "id"},
+			expected:           `{"displayName":"Alice","emails":[{"value":"alice@example.com"}],"id":"0001","schemas":["urn:ietf:params:scim:schemas:core:2.0:User","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],"userName":"alice"}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			projection := newAttributeProjection(nil, resourceType, test.attributes, test.excludedAttributes)
+			raw, err := json.Marshal(projection.project(newResource()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, test.expected, string(raw))
+		})
+	}
+}
+
+func TestServerResourceGetHandlerAttributes(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/Users/0001?attributes=userName", nil)
+	rr := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var resource map[string]interface{}
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resource))
+	assertEqual(t, "test01", resource["userName"])
+	assertEqual(t, "0001", resource["id"])
+	assertNil(t, resource["externalId"], "externalId")
+	assertNil(t, resource["meta"], "meta")
+}
+
+func TestServerResourcesGetHandlerExcludedAttributes(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/Users?excludedAttributes=externalId,meta", nil)
+	rr := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var response listResponse
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assertLen(t, response.Resources, 20)
+	for _, r := range response.Resources {
+		resource, ok := r.(map[string]interface{})
+		assertTypeOk(t, ok, "object")
+		assertNotNil(t, resource["userName"], "userName")
+		assertNil(t, resource["externalId"], "externalId")
+		assertNil(t, resource["meta"], "meta")
+	}
+}
@@ -0,0 +1,179 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/elimity-com/scim/errors"
+)
+
+// bulkIDPrefix is the prefix of a value that references a resource created within the same bulk request.
+const bulkIDPrefix = "bulkId:"
+
+// bulkErrorResponse creates the result of a bulk operation that failed before it could be dispatched.
+func bulkErrorResponse(op bulkOperation, scimErr errors.ScimError) bulkOperationResponse {
+	raw, _ := json.Marshal(scimErr)
+	return bulkOperationResponse{
+		Method:   op.Method,
+		BulkID:   op.BulkID,
+		Status:   fmt.Sprint(scimErr.Status),
+		Response: raw,
+	}
+}
+
+// bulkIDReferences returns all the bulk identifiers referenced by given value, e.g., "bulkId:qwerty".
+func bulkIDReferences(value interface{}) []string {
+	var references []string
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, bulkIDPrefix) {
+			references = append(references, strings.TrimPrefix(v, bulkIDPrefix))
+		}
+	case []interface{}:
+		for _, e := range v {
+			references = append(references, bulkIDReferences(e)...)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			references = append(references, bulkIDReferences(e)...)
+		}
+	}
+	return references
+}
+
+// isBulkOperation checks whether the given request is an operation of a bulk request, see withBulkOperation.
+func isBulkOperation(r *http.Request) bool {
+	_, ok := r.Context().Value(bulkOperationKey{}).(bool)
+	return ok
+}
+
+// resolveBulkIDs replaces all bulk identifier references within given value with the identifiers of the resources
+// they refer to.
+func resolveBulkIDs(value interface{}, resolved map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		if id, ok := resolved[strings.TrimPrefix(v, bulkIDPrefix)]; ok && strings.HasPrefix(v, bulkIDPrefix) {
+			return id
+		}
+		return v
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = resolveBulkIDs(e, resolved)
+		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			values[k] = resolveBulkIDs(e, resolved)
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+// withBulkOperation returns a shallow copy of the given request that is marked as an operation of a bulk request. Bulk
+// requests are not served for such requests, so the operations of a bulk request can not be bulk requests themselves.
+func withBulkOperation(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), bulkOperationKey{}, true))
+}
+
+// bulkOperation represents a single operation within a bulk request.
+type bulkOperation struct {
+	// Method is the HTTP method of the current operation, i.e. "POST", "PUT", "PATCH" or "DELETE".
+	Method string
+	// BulkID is the transient identifier of a newly created resource, unique within a bulk request and created by the
+	// client. REQUIRED when "method" is "POST".
+	BulkID string
+	// Version is the current resource version. Used to determine whether the resource has been modified.
+	Version string
+	// Path is the resource's relative path to the SCIM service provider's root, e.g., "/Users" or "/Groups/{id}".
+	Path string
+	// Data is the resource data as it would appear for a single POST, PUT, or PATCH operation.
+	Data interface{}
+}
+
+// references returns the bulk identifiers referenced within the path and the data of the operation.
+func (op bulkOperation) references() []string {
+	var references []string
+	for _, segment := range strings.Split(op.Path, "/") {
+		references = append(references, bulkIDReferences(segment)...)
+	}
+	return append(references, bulkIDReferences(op.Data)...)
+}
+
+// resolve returns the path and data of the operation in which all bulk identifier references are replaced.
+func (op bulkOperation) resolve(resolved map[string]string) (string, interface{}) {
+	segments := strings.Split(op.Path, "/")
+	for i, segment := range segments {
+		segments[i] = resolveBulkIDs(segment, resolved).(string)
+	}
+	return strings.Join(segments, "/"), resolveBulkIDs(op.Data, resolved)
+}
+
+// bulkOperationKey is the context key that marks a request as an operation of a bulk request.
+type bulkOperationKey struct{}
+
+// bulkOperationResponse represents the result of a single operation within a bulk request.
+type bulkOperationResponse struct {
+	Location string          `json:"location,omitempty"`
+	Method   string          `json:"method"`
+	BulkID   string          `json:"bulkId,omitempty"`
+	Version  string          `json:"version,omitempty"`
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// bulkRequest represents a bulk request as defined in RFC 7644, Section 3.7.
+type bulkRequest struct {
+	Schemas []string
+	// FailOnErrors is the number of errors that the service provider will accept before the operation is terminated.
+	// A value of "0" indicates that all operations are to be processed.
+	FailOnErrors int
+	// Operations defines the operations within a bulk job.
+	Operations []bulkOperation
+}
+
+// bulkResponse identifies a bulk response.
+type bulkResponse struct {
+	// Operations contains the results of the processed operations.
+	Operations []bulkOperationResponse
+}
+
+func (b bulkResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"schemas":    []string{"urn:ietf:params:scim:api:messages:2.0:BulkResponse"},
+		"Operations": b.Operations,
+	})
+}
+
+// bulkResponseWriter captures the response of a single bulk operation.
+type bulkResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBulkResponseWriter() *bulkResponseWriter {
+	return &bulkResponseWriter{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (w *bulkResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bulkResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bulkResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
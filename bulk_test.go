@@ -0,0 +1,280 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elimity-com/scim/errors"
+)
+
+type testBulkResponse struct {
+	Schemas    []string
+	Operations []struct {
+		Location string
+		Method   string
+		BulkID   string
+		Version  string
+		Status   string
+		Response map[string]interface{}
+	}
+}
+
+func TestServerBulkHandlerBulkIDReferences(t *testing.T) {
+	s := newTestBulkServer()
+	req := httptest.NewRequest(http.MethodPost, "/Bulk", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+		"Operations": [
+			{
+				"method": "POST",
+				"path": "/Groups",
+				"bulkId": "group",
+				"data": {
+					"displayName": "Tour Guides",
+					"members": [{"type": "User", "value": "bulkId:user"}]
+				}
+			},
+			{
+				"method": "POST",
+				"path": "/Users",
+				"bulkId": "user",
+				"data": {"userName": "Alice"}
+			}
+		]
+	}`))
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var response testBulkResponse
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assertEqualStrings(t, []string{"urn:ietf:params:scim:api:messages:2.0:BulkResponse"}, response.Schemas)
+	assertLen(t, response.Operations, 2)
+
+	group, user := response.Operations[0], response.Operations[1]
+	assertEqual(t, "group", group.BulkID)
+	assertEqual(t, "201", group.Status)
+	assertEqual(t, "user", user.BulkID)
+	assertEqual(t, "201", user.Status)
+
+	// The location is absolute, based on the scheme and host of the bulk request.
+	assertStringStartsWith(t, "http://example.com/v2/Groups/", group.Location)
+	assertStringStartsWith(t, "http://example.com/v2/Users/", user.Location)
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, group.Location, nil))
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var resource map[string]interface{}
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resource))
+	members, ok := resource["members"].([]interface{})
+	assertTypeOk(t, ok, "array")
+	assertLen(t, members, 1)
+	assertEqual(t, user.Location, "http://example.com/v2/Users/"+members[0].(map[string]interface{})["value"].(string))
+}
+
+func TestServerBulkHandlerCircularReferences(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/Bulk", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+		"Operations": [
+			{
+				"method": "POST",
+				"path": "/Groups",
+				"bulkId": "a",
+				"data": {"displayName": "A", "members": [{"type": "Group", "value": "bulkId:b"}]}
+			},
+			{
+				"method": "POST",
+				"path": "/Groups",
+				"bulkId": "b",
+				"data": {"displayName": "B", "members": [{"type": "Group", "value": "bulkId:a"}]}
+			},
+			{
+				"method": "PATCH",
+				"path": "/Groups/bulkId:unknown",
+				"data": {"Operations": [{"op": "remove", "path": "members"}]}
+			}
+		]
+	}`))
+	rr := httptest.NewRecorder()
+	newTestBulkServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var response testBulkResponse
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assertLen(t, response.Operations, 3)
+	for _, op := range response.Operations {
+		assertEqual(t, "409", op.Status)
+		assertEqual(t, "invalidValue", op.Response["scimType"])
+	}
+}
+
+func TestServerBulkHandlerDuplicateBulkIDs(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/Bulk", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+		"Operations": [
+			{"method": "POST", "path": "/Users", "bulkId": "user", "data": {"userName": "Alice"}},
+			{"method": "POST", "path": "/Users", "bulkId": "user", "data": {"userName": "Bob"}}
+		]
+	}`))
+	rr := httptest.NewRecorder()
+	s := newTestBulkServer()
+	users := len(s.ResourceTypes[0].Handler.(testResourceHandler).data)
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+	var scimErr errors.ScimError
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &scimErr))
+	assertEqual(t, errors.ScimErrorInvalidValue.ScimType, scimErr.ScimType)
+	// None of the operations is performed.
+	assertEqual(t, users, len(s.ResourceTypes[0].Handler.(testResourceHandler).data))
+}
+
+func TestServerBulkHandlerFailOnErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/Bulk", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+		"failOnErrors": 1,
+		"Operations": [
+			{"method": "DELETE", "path": "/Users/9999"},
+			{"method": "DELETE", "path": "/Users/0001"}
+		]
+	}`))
+	rr := httptest.NewRecorder()
+	newTestBulkServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var response testBulkResponse
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assertLen(t, response.Operations, 1)
+	assertEqual(t, "404", response.Operations[0].Status)
+	assertEqual(t, "404", response.Operations[0].Response["status"])
+}
+
+func TestServerBulkHandlerLimits(t *testing.T) {
+	s := newTestBulkServer()
+	s.Config.MaxBulkOperations = 1
+	s.Config.MaxBulkPayloadSize = 256
+
+	for _, test := range []struct {
+		name string
+		body string
+	}{
+		{
+			name: "too many operations",
+			body: `{"Operations": [{"method": "DELETE", "path": "/Users/0001"}, {"method": "DELETE", "path": "/Users/0002"}]}`,
+		},
+		{
+			name: "payload too large",
+			body: `{"Operations": [{"method": "POST", "path": "/Users", "bulkId": "x", "data": {"userName": "` + strings.Repeat("x", 256) + `"}}]}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/Bulk", strings.NewReader(test.body)))
+			assertEqualStatusCode(t, http.StatusRequestEntityTooLarge, rr.Code)
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ServiceProviderConfig", nil))
+
+	var config map[string]interface{}
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &config))
+	bulk, ok := config["bulk"].(map[string]interface{})
+	assertTypeOk(t, ok, "object")
+	assertEqual(t, true, bulk["supported"])
+	assertEqual(t, float64(1), bulk["maxOperations"])
+	assertEqual(t, float64(256), bulk["maxPayloadSize"])
+}
+
+func TestServerBulkHandlerNested(t *testing.T) {
+	s := newTestBulkServer()
+	s.Prefix = "/v2"
+	nested := `{"Operations": [{"method": "DELETE", "path": "/Users/0001"}]}`
+	for _, path := range []string{"/Bulk", "/Bulk?x=1", "/v2/Bulk", "/v2/Bulk?x=1"} {
+		t.Run(path, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"Operations": []interface{}{map[string]interface{}{
+					"method": "POST", "path": path, "bulkId": "nested", "data": json.RawMessage(nested),
+				}},
+			})
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v2/Bulk", bytes.NewReader(body)))
+			assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+			var response testBulkResponse
+			assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assertLen(t, response.Operations, 1)
+			assertEqual(t, "400", response.Operations[0].Status)
+		})
+	}
+
+	// Bulk requests are not served for operations of a bulk request, regardless of their path.
+	rr := httptest.NewRecorder()
+	req := withBulkOperation(httptest.NewRequest(http.MethodPost, "/v2/Bulk", strings.NewReader(nested)))
+	s.ServeHTTP(rr, req)
+	assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestServerBulkHandlerPaths(t *testing.T) {
+	s := newTestBulkServer()
+	s.Prefix = "/v2"
+	for _, test := range []struct {
+		method string
+		path   string
+		status string
+	}{
+		{http.MethodPost, "/Users", "201"},
+		{http.MethodPost, "/v2/Users", "201"},
+		{http.MethodPost, "/Users/0001", "400"},
+		{http.MethodPost, "/.search", "400"},
+		{http.MethodPost, "/Users/.search", "400"},
+		{http.MethodPost, "/Me", "400"},
+		{http.MethodPost, "/Schemas", "400"},
+		{http.MethodPost, "Users", "400"},
+		{http.MethodPut, "/Users", "400"},
+		{http.MethodPut, "/Me", "400"},
+		{http.MethodDelete, "/Users/", "400"},
+		{http.MethodDelete, "/Users/0001/x", "400"},
+		{http.MethodDelete, "/Unknown/0001", "400"},
+		{http.MethodDelete, "/Users/0001", "204"},
+	} {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"Operations": []interface{}{map[string]interface{}{
+					"method": test.method, "path": test.path, "bulkId": "op", "data": map[string]interface{}{
+						"userName": "Alice",
+					},
+				}},
+			})
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v2/Bulk", bytes.NewReader(body)))
+			assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+			var response testBulkResponse
+			assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assertLen(t, response.Operations, 1)
+			assertEqual(t, test.status, response.Operations[0].Status)
+		})
+	}
+}
+
+func TestServerBulkHandlerNotSupported(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/Bulk", strings.NewReader(`{"Operations": []}`))
+	rr := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusNotImplemented, rr.Code)
+}
+
+func newTestBulkServer() Server {
+	s := newTestServer()
+	s.Config.SupportBulk = true
+	return s
+}
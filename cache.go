@@ -0,0 +1,66 @@
+package scim
+
+import (
+	"sync"
+	"time"
+)
+
+// cache is a cache of values by key, of which the values expire after a duration. Values are kept until they are
+// invalidated if the duration is zero. It is safe for concurrent use.
+type cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// newCache returns an empty cache of which the values expire after the given duration.
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// get returns the value with the given key, if it is cached and not expired.
+func (c *cache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || (c.ttl != 0 && !c.now().Before(entry.expires)) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// invalidate removes the value with the given key.
+func (c *cache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// invalidateAll removes all values.
+func (c *cache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+}
+
+// set caches the given value with the given key.
+func (c *cache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{
+		value:   value,
+		expires: c.now().Add(c.ttl),
+	}
+}
+
+// cacheEntry is a cached value and the time it expires.
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
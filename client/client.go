@@ -0,0 +1,256 @@
+// Package client provides a client for SCIM service providers, e.g., to provision users and groups to the SCIM API of
+// another vendor.
+//
+// Resources are represented as scim.Resource values, of which the attributes do not include the "schemas", "id",
+// "externalId" and "meta" attributes. Error responses of the service provider are returned as errors.ScimError
+// values.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elimity-com/scim"
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/optional"
+	"github.com/elimity-com/scim/schema"
+)
+
+// decodeResource converts the given json representation of a resource to a resource.
+func decodeResource(raw map[string]interface{}) scim.Resource {
+	attributes := make(scim.ResourceAttributes, len(raw))
+	for k, v := range raw {
+		attributes[k] = v
+	}
+
+	var resource scim.Resource
+	if id, ok := attributes["id"].(string); ok {
+		resource.ID = id
+	}
+	if externalID, ok := attributes["externalId"].(string); ok {
+		resource.ExternalID = optional.NewString(externalID)
+	}
+	if meta, ok := attributes["meta"].(map[string]interface{}); ok {
+		resource.Meta.Created = parseTime(meta["created"])
+		resource.Meta.LastModified = parseTime(meta["lastModified"])
+		if version, ok := meta["version"].(string); ok {
+			resource.Meta.Version = version
+		}
+	}
+	for _, k := range []string{"schemas", "id", "externalId", "meta"} {
+		delete(attributes, k)
+	}
+	resource.Attributes = attributes
+	return resource
+}
+
+// errorResponse returns the error of the given response. Responses that do not contain a SCIM error are converted to a
+// SCIM error with the status of the response.
+func errorResponse(resp *http.Response) error {
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed reading error response: %w", err)
+	}
+
+	var scimErr errors.ScimError
+	if err := json.Unmarshal(raw, &scimErr); err != nil || scimErr.Status == 0 {
+		return errors.ScimError{
+			Detail: strings.TrimSpace(string(raw)),
+			Status: resp.StatusCode,
+		}
+	}
+	return scimErr
+}
+
+// parseTime returns the time of the given "DateTime" value, or nil if it is not a valid time.
+func parseTime(value interface{}) *time.Time {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// resourcePath returns the path of the resource with the given identifier at the given endpoint.
+func resourcePath(endpoint, id string) string {
+	return strings.TrimSuffix(endpoint, "/") + "/" + url.PathEscape(id)
+}
+
+// Client is a client for the SCIM API of a service provider. Endpoints are relative to the base URL, e.g., "/Users".
+type Client struct {
+	// BaseURL is the URL of the SCIM API of the service provider, e.g., "https://example.com/scim/v2".
+	BaseURL string
+	// HTTPClient is used to send the requests. The default HTTP client is used if it is nil.
+	HTTPClient *http.Client
+	// Header contains the headers that are added to every request, e.g., the "Authorization" header.
+	Header http.Header
+}
+
+// Create creates a resource with the given attributes at the given endpoint and returns the created resource. The
+// attributes should include the "schemas" attribute.
+func (c Client) Create(ctx context.Context, endpoint string, attributes scim.ResourceAttributes) (scim.Resource, error) {
+	var raw map[string]interface{}
+	if err := c.do(ctx, http.MethodPost, endpoint, nil, attributes, &raw); err != nil {
+		return scim.Resource{}, err
+	}
+	return decodeResource(raw), nil
+}
+
+// Delete deletes the resource with the given identifier at the given endpoint.
+func (c Client) Delete(ctx context.Context, endpoint, id string) error {
+	return c.do(ctx, http.MethodDelete, resourcePath(endpoint, id), nil, nil, nil)
+}
+
+// Get returns the resource with the given identifier at the given endpoint.
+func (c Client) Get(ctx context.Context, endpoint, id string) (scim.Resource, error) {
+	var raw map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, resourcePath(endpoint, id), nil, nil, &raw); err != nil {
+		return scim.Resource{}, err
+	}
+	return decodeResource(raw), nil
+}
+
+// List returns an iterator over the resources at the given endpoint that match the given parameters. The pages of
+// resources are requested as the iterator advances.
+func (c Client) List(ctx context.Context, endpoint string, params ListParams) *Iterator {
+	startIndex := params.StartIndex
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	return &Iterator{
+		client:     c,
+		ctx:        ctx,
+		endpoint:   endpoint,
+		params:     params,
+		startIndex: startIndex,
+	}
+}
+
+// Patch applies the given PATCH request to the resource with the given identifier at the given endpoint. It returns
+// the patched resource, or a resource with only the identifier if the service provider does not return the resource.
+func (c Client) Patch(ctx context.Context, endpoint, id string, req scim.PatchRequest) (scim.Resource, error) {
+	var raw map[string]interface{}
+	if err := c.do(ctx, http.MethodPatch, resourcePath(endpoint, id), nil, req, &raw); err != nil {
+		return scim.Resource{}, err
+	}
+	if raw == nil {
+		return scim.Resource{ID: id}, nil
+	}
+	return decodeResource(raw), nil
+}
+
+// Replace replaces the attributes of the resource with the given identifier at the given endpoint and returns the
+// replaced resource.
+func (c Client) Replace(ctx context.Context, endpoint, id string, attributes scim.ResourceAttributes) (scim.Resource, error) {
+	var raw map[string]interface{}
+	if err := c.do(ctx, http.MethodPut, resourcePath(endpoint, id), nil, attributes, &raw); err != nil {
+		return scim.Resource{}, err
+	}
+	return decodeResource(raw), nil
+}
+
+// ResourceTypes returns the json representations of the resource types of the service provider.
+func (c Client) ResourceTypes(ctx context.Context) ([]map[string]interface{}, error) {
+	var resp listResponse
+	if err := c.do(ctx, http.MethodGet, "/ResourceTypes", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Resources, nil
+}
+
+// Schemas returns the schemas of the service provider. A schema.DefinitionError is returned if the service provider
+// returns an invalid schema definition.
+func (c Client) Schemas(ctx context.Context) ([]schema.Schema, error) {
+	var resp struct {
+		Resources []schema.Schema
+	}
+	if err := c.do(ctx, http.MethodGet, "/Schemas", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Resources, nil
+}
+
+// ServiceProviderConfig returns the json representation of the configuration of the service provider.
+func (c Client) ServiceProviderConfig(ctx context.Context) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/ServiceProviderConfig", nil, nil, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// do sends a request with the given method to the given path, relative to the base URL, with the given query and the
+// json representation of the given body. The json representation of the response is decoded into the given value, if
+// the response has a body. Numbers are decoded as json.Number values.
+func (c Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, v interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed marshaling request body: %w", err)
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	for k, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(k, value)
+		}
+	}
+	req.Header.Set("Accept", "application/scim+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/scim+json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errorResponse(resp)
+	}
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	d := json.NewDecoder(resp.Body)
+	d.UseNumber()
+	if err := d.Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("failed decoding response body: %w", err)
+	}
+	return nil
+}
+
+// listResponse is a response to a query, see scim.Page.
+type listResponse struct {
+	TotalResults int
+	ItemsPerPage int
+	StartIndex   int
+	Resources    []map[string]interface{}
+}
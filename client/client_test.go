@@ -0,0 +1,223 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elimity-com/scim"
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/memstore"
+	"github.com/elimity-com/scim/optional"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestClient(t *testing.T) {
+	c, closeServer := newTestClient()
+	defer closeServer()
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, "/Users", scim.ResourceAttributes{
+		"schemas":    []string{schema.UserSchema},
+		"userName":   "alice",
+		"externalId": "a1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.ExternalID.Value() != "a1" || created.Meta.Created == nil || created.Meta.Version == "" {
+		t.Errorf("unexpected created resource: %v", created)
+	}
+	if _, ok := created.Attributes["id"]; ok {
+		t.Error("the attributes should not contain the id")
+	}
+
+	got, err := c.Get(ctx, "/Users", created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Attributes["userName"] != "alice" {
+		t.Errorf("unexpected user name: %v", got.Attributes["userName"])
+	}
+
+	replaced, err := c.Replace(ctx, "/Users", created.ID, scim.ResourceAttributes{
+		"userName":    "alice",
+		"displayName": "Alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.Attributes["displayName"] != "Alice" {
+		t.Errorf("unexpected display name: %v", replaced.Attributes["displayName"])
+	}
+
+	path, _ := filter.ParsePath([]byte("displayName"))
+	patched, err := c.Patch(ctx, "/Users", created.ID, scim.PatchRequest{
+		Operations: []scim.PatchOperation{
+			{Op: scim.PatchOperationReplace, Path: &path, Value: "Alice Smith"},
+			{Op: scim.PatchOperationAdd, Value: map[string]interface{}{"nickName": "Al"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Attributes["displayName"] != "Alice Smith" || patched.Attributes["nickName"] != "Al" {
+		t.Errorf("unexpected patched resource: %v", patched.Attributes)
+	}
+
+	if err := c.Delete(ctx, "/Users", created.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Get(ctx, "/Users", created.ID)
+	scimErr, ok := err.(errors.ScimError)
+	if !ok || scimErr.Status != http.StatusNotFound {
+		t.Errorf("expected a 404 scim error, got %v", err)
+	}
+
+	_, err = c.Create(ctx, "/Users", scim.ResourceAttributes{})
+	if scimErr, ok := err.(errors.ScimError); !ok || scimErr.ScimType != errors.ScimTypeInvalidValue {
+		t.Errorf("expected an invalid value scim error, got %v", err)
+	}
+}
+
+func TestClientDiscovery(t *testing.T) {
+	c, closeServer := newTestClient()
+	defer closeServer()
+	ctx := context.Background()
+
+	config, err := c.ServiceProviderConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patch, _ := config["patch"].(map[string]interface{}); patch["supported"] != true {
+		t.Errorf("expected patch to be supported: %v", config)
+	}
+
+	resourceTypes, err := c.ResourceTypes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resourceTypes) != 1 || resourceTypes[0]["endpoint"] != "/Users" {
+		t.Errorf("unexpected resource types: %v", resourceTypes)
+	}
+
+	schemas, err := c.Schemas(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schemas) != 1 || schemas[0].ID != schema.UserSchema {
+		t.Errorf("unexpected schemas: %v", schemas)
+	}
+	if _, ok := schemas[0].Attributes.ContainsAttribute("userName"); !ok {
+		t.Errorf("expected the schema to contain the userName attribute: %v", schemas[0].Attributes)
+	}
+}
+
+func TestClientList(t *testing.T) {
+	c, closeServer := newTestClient()
+	defer closeServer()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.Create(ctx, "/Users", scim.ResourceAttributes{
+			"userName": fmt.Sprintf("user%d", i),
+			"active":   i%2 == 0,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expr, _ := filter.ParseFilter([]byte("active eq true"))
+	for _, test := range []struct {
+		name      string
+		params    ListParams
+		userNames []string
+		total     int
+	}{
+		{"all", ListParams{}, []string{"user0", "user1", "user2", "user3", "user4"}, 5},
+		{"pages", ListParams{Count: 2}, []string{"user0", "user1", "user2", "user3", "user4"}, 5},
+		{"start index", ListParams{Count: 2, StartIndex: 4}, []string{"user3", "user4"}, 5},
+		{"filter", ListParams{Count: 1, Filter: expr}, []string{"user0", "user2", "user4"}, 3},
+		{"sort", ListParams{SortBy: "userName", SortOrder: scim.SortOrderDescending}, []string{"user4", "user3", "user2", "user1", "user0"}, 5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var userNames []string
+			it := c.List(ctx, "/Users", test.params)
+			for it.Next() {
+				userNames = append(userNames, it.Resource().Attributes["userName"].(string))
+			}
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(userNames) != fmt.Sprint(test.userNames) {
+				t.Errorf("expected %v, got %v", test.userNames, userNames)
+			}
+			if it.TotalResults() != test.total {
+				t.Errorf("unexpected total results: %d", it.TotalResults())
+			}
+		})
+	}
+
+	it := c.List(ctx, "/Groups", ListParams{})
+	if it.Next() || it.Err() == nil {
+		t.Error("expected an error for an unknown endpoint")
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		status int
+		body   string
+		err    errors.ScimError
+	}{
+		{"scim error", http.StatusConflict, `{"scimType": "uniqueness", "status": "409"}`, errors.ScimError{
+			ScimType: errors.ScimTypeUniqueness,
+			Status:   http.StatusConflict,
+		}},
+		{"numeric status", http.StatusNotFound, `{"detail": "Not found.", "status": 404}`, errors.ScimError{
+			Detail: "Not found.",
+			Status: http.StatusNotFound,
+		}},
+		{"no scim error", http.StatusBadGateway, "Bad Gateway\n", errors.ScimError{
+			Detail: "Bad Gateway",
+			Status: http.StatusBadGateway,
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			}))
+			defer ts.Close()
+
+			err := Client{BaseURL: ts.URL}.Delete(context.Background(), "/Users", "0001")
+			if err != test.err {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+// newTestClient returns a client for a server that serves users, and a function that closes the server.
+func newTestClient() (Client, func()) {
+	users := scim.ResourceType{
+		ID:       optional.NewString("User"),
+		Name:     "User",
+		Endpoint: "/Users",
+		Schema:   schema.CoreUserSchema(),
+	}
+	users.Handler = memstore.New(users)
+	ts := httptest.NewServer(scim.Server{
+		Config: scim.ServiceProviderConfig{
+			SupportFiltering: true,
+			SupportPatch:     true,
+			SupportSort:      true,
+		},
+		ResourceTypes: []scim.ResourceType{users},
+	})
+	return Client{BaseURL: ts.URL}, ts.Close
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/elimity-com/scim"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// Iterator iterates over the resources of a list request, requesting the next page when the resources of the current
+// page are exhausted.
+//
+//	it := c.List(ctx, "/Users", client.ListParams{Count: 100})
+//	for it.Next() {
+//		resource := it.Resource()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	client   Client
+	ctx      context.Context
+	endpoint string
+	params   ListParams
+
+	// startIndex is the 1-based index of the first resource of the next page.
+	startIndex   int
+	totalResults int
+	resources    []scim.Resource
+	resource     scim.Resource
+	done         bool
+	err          error
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Next advances the iterator to the next resource, which is then available through Resource. It returns false when
+// there are no more resources or an error occurred.
+func (it *Iterator) Next() bool {
+	for len(it.resources) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.resource, it.resources = it.resources[0], it.resources[1:]
+	return true
+}
+
+// Resource returns the current resource.
+func (it *Iterator) Resource() scim.Resource {
+	return it.resource
+}
+
+// TotalResults returns the total number of resources that match the list request, as reported by the service
+// provider with the last page. It is zero until the first page is requested.
+func (it *Iterator) TotalResults() int {
+	return it.totalResults
+}
+
+// fetch requests the next page of resources.
+func (it *Iterator) fetch() {
+	query := it.params.query()
+	query.Set("startIndex", strconv.Itoa(it.startIndex))
+
+	var resp listResponse
+	if err := it.client.do(it.ctx, http.MethodGet, it.endpoint, query, nil, &resp); err != nil {
+		it.err = err
+		return
+	}
+
+	it.totalResults = resp.TotalResults
+	for _, raw := range resp.Resources {
+		it.resources = append(it.resources, decodeResource(raw))
+	}
+	it.startIndex += len(resp.Resources)
+	// Service providers may return less resources than requested, so the iteration only stops when a page is empty or
+	// all resources are returned.
+	if len(resp.Resources) == 0 || it.startIndex > resp.TotalResults {
+		it.done = true
+	}
+}
+
+// ListParams are the parameters of a list request.
+type ListParams struct {
+	// Attributes are the names of the attributes to return, overriding the attributes that are returned by default.
+	Attributes []string
+	// Count is the maximum number of resources per page. The service provider decides the number of resources per page
+	// if it is zero.
+	Count int
+	// ExcludedAttributes are the names of the attributes to remove from the attributes that are returned by default.
+	ExcludedAttributes []string
+	// Filter is the filter that the resources must match. All resources are returned if it is nil.
+	Filter filter.Expression
+	// SortBy is the attribute path of the attribute by which the resources are sorted, e.g., "name.familyName".
+	SortBy string
+	// SortOrder is the order in which the resources are sorted by the SortBy attribute.
+	SortOrder scim.SortOrder
+	// StartIndex is the 1-based index of the first resource to return. The iteration starts at the first resource if
+	// it is zero.
+	StartIndex int
+}
+
+// query returns the query parameters of the list request, except for the start index.
+func (p ListParams) query() url.Values {
+	query := make(url.Values)
+	if len(p.Attributes) != 0 {
+		query.Set("attributes", strings.Join(p.Attributes, ","))
+	}
+	if p.Count > 0 {
+		query.Set("count", strconv.Itoa(p.Count))
+	}
+	if len(p.ExcludedAttributes) != 0 {
+		query.Set("excludedAttributes", strings.Join(p.ExcludedAttributes, ","))
+	}
+	if p.Filter != nil {
+		query.Set("filter", fmt.Sprint(p.Filter))
+	}
+	if p.SortBy != "" {
+		query.Set("sortBy", p.SortBy)
+	}
+	if p.SortOrder != "" {
+		query.Set("sortOrder", string(p.SortOrder))
+	}
+	return query
+}
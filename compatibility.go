@@ -0,0 +1,92 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/elimity-com/scim/schema"
+)
+
+// withCompatibility returns a shallow copy of the given request of which the context holds the given compatibility.
+func withCompatibility(r *http.Request, compatibility Compatibility) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), compatibilityKey{}, compatibility))
+}
+
+// Compatibility switches known deviations of clients from the SCIM specification on or off. The zero value accepts
+// none of them, so the server strictly follows the RFCs. The profiles returned by CompatibilityAzureAD and
+// CompatibilityOkta switch on the deviations of those clients.
+type Compatibility struct {
+	// AddFilteredValues adds a new value to a multi-valued attribute if the value filter of an "add" operation does not
+	// match any values, instead of returning a "noTarget" error. The new value contains the attributes that are compared
+	// with "eq" in the filter, e.g., an "add" operation with the path `emails[type eq "work"].value` adds the value
+	// {"type": "work", "value": ...} if the resource has no work email. Azure AD relies on this behavior.
+	AddFilteredValues bool
+	// BooleanStrings accepts the strings "true", "True", "false" and "False" as boolean values. Azure AD sends booleans
+	// as strings in PATCH operations.
+	BooleanStrings bool
+	// IgnoreReadOnlyValues ignores read-only attributes, e.g., the "id", within the value of an "add" or "replace"
+	// operation without a path, instead of rejecting the operation. Okta includes the id of a group if it is renamed.
+	IgnoreReadOnlyValues bool
+	// ManagerStrings accepts a string as value of the complex "manager" attribute, instead of a complex value with a
+	// "value" sub-attribute. Azure AD sends the identifier of the manager as a string.
+	ManagerStrings bool
+	// NullPatchValues accepts "add" and "replace" operations of which the value is null, which removes the targeted
+	// attribute. Azure AD clears attributes this way.
+	NullPatchValues bool
+	// RemoveValues only removes the given values of a multi-valued attribute if a "remove" operation without a value
+	// filter has a value, e.g., {"op": "remove", "path": "members", "value": [{"value": "2819c223"}]}. By default, all
+	// values of the attribute are removed. Azure AD removes members of groups this way.
+	RemoveValues bool
+	// RootHealthCheck responds with 200 "OK" to requests for the root of the server, which are used to test the
+	// connectivity. Azure AD expects this response.
+	RootHealthCheck bool
+}
+
+// CompatibilityAzureAD returns the compatibility profile of Azure AD (Microsoft Entra ID).
+func CompatibilityAzureAD() Compatibility {
+	return Compatibility{
+		AddFilteredValues: true,
+		BooleanStrings:    true,
+		ManagerStrings:    true,
+		NullPatchValues:   true,
+		RemoveValues:      true,
+		RootHealthCheck:   true,
+	}
+}
+
+// CompatibilityFromContext returns the compatibility of the server that serves the request with the given context.
+// Resource handlers can use it to apply PATCH requests, see Compatibility.ApplyPatch. The zero value is returned if
+// the context does not belong to a request served by a server.
+func CompatibilityFromContext(ctx context.Context) Compatibility {
+	compatibility, _ := ctx.Value(compatibilityKey{}).(Compatibility)
+	return compatibility
+}
+
+// CompatibilityOkta returns the compatibility profile of Okta.
+func CompatibilityOkta() Compatibility {
+	return Compatibility{
+		IgnoreReadOnlyValues: true,
+		RemoveValues:         true,
+	}
+}
+
+// ApplyPatch applies the operations of the given PATCH request like the ApplyPatch function, but also accepts the
+// deviations of the PATCH semantics that are switched on, i.e., AddFilteredValues and RemoveValues.
+func (c Compatibility) ApplyPatch(s schema.Schema, extensions []schema.Schema, attributes ResourceAttributes, req PatchRequest) (ResourceAttributes, bool, error) {
+	return patcher{
+		compatibility: c,
+		schema:        s,
+		extensions:    extensions,
+	}.applyPatch(attributes, req)
+}
+
+// quirks returns the deviations that are accepted when attribute values are validated.
+func (c Compatibility) quirks() schema.Quirks {
+	return schema.Quirks{
+		BooleanStrings: c.BooleanStrings,
+		ManagerStrings: c.ManagerStrings,
+	}
+}
+
+// compatibilityKey is the context key of the compatibility of the server that serves a request.
+type compatibilityKey struct{}
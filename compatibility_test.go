@@ -0,0 +1,153 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elimity-com/scim/schema"
+)
+
+func TestCompatibilityApplyPatch(t *testing.T) {
+	user := ResourceAttributes{
+		"userName": "alice",
+		"emails": []interface{}{
+			map[string]interface{}{"value": "alice@home.com", "type": "home"},
+		},
+	}
+	group := ResourceAttributes{
+		"displayName": "Tour Guides",
+		"members": []interface{}{
+			map[string]interface{}{"value": "0001"},
+			map[string]interface{}{"value": "0002"},
+			map[string]interface{}{"value": "0003"},
+		},
+	}
+
+	for _, test := range []struct {
+		name          string
+		compatibility Compatibility
+		schema        schema.Schema
+		attributes    ResourceAttributes
+		op            PatchOperation
+		expected      string
+	}{
+		{
+			name:          "add filtered values",
+			compatibility: Compatibility{AddFilteredValues: true},
+			schema:        schema.CoreUserSchema(),
+			attributes:    user,
+			op:            newTestPatchOperation(t, "add", `emails[type eq "work"].value`, "alice@work.com"),
+			expected:      `{"emails":[{"type":"home","value":"alice@home.com"},{"type":"work","value":"alice@work.com"}],"userName":"alice"}`,
+		},
+		{
+			name:          "remove values",
+			compatibility: Compatibility{RemoveValues: true},
+			schema:        schema.CoreGroupSchema(),
+			attributes:    group,
+			op: newTestPatchOperation(t, "remove", "members", []interface{}{
+				map[string]interface{}{"value": "0001"},
+				map[string]interface{}{"value": "0003"},
+			}),
+			expected: `{"displayName":"Tour Guides","members":[{"value":"0002"}]}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := PatchRequest{Operations: []PatchOperation{test.op}}
+
+			// The deviation is not accepted by default.
+			patched, _, err := ApplyPatch(test.schema, nil, test.attributes, req)
+			raw, _ := json.Marshal(patched)
+			assertTrue(t, err != nil || string(raw) != test.expected)
+
+			patched, changed, err := test.compatibility.ApplyPatch(test.schema, nil, test.attributes, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertTrue(t, changed)
+			raw, _ = json.Marshal(patched)
+			assertEqual(t, test.expected, string(raw))
+		})
+	}
+}
+
+func TestCompatibilityFromContext(t *testing.T) {
+	assertEqual(t, Compatibility{}, CompatibilityFromContext(context.Background()))
+
+	r := withCompatibility(httptest.NewRequest(http.MethodGet, "/", nil), CompatibilityOkta())
+	assertEqual(t, CompatibilityOkta(), CompatibilityFromContext(r.Context()))
+}
+
+func TestCompatibilityIgnoreReadOnlyValues(t *testing.T) {
+	resourceType := newTestServer().ResourceTypes[0]
+	body := `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "value": {"id": "0001", "displayName": "Alice"}}]
+	}`
+
+	r := httptest.NewRequest(http.MethodPatch, "/Users/0001", strings.NewReader(body))
+	req, scimErr := resourceType.validatePatch(r)
+	assertTrue(t, scimErr == nil)
+	assertEqual(t, "0001", req.Operations[0].Value.(map[string]interface{})["id"])
+
+	r = httptest.NewRequest(http.MethodPatch, "/Users/0001", strings.NewReader(body))
+	r = withCompatibility(r, Compatibility{IgnoreReadOnlyValues: true})
+	req, scimErr = resourceType.validatePatch(r)
+	assertTrue(t, scimErr == nil)
+	value := req.Operations[0].Value.(map[string]interface{})
+	_, ok := value["id"]
+	assertTrue(t, !ok)
+	assertEqual(t, "Alice", value["displayName"])
+}
+
+func TestServerCompatibility(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		compatibility Compatibility
+		method        string
+		target        string
+		body          string
+	}{
+		{
+			name:          "boolean strings",
+			compatibility: Compatibility{BooleanStrings: true},
+			method:        http.MethodPatch,
+			target:        "/v2/Users/0001",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+				"Operations": [{"op": "replace", "path": "active", "value": "False"}]
+			}`,
+		},
+		{
+			name:          "null patch values",
+			compatibility: Compatibility{NullPatchValues: true},
+			method:        http.MethodPatch,
+			target:        "/v2/Users/0001",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+				"Operations": [{"op": "replace", "path": "displayName", "value": null}]
+			}`,
+		},
+		{
+			name:          "root health check",
+			compatibility: Compatibility{RootHealthCheck: true},
+			method:        http.MethodGet,
+			target:        "/v2/",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer()
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+			assertTrue(t, rr.Code >= http.StatusBadRequest)
+
+			s.Compatibility = test.compatibility
+			rr = httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+			assertEqualStatusCode(t, http.StatusOK, rr.Code)
+		})
+	}
+}
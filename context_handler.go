@@ -0,0 +1,156 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// ifMatchVersion returns the version of the given "If-Match" header. A single (weak) entity-tag is unquoted, e.g.,
+// `W/"v1"` results in "v1". Other values, like "*" or a list of entity-tags, are returned unchanged.
+func ifMatchVersion(header string) string {
+	header = strings.TrimSpace(header)
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return header
+	}
+	if version := tag[1 : len(tag)-1]; !strings.Contains(version, `"`) {
+		return version
+	}
+	return header
+}
+
+// withSubject returns a shallow copy of the given request with the identifier of the resource of the authenticated
+// subject in its context, as resolved for a request to the "/Me" endpoint.
+func withSubject(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), subjectKey{}, id))
+}
+
+// ContextResourceHandler represents a set of callback methods that connect the SCIM server with a provider of a certain
+// resource, like a ResourceHandler. Instead of the HTTP request, the methods receive a context and the information of
+// the request that is relevant to the handler. This makes it possible to call the handler from other sources than HTTP
+// requests, e.g., a bulk processor or a message queue consumer.
+type ContextResourceHandler interface {
+	// Create stores given attributes. Returns a resource with the attributes that are stored and a (new) unique identifier.
+	Create(ctx context.Context, info RequestInfo, attributes ResourceAttributes) (Resource, error)
+	// Get returns the resource corresponding with the given identifier.
+	Get(ctx context.Context, info RequestInfo, id string) (Resource, error)
+	// GetAll returns a paginated list of resources.
+	GetAll(ctx context.Context, info RequestInfo, params ListRequestParams) (Page, error)
+	// Replace replaces ALL existing attributes of the resource with given identifier. Given attributes that are empty
+	// are to be deleted. Returns a resource with the attributes that are stored.
+	Replace(ctx context.Context, info RequestInfo, id string, attributes ResourceAttributes) (Resource, error)
+	// Delete removes the resource with corresponding ID.
+	Delete(ctx context.Context, info RequestInfo, id string) error
+	// Patch update one or more attributes of a SCIM resource using a sequence of operations to "add", "remove", or
+	// "replace" values. If you return no Resource.Attributes, a 204 No Content status code will be returned. See
+	// ResourceHandler.Patch for more information.
+	Patch(ctx context.Context, info RequestInfo, id string, request PatchRequest) (Resource, error)
+}
+
+// ContextUniquenessChecker is the UniquenessChecker of a ContextResourceHandler. It is an optional interface that can
+// be implemented by a ContextResourceHandler to enforce the uniqueness of attributes, see UniquenessChecker.
+type ContextUniquenessChecker interface {
+	// LookupUnique returns the identifiers of the resources that have the given value for the given attribute, see
+	// UniquenessChecker.LookupUnique.
+	LookupUnique(ctx context.Context, info RequestInfo, attribute UniqueAttribute, value interface{}) ([]string, error)
+}
+
+// ContextVersioner is the Versioner of a ContextResourceHandler. It is an optional interface that can be implemented
+// by a ContextResourceHandler to retrieve the current version of a resource without retrieving the resource itself,
+// see Versioner.
+type ContextVersioner interface {
+	// Version returns the current version of the resource with the given identifier.
+	Version(ctx context.Context, info RequestInfo, id string) (string, error)
+}
+
+// RequestInfo contains the information of a request that is relevant to a ContextResourceHandler.
+type RequestInfo struct {
+	// ResourceType is the name of the resource type the request targets.
+	ResourceType string
+	// Tenant identifies the tenant the request is served for. It is empty for servers that serve a single tenant.
+	Tenant string
+	// Subject is the identifier of the resource of the authenticated subject, as resolved by the subject resolver of
+	// the server, for requests to the "/Me" endpoint. It is empty for requests to other endpoints.
+	Subject string
+	// Attributes is a list of attribute names that are requested to be returned.
+	Attributes []string
+	// ExcludedAttributes is a list of attribute names that are requested to be removed from the response.
+	ExcludedAttributes []string
+	// IfMatch is the version of the "If-Match" header, e.g., "v1" for `W/"v1"`. It is empty if the header is absent.
+	// The precondition is already evaluated by the server before the handler is called.
+	IfMatch string
+}
+
+// newRequestInfo constructs the request info of the given HTTP request to a resource of the given resource type.
+func newRequestInfo(r *http.Request, resourceType string) RequestInfo {
+	info := RequestInfo{
+		ResourceType:       resourceType,
+		Tenant:             TenantFromContext(r.Context()),
+		Attributes:         getAttributes(r.URL.Query(), "attributes"),
+		ExcludedAttributes: getAttributes(r.URL.Query(), "excludedAttributes"),
+		IfMatch:            ifMatchVersion(r.Header.Get("If-Match")),
+	}
+	if id, ok := r.Context().Value(subjectKey{}).(string); ok {
+		info.Subject = id
+	}
+	return info
+}
+
+// contextResourceHandler adapts a ContextResourceHandler to a ResourceHandler.
+type contextResourceHandler struct {
+	handler      ContextResourceHandler
+	resourceType string
+}
+
+func (h contextResourceHandler) Create(r *http.Request, attributes ResourceAttributes) (Resource, error) {
+	return h.handler.Create(r.Context(), newRequestInfo(r, h.resourceType), attributes)
+}
+
+func (h contextResourceHandler) Delete(r *http.Request, id string) error {
+	return h.handler.Delete(r.Context(), newRequestInfo(r, h.resourceType), id)
+}
+
+func (h contextResourceHandler) Get(r *http.Request, id string) (Resource, error) {
+	return h.handler.Get(r.Context(), newRequestInfo(r, h.resourceType), id)
+}
+
+func (h contextResourceHandler) GetAll(r *http.Request, params ListRequestParams) (Page, error) {
+	// The attributes of search requests are part of the request body.
+	info := newRequestInfo(r, h.resourceType)
+	info.Attributes = params.Attributes
+	info.ExcludedAttributes = params.ExcludedAttributes
+	return h.handler.GetAll(r.Context(), info, params)
+}
+
+func (h contextResourceHandler) Patch(r *http.Request, id string, request PatchRequest) (Resource, error) {
+	return h.handler.Patch(r.Context(), newRequestInfo(r, h.resourceType), id, request)
+}
+
+func (h contextResourceHandler) Replace(r *http.Request, id string, attributes ResourceAttributes) (Resource, error) {
+	return h.handler.Replace(r.Context(), newRequestInfo(r, h.resourceType), id, attributes)
+}
+
+// contextUniquenessChecker adapts a ContextUniquenessChecker to a UniquenessChecker.
+type contextUniquenessChecker struct {
+	checker      ContextUniquenessChecker
+	resourceType string
+}
+
+func (c contextUniquenessChecker) LookupUnique(r *http.Request, attribute UniqueAttribute, value interface{}) ([]string, error) {
+	return c.checker.LookupUnique(r.Context(), newRequestInfo(r, c.resourceType), attribute, value)
+}
+
+// contextVersioner adapts a ContextVersioner to a Versioner.
+type contextVersioner struct {
+	versioner    ContextVersioner
+	resourceType string
+}
+
+func (v contextVersioner) Version(r *http.Request, id string) (string, error) {
+	return v.versioner.Version(r.Context(), newRequestInfo(r, v.resourceType), id)
+}
+
+// subjectKey is the context key of the identifier of the resource of the authenticated subject of a request to the
+// "/Me" endpoint.
+type subjectKey struct{}
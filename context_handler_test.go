@@ -0,0 +1,181 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	for header, expected := range map[string]string{
+		"":             "",
+		`"v1"`:         "v1",
+		`W/"v1"`:       "v1",
+		`*`:            "*",
+		`"v1", "v2"`:   `"v1", "v2"`,
+		`v1`:           "v1",
+		`"v1"quote"`:   `"v1"quote"`,
+		` W/"v1" `:     "v1",
+		`W/"3694e05e"`: "3694e05e",
+	} {
+		assertEqual(t, expected, ifMatchVersion(header))
+	}
+}
+
+func TestServerContextHandler(t *testing.T) {
+	s := newTestMeServer()
+	handler := &testContextResourceHandler{
+		handler: newTestResourceHandler(),
+	}
+	s.ResourceTypes[0].Handler = nil
+	s.ResourceTypes[0].ContextHandler = handler
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/Users/0001?attributes=userName,name", nil)
+	req.Header.Set("Authorization", "0002")
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+	assertLen(t, handler.infos, 1)
+	info := handler.infos[0]
+	assertEqual(t, "User", info.ResourceType)
+	// The subject is only resolved for requests to the "/Me" endpoint.
+	assertEqual(t, "", info.Subject)
+	assertEqualStrings(t, []string{"userName", "name"}, info.Attributes)
+
+	req = httptest.NewRequest(http.MethodPut, "/v2/Users/0001", strings.NewReader(`{"userName": "other"}`))
+	req.Header.Set("If-Match", `"v1"`)
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+	// The precondition is evaluated with Get, followed by Replace.
+	assertLen(t, handler.infos, 3)
+	info = handler.infos[2]
+	assertEqual(t, "v1", info.IfMatch)
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/Me", nil)
+	req.Header.Set("Authorization", "0001")
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+	assertLen(t, handler.infos, 4)
+	assertEqual(t, "0001", handler.infos[3].Subject)
+}
+
+func TestServerContextHandlerOptionalInterfaces(t *testing.T) {
+	s := newTestServer()
+	handler := testContextOptionalHandler{
+		testContextResourceHandler: &testContextResourceHandler{
+			handler: s.ResourceTypes[0].Handler,
+		},
+		uniqueness: testUniquenessHandler{
+			testResourceHandler: s.ResourceTypes[0].Handler.(testResourceHandler),
+		},
+		version: "v2",
+	}
+	s.ResourceTypes[0].Handler = nil
+	s.ResourceTypes[0].ContextHandler = handler
+
+	for _, test := range []struct {
+		name    string
+		method  string
+		target  string
+		ifMatch string
+		body    string
+		status  int
+	}{
+		{"precondition failed", http.MethodPut, "/v2/Users/0001", `"v1"`, `{"userName": "other"}`, http.StatusPreconditionFailed},
+		{"precondition", http.MethodPut, "/v2/Users/0001", `"v2"`, `{"userName": "other"}`, http.StatusOK},
+		{"create", http.MethodPost, "/v2/Users", "", `{"userName": "TEST02"}`, http.StatusConflict},
+		{"patch", http.MethodPatch, "/v2/Users/0003", "", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "userName", "value": "test02"}]
+		}`, http.StatusConflict},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+			assertEqualStatusCode(t, test.status, rr.Code)
+		})
+	}
+}
+
+func TestServerContextHandlerSearch(t *testing.T) {
+	handler := &testContextResourceHandler{
+		handler: newTestResourceHandler(),
+	}
+	s := newTestServer()
+	s.ResourceTypes[0].Handler = nil
+	s.ResourceTypes[0].ContextHandler = handler
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v2/Users/.search", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],
+		"excludedAttributes": ["name"]
+	}`)))
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+	assertLen(t, handler.infos, 1)
+	assertEqualStrings(t, []string{"name"}, handler.infos[0].ExcludedAttributes)
+}
+
+// testContextResourceHandler records the request info of each call and forwards the call to the wrapped handler.
+type testContextResourceHandler struct {
+	handler ResourceHandler
+	infos   []RequestInfo
+}
+
+func (h *testContextResourceHandler) Create(_ context.Context, info RequestInfo, attributes ResourceAttributes) (Resource, error) {
+	h.infos = append(h.infos, info)
+	return h.handler.Create(nil, attributes)
+}
+
+func (h *testContextResourceHandler) Delete(_ context.Context, info RequestInfo, id string) error {
+	h.infos = append(h.infos, info)
+	return h.handler.Delete(nil, id)
+}
+
+func (h *testContextResourceHandler) Get(_ context.Context, info RequestInfo, id string) (Resource, error) {
+	h.infos = append(h.infos, info)
+	return h.handler.Get(nil, id)
+}
+
+func (h *testContextResourceHandler) GetAll(_ context.Context, info RequestInfo, params ListRequestParams) (Page, error) {
+	h.infos = append(h.infos, info)
+	return h.handler.GetAll(nil, params)
+}
+
+func (h *testContextResourceHandler) Patch(_ context.Context, info RequestInfo, id string, request PatchRequest) (Resource, error) {
+	h.infos = append(h.infos, info)
+	return h.handler.Patch(nil, id, request)
+}
+
+func (h *testContextResourceHandler) Replace(_ context.Context, info RequestInfo, id string, attributes ResourceAttributes) (Resource, error) {
+	h.infos = append(h.infos, info)
+	return h.handler.Replace(nil, id, attributes)
+}
+
+// testContextOptionalHandler is a context handler that implements the optional interfaces of context handlers.
+type testContextOptionalHandler struct {
+	*testContextResourceHandler
+	uniqueness testUniquenessHandler
+	version    string
+}
+
+func (h testContextOptionalHandler) LookupUnique(_ context.Context, info RequestInfo, attribute UniqueAttribute, value interface{}) ([]string, error) {
+	h.infos = append(h.infos, info)
+	return h.uniqueness.LookupUnique(nil, attribute, value)
+}
+
+func (h testContextOptionalHandler) Version(_ context.Context, info RequestInfo, _ string) (string, error) {
+	h.infos = append(h.infos, info)
+	return h.version, nil
+}
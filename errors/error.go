@@ -74,6 +74,11 @@ var (
 		Detail:   "The specified request cannot be completed, due to the passing of sensitive information in a request URI.",
 		Status:   http.StatusForbidden,
 	}
+	// ScimErrorPreconditionFailed returns an 412 SCIM error with a detailed message.
+	ScimErrorPreconditionFailed = ScimError{
+		Detail: "Failed to update. Resource has changed on the server.",
+		Status: http.StatusPreconditionFailed,
+	}
 	// ScimErrorInternal returns an 500 SCIM error without a message.
 	ScimErrorInternal = ScimError{
 		Status: http.StatusInternalServerError,
@@ -177,6 +182,16 @@ func ScimErrorBadRequest(msg string) ScimError {
 	}
 }
 
+// ScimErrorNotUnique returns an 409 SCIM error with a detailed message based on the attribute of which the value is
+// already in use.
+func ScimErrorNotUnique(attribute string) ScimError {
+	return ScimError{
+		ScimType: ScimTypeUniqueness,
+		Detail:   ScimErrorUniqueness.Detail + fmt.Sprintf(" The value of %s is already in use.", attribute),
+		Status:   http.StatusConflict,
+	}
+}
+
 // ScimErrorResourceNotFound returns an 404 SCIM error with a detailed message based on the id.
 func ScimErrorResourceNotFound(id string) ScimError {
 	return ScimError{
@@ -211,12 +226,13 @@ func (e ScimError) MarshalJSON() ([]byte, error) {
 	})
 }
 
-// UnmarshalJSON converts the error json data to its corresponding struct representation.
+// UnmarshalJSON converts the error json data to its corresponding struct representation. The status can be either a
+// string or a number.
 func (e *ScimError) UnmarshalJSON(data []byte) error {
 	var tmpScimError struct {
 		ScimType ScimType
 		Detail   string
-		Status   string
+		Status   json.Number
 	}
 
 	err := json.Unmarshal(data, &tmpScimError)
@@ -224,7 +240,7 @@ func (e *ScimError) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	status, err := strconv.Atoi(tmpScimError.Status)
+	status, err := strconv.Atoi(tmpScimError.Status.String())
 	if err != nil {
 		return err
 	}
@@ -103,4 +103,38 @@ func TestScimErrorMarshalling(t *testing.T) {
 	if e.Status != scimErr.Status {
 		t.Errorf("got invalid status: %d", e.Status)
 	}
+
+	// Some service providers send the status as a number.
+	if err := json.Unmarshal([]byte(`{"detail": "Not found.", "status": 404}`), &e); err != nil {
+		t.Error(err)
+	}
+	if e.Status != http.StatusNotFound {
+		t.Errorf("got invalid status: %d", e.Status)
+	}
+	if err := json.Unmarshal([]byte(`{"status": "unknown"}`), &e); err == nil {
+		t.Error("expected an error for an invalid status")
+	}
+}
+
+func TestViolations(t *testing.T) {
+	if Violations(nil).Err() != nil {
+		t.Error("no error expected without violations")
+	}
+
+	violations := Violations{
+		{Path: "userName", ScimType: ScimTypeInvalidValue, Detail: "Required attribute is missing."},
+		{Path: "manager.value", ScimType: ScimTypeInvalidValue, Detail: "String attribute value is not of the right type."},
+		{ScimType: ScimTypeMutability, Detail: "Immutable attribute can not be updated."},
+	}
+	scimErr := violations.WithPrefix("Operations[0].").Err()
+	expected := ScimErrorInvalidValue.Detail +
+		" Operations[0].userName: Required attribute is missing." +
+		" Operations[0].manager.value: String attribute value is not of the right type." +
+		" Operations[0]: Immutable attribute can not be updated."
+	if scimErr.ScimType != ScimTypeInvalidValue || scimErr.Status != http.StatusBadRequest || scimErr.Detail != expected {
+		t.Errorf("unexpected error: %v", scimErr)
+	}
+	if violations[0].Path != "userName" {
+		t.Error("violations should not be modified")
+	}
 }
@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Violation describes an attribute value that failed validation.
+type Violation struct {
+	// Path is the path of the attribute value within the request body, e.g., "emails[1].value" or
+	// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value".
+	Path string
+	// ScimType is the SCIM detail error keyword of the violation.
+	ScimType ScimType
+	// Detail is a human-readable message that describes the violation.
+	Detail string
+}
+
+// Violations is a list of attribute values that failed validation.
+type Violations []Violation
+
+// Err returns a SCIM error that describes all violations, or nil if there are no violations. The type and the status
+// of the error are based on the type of the first violation. Its detail starts with the generic message of that type,
+// followed by the path and the detail of each violation, e.g.: "A required value was missing, [...] or resource
+// schema. userName: Required attribute is missing. emails[1].value: String attribute value is not of the right type."
+func (v Violations) Err() *ScimError {
+	if len(v) == 0 {
+		return nil
+	}
+
+	generic := ScimError{
+		ScimType: v[0].ScimType,
+		Status:   http.StatusBadRequest,
+	}
+	for _, err := range []ScimError{
+		ScimErrorInvalidFilter,
+		ScimErrorTooMany,
+		ScimErrorUniqueness,
+		ScimErrorMutability,
+		ScimErrorInvalidSyntax,
+		ScimErrorInvalidPath,
+		ScimErrorNoTarget,
+		ScimErrorInvalidValue,
+		ScimErrorInvalidVersion,
+		ScimErrorSensitive,
+	} {
+		if err.ScimType == v[0].ScimType {
+			generic = err
+			break
+		}
+	}
+
+	var detail strings.Builder
+	detail.WriteString(generic.Detail)
+	for _, violation := range v {
+		if detail.Len() != 0 {
+			detail.WriteString(" ")
+		}
+		if violation.Path != "" {
+			detail.WriteString(violation.Path + ": ")
+		}
+		detail.WriteString(violation.Detail)
+	}
+	return &ScimError{
+		ScimType: generic.ScimType,
+		Detail:   detail.String(),
+		Status:   generic.Status,
+	}
+}
+
+// WithPrefix returns a copy of the violations of which the paths are prefixed with the given prefix, e.g.,
+// "Operations[0]." or the id of a schema extension followed by a colon. Violations without a path get the prefix
+// without its trailing separator as path.
+func (v Violations) WithPrefix(prefix string) Violations {
+	prefixed := make(Violations, len(v))
+	for i, violation := range v {
+		if violation.Path == "" {
+			violation.Path = strings.TrimRight(prefix, ".:")
+		} else {
+			violation.Path = prefix + violation.Path
+		}
+		prefixed[i] = violation
+	}
+	return prefixed
+}
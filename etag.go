@@ -0,0 +1,54 @@
+package scim
+
+import (
+	"net/http"
+	"strings"
+)
+
+// etag formats the given version as an entity-tag, as defined in RFC 7232, Section 2.3. Versions that are already
+// formatted as a (weak) entity-tag, e.g., `W/"3694e05e9dff591"`, are returned unchanged. Other versions are returned
+// as a strong entity-tag.
+func etag(version string) string {
+	if version == "" || strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`) {
+		return version
+	}
+	return `"` + version + `"`
+}
+
+// matchETag reports whether the given list of entity-tags of an "If-Match" or "If-None-Match" header matches the
+// given version, as defined in RFC 7232, Section 3. The strong comparison function is used for "If-Match", so weak
+// entity-tags never match, and the weak comparison function for "If-None-Match". A resource without version only
+// matches "*" for "If-Match", since it is not known whether the client has its current representation.
+func matchETag(header, version string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return version != "" || !weak
+	}
+	if version == "" {
+		return false
+	}
+	version = etag(version)
+	if weak {
+		version = strings.TrimPrefix(version, "W/")
+	} else if strings.HasPrefix(version, "W/") {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = etag(strings.TrimSpace(tag))
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Versioner is an optional interface that can be implemented by a ResourceHandler to retrieve the current version of
+// a resource without retrieving the resource itself. It is used to evaluate the "If-Match" precondition of PUT, PATCH
+// and DELETE requests. If a handler does not implement this interface, the resource is retrieved with the Get method.
+// A ContextResourceHandler implements ContextVersioner instead.
+type Versioner interface {
+	// Version returns the current version of the resource with the given identifier.
+	Version(r *http.Request, id string) (string, error)
+}
@@ -0,0 +1,137 @@
+package scim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	for version, expected := range map[string]string{
+		"":         "",
+		"v1":       `"v1"`,
+		`"v1"`:     `"v1"`,
+		`W/"v1"`:   `W/"v1"`,
+		"W/v1":     `"W/v1"`,
+		`v1"quote`: `"v1"quote"`,
+	} {
+		assertEqual(t, expected, etag(version))
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	for _, test := range []struct {
+		header  string
+		version string
+		strong  bool
+		weak    bool
+	}{
+		{`*`, "v1", true, true},
+		{`*`, "", true, false},
+		{`"v1"`, "v1", true, true},
+		{`W/"v1"`, "v1", false, true},
+		{`"v1"`, `W/"v1"`, false, true},
+		{`W/"v1"`, `W/"v1"`, false, true},
+		{`"v0", "v1"`, "v1", true, true},
+		{`"v2"`, "v1", false, false},
+		{`"v1"`, "", false, false},
+	} {
+		t.Run(test.header+" "+test.version, func(t *testing.T) {
+			assertEqual(t, test.strong, matchETag(test.header, test.version, false))
+			assertEqual(t, test.weak, matchETag(test.header, test.version, true))
+		})
+	}
+}
+
+func TestServerResourceDeleteHandlerIfMatch(t *testing.T) {
+	for _, test := range []struct {
+		ifMatch        string
+		expectedStatus int
+	}{
+		{`"v2"`, http.StatusPreconditionFailed},
+		{`W/"v1"`, http.StatusPreconditionFailed},
+		{`"v1"`, http.StatusNoContent},
+		{`*`, http.StatusNoContent},
+	} {
+		t.Run(test.ifMatch, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/Users/0001", nil)
+			req.Header.Set("If-Match", test.ifMatch)
+			rr := httptest.NewRecorder()
+			newTestServer().ServeHTTP(rr, req)
+
+			assertEqualStatusCode(t, test.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestServerResourceGetHandlerIfNoneMatch(t *testing.T) {
+	for _, test := range []struct {
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{`"v1"`, http.StatusNotModified},
+		{`W/"v1"`, http.StatusNotModified},
+		{`"v2"`, http.StatusOK},
+	} {
+		t.Run(test.ifNoneMatch, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/Users/0001", nil)
+			req.Header.Set("If-None-Match", test.ifNoneMatch)
+			rr := httptest.NewRecorder()
+			newTestServer().ServeHTTP(rr, req)
+
+			assertEqualStatusCode(t, test.expectedStatus, rr.Code)
+			assertEqual(t, `"v1"`, rr.Header().Get("Etag"))
+			if test.expectedStatus == http.StatusNotModified {
+				assertEqual(t, 0, rr.Body.Len())
+			}
+		})
+	}
+}
+
+func TestServerResourcePatchHandlerIfMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/Users/0001", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "path": "userName", "value": "other"}]
+	}`))
+	req.Header.Set("If-Match", `"v2"`)
+	rr := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusPreconditionFailed, rr.Code)
+}
+
+func TestServerResourcePutHandlerIfMatchVersioner(t *testing.T) {
+	s := newTestServer()
+	s.ResourceTypes[0].Handler = testVersioner{
+		ResourceHandler: s.ResourceTypes[0].Handler,
+		version:         "v2",
+	}
+
+	for _, test := range []struct {
+		ifMatch        string
+		expectedStatus int
+	}{
+		{`"v1"`, http.StatusPreconditionFailed},
+		{`"v2"`, http.StatusOK},
+	} {
+		t.Run(test.ifMatch, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/Users/0001", strings.NewReader(`{"userName": "other"}`))
+			req.Header.Set("If-Match", test.ifMatch)
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+
+			assertEqualStatusCode(t, test.expectedStatus, rr.Code)
+		})
+	}
+}
+
+// testVersioner overrides the versions of the resources of the wrapped handler.
+type testVersioner struct {
+	ResourceHandler
+	version string
+}
+
+func (v testVersioner) Version(r *http.Request, id string) (string, error) {
+	return v.version, nil
+}
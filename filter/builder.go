@@ -0,0 +1,213 @@
+package filter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// And returns a builder of a filter that matches if all the given filters match.
+func And(builders ...Builder) Builder {
+	return logical(filter.AND, builders)
+}
+
+// Attr returns a builder of attribute expressions for the attribute with the given path, e.g., "userName",
+// "name.familyName" or "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber".
+func Attr(path string) AttributeBuilder {
+	attrPath, err := filter.ParseAttrPath([]byte(path))
+	if err != nil {
+		err = &PathError{
+			Path:   path,
+			Reason: err.Error(),
+		}
+	}
+	return AttributeBuilder{
+		path: attrPath,
+		err:  err,
+	}
+}
+
+// Not returns a builder of a filter that matches if the given filter does not match.
+func Not(builder Builder) Builder {
+	if builder.err != nil {
+		return builder
+	}
+	return Builder{
+		expression: &filter.NotExpression{Expression: builder.expression},
+	}
+}
+
+// Or returns a builder of a filter that matches if any of the given filters match.
+func Or(builders ...Builder) Builder {
+	return logical(filter.OR, builders)
+}
+
+// ValuePath returns a builder of a filter that matches if any value of the given multi-valued complex attribute
+// matches the given filter, e.g., `emails[type eq "work"]`. The attributes of the given filter are the
+// sub-attributes of the complex attribute.
+func ValuePath(path string, builder Builder) Builder {
+	attr := Attr(path)
+	switch {
+	case attr.err != nil:
+		return Builder{err: attr.err}
+	case builder.err != nil:
+		return builder
+	}
+	return Builder{
+		expression: &filter.ValuePath{
+			AttributePath: attr.path,
+			ValueFilter:   builder.expression,
+		},
+	}
+}
+
+// logical returns a builder of a filter that combines the given filters with the given operator. The filters are
+// nested to the left, the same way the filter parser nests them.
+func logical(operator filter.LogicalOperator, builders []Builder) Builder {
+	if len(builders) == 0 {
+		return Builder{err: &ExpressionError{
+			Expression: string(operator),
+			Reason:     "at least one filter is required",
+		}}
+	}
+	for _, b := range builders {
+		if b.err != nil {
+			return b
+		}
+	}
+
+	e := builders[0].expression
+	for _, b := range builders[1:] {
+		e = &filter.LogicalExpression{
+			Left:     e,
+			Right:    b.expression,
+			Operator: operator,
+		}
+	}
+	return Builder{expression: e}
+}
+
+// AttributeBuilder builds attribute expressions of an attribute.
+type AttributeBuilder struct {
+	path filter.AttributePath
+	err  error
+}
+
+// Co returns a builder of a filter that matches if the value of the attribute contains the given value.
+func (b AttributeBuilder) Co(value string) Builder {
+	return b.compare(filter.CO, value)
+}
+
+// Eq returns a builder of a filter that matches if the value of the attribute equals the given value. The value is
+// either a string, a boolean, a number, a time or nil.
+func (b AttributeBuilder) Eq(value interface{}) Builder {
+	return b.compare(filter.EQ, value)
+}
+
+// Ew returns a builder of a filter that matches if the value of the attribute ends with the given value.
+func (b AttributeBuilder) Ew(value string) Builder {
+	return b.compare(filter.EW, value)
+}
+
+// Ge returns a builder of a filter that matches if the value of the attribute is greater than or equal to the given
+// value.
+func (b AttributeBuilder) Ge(value interface{}) Builder {
+	return b.compare(filter.GE, value)
+}
+
+// Gt returns a builder of a filter that matches if the value of the attribute is greater than the given value.
+func (b AttributeBuilder) Gt(value interface{}) Builder {
+	return b.compare(filter.GT, value)
+}
+
+// Le returns a builder of a filter that matches if the value of the attribute is less than or equal to the given
+// value.
+func (b AttributeBuilder) Le(value interface{}) Builder {
+	return b.compare(filter.LE, value)
+}
+
+// Lt returns a builder of a filter that matches if the value of the attribute is less than the given value.
+func (b AttributeBuilder) Lt(value interface{}) Builder {
+	return b.compare(filter.LT, value)
+}
+
+// Ne returns a builder of a filter that matches if the value of the attribute does not equal the given value.
+func (b AttributeBuilder) Ne(value interface{}) Builder {
+	return b.compare(filter.NE, value)
+}
+
+// Pr returns a builder of a filter that matches if the attribute has a non-empty value.
+func (b AttributeBuilder) Pr() Builder {
+	return b.compare(filter.PR, nil)
+}
+
+// Sw returns a builder of a filter that matches if the value of the attribute starts with the given value.
+func (b AttributeBuilder) Sw(value string) Builder {
+	return b.compare(filter.SW, value)
+}
+
+// compare returns a builder of an attribute expression with the given operator and value. Times are converted to
+// "DateTime" strings.
+func (b AttributeBuilder) compare(operator filter.CompareOperator, value interface{}) Builder {
+	if b.err != nil {
+		return Builder{err: b.err}
+	}
+
+	switch v := value.(type) {
+	case nil, string, bool, int, int32, int64, float32, float64:
+	case time.Time:
+		value = v.Format(time.RFC3339Nano)
+	default:
+		return Builder{err: &ExpressionError{
+			Expression: fmt.Sprintf("%s %s", b.path, operator),
+			Reason:     fmt.Sprintf("unsupported value of type %T", value),
+		}}
+	}
+	return Builder{
+		expression: &filter.AttributeExpression{
+			AttributePath: b.path,
+			Operator:      operator,
+			CompareValue:  value,
+		},
+	}
+}
+
+// Builder builds a filter. The first error that is encountered while building the filter is returned by Build.
+//
+//	b := filter.And(
+//		filter.Attr("userName").Eq(`a"b`),
+//		filter.Attr("meta.lastModified").Gt(since),
+//	)
+//	b.String() // userName eq "a\"b" and meta.lastModified gt "2011-05-13T04:42:34Z"
+type Builder struct {
+	expression filter.Expression
+	err        error
+}
+
+// Build returns the expression of the filter, or the error that was encountered while building it.
+func (b Builder) Build() (filter.Expression, error) {
+	return b.expression, b.err
+}
+
+// String returns the string representation of the filter, of which string values are escaped, see FormatExpression.
+// It is empty if an error was encountered while building the filter.
+func (b Builder) String() string {
+	if b.err != nil {
+		return ""
+	}
+	return FormatExpression(b.expression)
+}
+
+// Validate returns the expression of the filter if it is a valid filter within the given schema and extensions, see
+// Validator.Validate.
+func (b Builder) Validate(s schema.Schema, exts ...schema.Schema) (filter.Expression, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := NewFilterValidator(b.expression, s, exts...).Validate(); err != nil {
+		return nil, err
+	}
+	return b.expression, nil
+}
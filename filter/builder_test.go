@@ -0,0 +1,95 @@
+package filter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	scimfilter "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestBuilder(t *testing.T) {
+	userSchema := schema.CoreUserSchema()
+	userSchema.Attributes = append(userSchema.Attributes, schema.CommonAttributes()...)
+	since := time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)
+	for _, test := range []struct {
+		builder  scimfilter.Builder
+		expected string
+	}{
+		{
+			scimfilter.And(scimfilter.Attr("userName").Eq(`a"b\c`), scimfilter.Attr("meta.lastModified").Gt(since)),
+			`userName eq "a\"b\\c" and meta.lastModified gt "2011-05-13T04:42:34Z"`,
+		},
+		{
+			scimfilter.Or(scimfilter.Attr("title").Pr(), scimfilter.Attr("userType").Ne("Intern"), scimfilter.Attr("active").Eq(false)),
+			`title pr or userType ne "Intern" or active eq false`,
+		},
+		{
+			scimfilter.And(
+				scimfilter.Attr("userType").Eq("Employee"),
+				scimfilter.Or(scimfilter.Attr("emails").Co("example.com"), scimfilter.Attr("emails").Ew("example.org")),
+			),
+			`userType eq "Employee" and (emails co "example.com" or emails ew "example.org")`,
+		},
+		{
+			scimfilter.Not(scimfilter.Attr("name.familyName").Sw("Jen")),
+			`not (name.familyName sw "Jen")`,
+		},
+		{
+			scimfilter.ValuePath("emails", scimfilter.And(scimfilter.Attr("type").Eq("work"), scimfilter.Attr("primary").Eq(true))),
+			`emails[type eq "work" and primary eq true]`,
+		},
+		{
+			scimfilter.Attr("urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber").Eq("701984"),
+			`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`,
+		},
+		{
+			scimfilter.Attr("x509Certificates.value").Eq(nil),
+			`x509Certificates.value eq null`,
+		},
+	} {
+		t.Run(test.expected, func(t *testing.T) {
+			if s := test.builder.String(); s != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, s)
+			}
+			if _, err := test.builder.Validate(userSchema, schema.ExtensionEnterpriseUser()); err != nil {
+				t.Fatal(err)
+			}
+
+			// The string is accepted by the filter parser.
+			if _, err := filter.ParseFilter([]byte(test.expected)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		builder scimfilter.Builder
+	}{
+		{"invalid path", scimfilter.Attr("user name").Eq("bjensen")},
+		{"unsupported value", scimfilter.Attr("userName").Eq(struct{}{})},
+		{"no filters", scimfilter.And()},
+		{"nested", scimfilter.Not(scimfilter.Or(scimfilter.Attr("title").Pr(), scimfilter.Attr("").Pr()))},
+		{"value path", scimfilter.ValuePath("emails[", scimfilter.Attr("type").Pr())},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.builder.Build(); err == nil {
+				t.Error("expected an error")
+			}
+			if s := test.builder.String(); s != "" {
+				t.Errorf("expected an empty string, got %s", s)
+			}
+		})
+	}
+
+	_, err := scimfilter.Attr("employeeNumber").Eq("701984").Validate(schema.CoreUserSchema())
+	var pathErr *scimfilter.PathError
+	if !errors.As(err, &pathErr) {
+		t.Errorf("expected a path error, got %v", err)
+	}
+}
@@ -0,0 +1,414 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	datetime "github.com/di-wu/xsd-datetime"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// compareFloats returns -1, 0 or 1 if the given value is respectively less than, equal to or greater than the reference.
+func compareFloats(v, ref float64) int {
+	switch {
+	case v < ref:
+		return -1
+	case v > ref:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compileAttributeExpression resolves the attribute path and the compare function of the given attribute expression.
+// Expressions on multi-valued attributes match if one of the values matches.
+func compileAttributeExpression(refs []schema.Schema, e *filter.AttributeExpression) (predicate, error) {
+	ref, attr, err := validateReferenceAttributePath(refs, e.AttributePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		get = compileLookup(ref, attr.Name())
+		// cmpAttr will be the attribute to validate the filter against.
+		cmpAttr     = attr
+		subAttrName string
+	)
+	if subAttrName = e.AttributePath.SubAttributeName(); subAttrName != "" {
+		cmpAttr, _ = attr.SubAttributes().ContainsAttribute(subAttrName)
+		subAttrName = cmpAttr.Name()
+	} else if attr.HasSubAttributes() && e.Operator != filter.PR {
+		// e.g. emails co "example.com" is equal to emails.value co "example.com"
+		value, ok := attr.SubAttributes().ContainsAttribute("value")
+		if !ok {
+			return nil, &ExpressionError{
+				Expression: e.String(),
+				Reason:     "a complex attribute without a value sub-attribute can not be compared to a value",
+			}
+		}
+		cmpAttr, subAttrName = value, value.Name()
+	}
+
+	match := func(value interface{}) bool {
+		return !isEmpty(value)
+	}
+	// If the attribute has a non-empty or non-null value or if it contains a non-empty node for complex attributes,
+	// there is a match.
+	if e.Operator != filter.PR {
+		var err error
+		if match, err = compileCompare(e, cmpAttr); err != nil {
+			return nil, err
+		}
+	}
+
+	// value returns the (sub-)attribute value of the given attribute value.
+	value := func(v interface{}) (interface{}, bool) {
+		if subAttrName == "" {
+			return v, true
+		}
+		complex, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = complex[subAttrName]
+		return v, ok
+	}
+
+	if !attr.MultiValued() {
+		return func(resource map[string]interface{}) bool {
+			v, ok := get(resource)
+			if !ok {
+				return false
+			}
+			v, ok = value(v)
+			return ok && match(v)
+		}, nil
+	}
+	return func(resource map[string]interface{}) bool {
+		v, _ := get(resource)
+		values, _ := v.([]interface{})
+		for _, v := range values {
+			if v, ok := value(v); ok && match(v) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// compileCompare returns a function that compares a given value to the compare value of the given attribute expression.
+// Comparisons of strings and the ordering of numbers, booleans and dateTimes are resolved once and do not allocate
+// errors, other comparisons fall back on the compare functions that are used by the Validator.
+func compileCompare(e *filter.AttributeExpression, attr schema.CoreAttribute) (func(interface{}) bool, error) {
+	cmp, err := createCompareFunction(e, attr)
+	if err != nil {
+		return nil, err
+	}
+	fallback := func(value interface{}) bool {
+		value = normalize(attr, value)
+		return hasType(attr, value) && cmp(value) == nil
+	}
+
+	op := e.Operator
+	switch typ := attr.AttributeType(); typ {
+	case "binary", "reference", "string":
+		var (
+			ref       = e.CompareValue.(string)
+			caseExact = typ == "binary" || attr.CaseExact()
+			test      = func(v, ref string) bool {
+				return order(op, strings.Compare(v, ref))
+			}
+		)
+		if !caseExact {
+			ref = strings.ToLower(ref)
+		}
+		switch op {
+		case filter.CO:
+			test = strings.Contains
+		case filter.SW:
+			test = strings.HasPrefix
+		case filter.EW:
+			test = strings.HasSuffix
+		}
+		return func(value interface{}) bool {
+			v, ok := value.(string)
+			if !ok {
+				return false
+			}
+			if !caseExact {
+				v = strings.ToLower(v)
+			}
+			return test(v, ref)
+		}, nil
+	case "dateTime":
+		if !isOrderOperator(op) {
+			return fallback, nil
+		}
+		ref, _ := datetime.Parse(e.CompareValue.(string))
+		return func(value interface{}) bool {
+			s, ok := value.(string)
+			if !ok {
+				return false
+			}
+			v, err := datetime.Parse(s)
+			if err != nil {
+				return false
+			}
+			switch {
+			case v.Before(ref):
+				return order(op, -1)
+			case v.After(ref):
+				return order(op, 1)
+			default:
+				return order(op, 0)
+			}
+		}, nil
+	case "boolean":
+		if op != filter.EQ && op != filter.NE {
+			return fallback, nil
+		}
+		ref := e.CompareValue.(bool)
+		return func(value interface{}) bool {
+			v, ok := value.(bool)
+			return ok && (v == ref) == (op == filter.EQ)
+		}, nil
+	case "decimal":
+		if !isOrderOperator(op) {
+			return fallback, nil
+		}
+		ref := e.CompareValue.(float64)
+		return func(value interface{}) bool {
+			v, ok := normalize(attr, value).(float64)
+			return ok && order(op, compareFloats(v, ref))
+		}, nil
+	case "integer":
+		if !isOrderOperator(op) {
+			return fallback, nil
+		}
+		ref := e.CompareValue.(int)
+		return func(value interface{}) bool {
+			v, ok := normalize(attr, value).(int)
+			return ok && order(op, compareFloats(float64(v), float64(ref)))
+		}, nil
+	default:
+		return fallback, nil
+	}
+}
+
+// compileExpression compiles the given expression into a predicate. Each attribute path within the expression needs to
+// be valid within at least one of the given reference schemas.
+func compileExpression(refs []schema.Schema, e filter.Expression) (predicate, error) {
+	switch e := e.(type) {
+	case *filter.ValuePath:
+		return compileValuePath(refs, e)
+	case *filter.AttributeExpression:
+		return compileAttributeExpression(refs, e)
+	case *filter.LogicalExpression:
+		left, err := compileExpression(refs, e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compileExpression(refs, e.Right)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator {
+		case filter.AND:
+			return func(resource map[string]interface{}) bool {
+				return left(resource) && right(resource)
+			}, nil
+		case filter.OR:
+			return func(resource map[string]interface{}) bool {
+				return left(resource) || right(resource)
+			}, nil
+		default:
+			return nil, &ExpressionError{
+				Expression: e.String(),
+				Reason:     fmt.Sprintf("unknown logical operator: %s", e.Operator),
+			}
+		}
+	case *filter.NotExpression:
+		p, err := compileExpression(refs, e.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return func(resource map[string]interface{}) bool {
+			return !p(resource)
+		}, nil
+	default:
+		return nil, &ExpressionError{
+			Expression: fmt.Sprint(e),
+			Reason:     "unknown expression type",
+		}
+	}
+}
+
+// compileLookup returns a function that looks up the value of the attribute with the given name of the given reference
+// schema, like lookup does.
+func compileLookup(ref schema.Schema, name string) func(resource map[string]interface{}) (interface{}, bool) {
+	if ref.ID == "" {
+		return func(resource map[string]interface{}) (interface{}, bool) {
+			value, ok := resource[name]
+			return value, ok
+		}
+	}
+	prefixed := fmt.Sprintf("%s:%s", ref.ID, name)
+	return func(resource map[string]interface{}) (interface{}, bool) {
+		if value, ok := resource[name]; ok {
+			return value, true
+		}
+		if value, ok := resource[prefixed]; ok {
+			return value, true
+		}
+		if extension, ok := resource[ref.ID].(map[string]interface{}); ok {
+			value, ok := extension[name]
+			return value, ok
+		}
+		return nil, false
+	}
+}
+
+// compileValuePath compiles the value filter of the given value path against the sub-attributes of the multi-valued
+// attribute. A value path matches if one of the values passes the value filter.
+func compileValuePath(refs []schema.Schema, e *filter.ValuePath) (predicate, error) {
+	ref, attr, err := validateReferenceAttributePath(refs, e.AttributePath)
+	if err != nil {
+		return nil, err
+	}
+	if !attr.MultiValued() || !attr.HasSubAttributes() {
+		return nil, &PathError{
+			Path:   e.AttributePath.String(),
+			Reason: "value path filters can only be applied to multi-valued complex attributes",
+		}
+	}
+
+	valueFilter, err := compileExpression(
+		[]schema.Schema{{
+			ID:         ref.ID,
+			Attributes: attr.SubAttributes(),
+		}},
+		e.ValueFilter,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	get := compileLookup(ref, attr.Name())
+	return func(resource map[string]interface{}) bool {
+		v, _ := get(resource)
+		values, _ := v.([]interface{})
+		for _, v := range values {
+			if complex, ok := v.(map[string]interface{}); ok && valueFilter(complex) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// hasType checks whether the given normalized value has the type that the compare functions of the given attribute
+// expect, see normalize. The compare functions of the Validator panic on values of another type.
+func hasType(attr schema.CoreAttribute, value interface{}) bool {
+	switch attr.AttributeType() {
+	case "binary", "reference", "string":
+		_, ok := value.(string)
+		return ok
+	case "dateTime":
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		_, err := datetime.Parse(s)
+		return err == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "decimal":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		_, ok := value.(int)
+		return ok
+	default:
+		return false
+	}
+}
+
+// isEmpty checks whether the given value is null, an empty string or an empty list/complex value.
+func isEmpty(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	default:
+		return false
+	}
+}
+
+// isOrderOperator checks whether the given operator is either an equality or an ordering operator.
+func isOrderOperator(op filter.CompareOperator) bool {
+	switch op {
+	case filter.EQ, filter.NE, filter.GT, filter.GE, filter.LT, filter.LE:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalize converts numbers to the types that are expected by the compare functions of integer and decimal attributes.
+// Numbers that are decoded from JSON are either a float64 or a json.Number.
+func normalize(attr schema.CoreAttribute, value interface{}) interface{} {
+	switch attr.AttributeType() {
+	case "integer":
+		switch v := value.(type) {
+		case float64:
+			if v == float64(int(v)) {
+				return int(v)
+			}
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				return int(i)
+			}
+		}
+	case "decimal":
+		switch v := value.(type) {
+		case int:
+			return float64(v)
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+	}
+	return value
+}
+
+// order checks whether the given result of a comparison (-1, 0 or 1) satisfies the given equality or ordering operator.
+func order(op filter.CompareOperator, c int) bool {
+	switch op {
+	case filter.EQ:
+		return c == 0
+	case filter.NE:
+		return c != 0
+	case filter.GT:
+		return c > 0
+	case filter.GE:
+		return c >= 0
+	case filter.LT:
+		return c < 0
+	case filter.LE:
+		return c <= 0
+	default:
+		return false
+	}
+}
+
+// predicate reports whether the given resource (or complex value) passes a (sub-)filter.
+type predicate func(resource map[string]interface{}) bool
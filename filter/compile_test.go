@@ -0,0 +1,187 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+
+	scimfilter "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+const benchmarkFilter = `userName sw "user1" and meta.lastModified gt "2011-05-13T04:42:34Z" and emails[type eq "work" and value ew "@example.com"]`
+
+func BenchmarkFilter_Matches(b *testing.B) {
+	exp, err := filter.ParseFilter([]byte(benchmarkFilter))
+	if err != nil {
+		b.Fatal(err)
+	}
+	f, err := scimfilter.Compile(exp, benchmarkSchema())
+	if err != nil {
+		b.Fatal(err)
+	}
+	resources := benchmarkResources(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, resource := range resources {
+			f.Matches(resource)
+		}
+	}
+}
+
+func BenchmarkValidator_PassesFilter(b *testing.B) {
+	validator, err := scimfilter.NewValidator(benchmarkFilter, benchmarkSchema())
+	if err != nil {
+		b.Fatal(err)
+	}
+	resources := benchmarkResources(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, resource := range resources {
+			_ = validator.PassesFilter(resource)
+		}
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	userSchema := schema.CoreUserSchema()
+	userSchema.Attributes = append(userSchema.Attributes, schema.CommonAttributes()...)
+
+	for _, test := range []struct {
+		amount int
+		filter string
+	}{
+		{amount: 1, filter: `userName eq "di-wu"`},
+		{amount: 5, filter: `userName ne "di-wu"`},
+		{amount: 3, filter: `userName co "u"`},
+		{amount: 2, filter: `name.familyName co "d"`},
+		{amount: 2, filter: `userName sw "a"`},
+		{amount: 2, filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "a"`},
+		{amount: 2, filter: `userName ew "n"`},
+		{amount: 6, filter: `userName pr`},
+		{amount: 2, filter: `userName gt "guest"`},
+		{amount: 3, filter: `userName ge "guest"`},
+		{amount: 3, filter: `userName lt "guest"`},
+		{amount: 4, filter: `userName le "guest"`},
+		{amount: 2, filter: `emails[type eq "work"]`},
+		{amount: 2, filter: `emails.type eq "work"`},
+		{amount: 1, filter: `emails co "quint"`},
+		{amount: 1, filter: `name.familyName eq "ad" and userType eq "admin"`},
+		{amount: 2, filter: `name.familyName eq "ad" or userType eq "admin"`},
+		{amount: 5, filter: `not (userName eq "di-wu")`},
+		{amount: 1, filter: `meta.lastModified gt "2011-05-13T04:42:34Z"`},
+		{amount: 2, filter: `schemas eq "urn:ietf:params:scim:schemas:core:2.0:User"`},
+		{amount: 1, filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:organization eq "Elimity"`},
+		{amount: 1, filter: `manager.displayName eq "di-wu"`},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			exp, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := scimfilter.Compile(exp, userSchema, schema.ExtensionEnterpriseUser())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var amount int
+			for _, resource := range testResources() {
+				if f.Matches(resource) {
+					amount++
+				}
+			}
+			if amount != test.amount {
+				t.Errorf("Expected %d resources to pass, got %d.", test.amount, amount)
+			}
+		})
+	}
+}
+
+func TestFilter_MatchesInvalidTypes(t *testing.T) {
+	ref := schema.Schema{
+		Attributes: []schema.CoreAttribute{
+			schema.SimpleCoreAttribute(schema.SimpleNumberParams(schema.NumberParams{
+				Name: "int",
+				Type: schema.AttributeTypeInteger(),
+			})),
+			schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+				Name: "str",
+			})),
+			schema.SimpleCoreAttribute(schema.SimpleBooleanParams(schema.BooleanParams{
+				Name: "bool",
+			})),
+			schema.SimpleCoreAttribute(schema.SimpleDateTimeParams(schema.DateTimeParams{
+				Name: "date",
+			})),
+			schema.SimpleCoreAttribute(schema.SimpleNumberParams(schema.NumberParams{
+				Name: "dec",
+				Type: schema.AttributeTypeDecimal(),
+			})),
+		},
+	}
+
+	for _, test := range []struct {
+		filter   string
+		resource map[string]interface{}
+		matches  bool
+	}{
+		{`int eq 1`, map[string]interface{}{"int": 1.0}, true},
+		{`int gt 1`, map[string]interface{}{"int": 1.5}, false},
+		{`str eq "1"`, map[string]interface{}{"str": 1}, false},
+		{`str pr`, map[string]interface{}{"str": ""}, false},
+		{`bool co true`, map[string]interface{}{"bool": "true"}, false},
+		{`date co "2011-05-13T04:42:34Z"`, map[string]interface{}{"date": "2011"}, false},
+		{`date co "2011-05-13T04:42:34Z"`, map[string]interface{}{"date": 2011}, false},
+		{`dec co 1.5`, map[string]interface{}{"dec": "1"}, false},
+		{`int co 1`, map[string]interface{}{"int": "1"}, false},
+		// A value of an unexpected type only fails its own attribute expression.
+		{`bool co true or str eq "a"`, map[string]interface{}{"bool": "true", "str": "a"}, true},
+		{`date co "2011-05-13T04:42:34Z" or str eq "a"`, map[string]interface{}{"date": 2011, "str": "a"}, true},
+		{`not (dec co 1.5)`, map[string]interface{}{"dec": "1"}, true},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			exp, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := scimfilter.Compile(exp, ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Predicate()(test.resource) != test.matches {
+				t.Errorf("expected %v, got %v", test.matches, !test.matches)
+			}
+		})
+	}
+}
+
+func benchmarkResources(n int) []map[string]interface{} {
+	resources := make([]map[string]interface{}, n)
+	for i := range resources {
+		resources[i] = map[string]interface{}{
+			"userName": fmt.Sprintf("user%d", i),
+			"emails": []interface{}{
+				map[string]interface{}{
+					"value": fmt.Sprintf("user%d@home.com", i),
+					"type":  "home",
+				},
+				map[string]interface{}{
+					"value": fmt.Sprintf("user%d@example.com", i),
+					"type":  "work",
+				},
+			},
+			"meta": map[string]interface{}{
+				"lastModified": "2020-07-26T20:02:34Z",
+			},
+		}
+	}
+	return resources
+}
+
+func benchmarkSchema() schema.Schema {
+	userSchema := schema.CoreUserSchema()
+	userSchema.Attributes = append(userSchema.Attributes, schema.CommonAttributes()...)
+	return userSchema
+}
@@ -0,0 +1,40 @@
+package filter
+
+import (
+	"fmt"
+)
+
+// ExpressionError is returned when an attribute expression can not be evaluated against the attribute it refers to.
+// e.g. `active eq "true"` compares a boolean attribute to a string.
+type ExpressionError struct {
+	// Expression is the attribute expression that is invalid.
+	Expression string
+	// Reason describes why the expression is invalid.
+	Reason string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("invalid expression %s: %s", e.Expression, e.Reason)
+}
+
+// MismatchError is returned when a resource does not pass a filter.
+type MismatchError struct {
+	// Reason describes why the resource does not pass the filter.
+	Reason string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("the resource does not pass the filter: %s", e.Reason)
+}
+
+// PathError is returned when an attribute path does not refer to an attribute within the reference schemas.
+type PathError struct {
+	// Path is the attribute path that could not be resolved.
+	Path string
+	// Reason describes why the path could not be resolved.
+	Reason string
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("invalid attribute path %s: %s", e.Path, e.Reason)
+}
@@ -0,0 +1,400 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// lookup returns the value of the attribute with the given name of the given reference schema. Besides the name of the
+// attribute itself, the attribute can also be prefixed with the id of the schema or be nested within a complex value
+// that is keyed with the id of the schema (which is how extensions are represented in resources).
+func lookup(resource map[string]interface{}, ref schema.Schema, name string) (interface{}, bool) {
+	if value, ok := resource[name]; ok {
+		return value, true
+	}
+	if ref.ID == "" {
+		return nil, false
+	}
+	// Also try with the id as prefix.
+	if value, ok := resource[fmt.Sprintf("%s:%s", ref.ID, name)]; ok {
+		return value, true
+	}
+	if extension, ok := resource[ref.ID].(map[string]interface{}); ok {
+		value, ok := extension[name]
+		return value, ok
+	}
+	return nil, false
+}
+
+// validateAttributePath checks whether the given attribute path is a valid path within the given reference schema.
+func validateAttributePath(ref schema.Schema, attrPath filter.AttributePath) (schema.CoreAttribute, error) {
+	if uri := attrPath.URI(); uri != "" && uri != ref.ID {
+		return schema.CoreAttribute{}, &PathError{
+			Path:   attrPath.String(),
+			Reason: fmt.Sprintf("the uri does not match the schema id: %s", uri),
+		}
+	}
+
+	attr, ok := ref.Attributes.ContainsAttribute(attrPath.AttributeName)
+	if !ok {
+		return schema.CoreAttribute{}, &PathError{
+			Path:   attrPath.String(),
+			Reason: fmt.Sprintf("the reference schema does not have an attribute with the name: %s", attrPath.AttributeName),
+		}
+	}
+	// e.g. name.givenName
+	//           ^________
+	if subAttrName := attrPath.SubAttributeName(); subAttrName != "" {
+		if err := validateSubAttribute(attr, subAttrName); err != nil {
+			return schema.CoreAttribute{}, err
+		}
+	}
+	return attr, nil
+}
+
+// validateExpression checks whether the given expression is a valid expression within the given reference schemas. Each
+// attribute path within the expression needs to be valid within at least one of the reference schemas.
+func validateExpression(refs []schema.Schema, e filter.Expression) error {
+	switch e := e.(type) {
+	case *filter.ValuePath:
+		ref, attr, err := validateReferenceAttributePath(refs, e.AttributePath)
+		if err != nil {
+			return err
+		}
+		return validateExpression(
+			[]schema.Schema{{
+				ID:         ref.ID,
+				Attributes: attr.SubAttributes(),
+			}},
+			e.ValueFilter,
+		)
+	case *filter.AttributeExpression:
+		_, _, err := validateReferenceAttributePath(refs, e.AttributePath)
+		return err
+	case *filter.LogicalExpression:
+		if err := validateExpression(refs, e.Left); err != nil {
+			return err
+		}
+		return validateExpression(refs, e.Right)
+	case *filter.NotExpression:
+		return validateExpression(refs, e.Expression)
+	default:
+		panic(fmt.Sprintf("unknown expression type: %s", e))
+	}
+}
+
+// validateReferenceAttributePath checks whether the given attribute path is a valid path within one of the given
+// reference schemas. If not, the error of the first reference schema is returned.
+func validateReferenceAttributePath(refs []schema.Schema, attrPath filter.AttributePath) (schema.Schema, schema.CoreAttribute, error) {
+	var firstErr error
+	for _, ref := range refs {
+		attr, err := validateAttributePath(ref, attrPath)
+		if err == nil {
+			return ref, attr, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = &PathError{
+			Path:   attrPath.String(),
+			Reason: "there are no reference schemas",
+		}
+	}
+	return schema.Schema{}, schema.CoreAttribute{}, firstErr
+}
+
+// validateSubAttribute checks whether the given attribute name is a attribute within the given reference attribute.
+func validateSubAttribute(attr schema.CoreAttribute, subAttrName string) error {
+	if !attr.HasSubAttributes() {
+		return &PathError{
+			Path:   fmt.Sprintf("%s.%s", attr.Name(), subAttrName),
+			Reason: "the attribute has no sub-attributes",
+		}
+	}
+
+	if _, ok := attr.SubAttributes().ContainsAttribute(subAttrName); !ok {
+		return &PathError{
+			Path:   fmt.Sprintf("%s.%s", attr.Name(), subAttrName),
+			Reason: fmt.Sprintf("the attribute has no sub-attributes named: %s", subAttrName),
+		}
+	}
+	return nil
+}
+
+// Filter represents a filter expression that is compiled against the schema and schema extensions of a resource type.
+// The attribute paths and compare functions are resolved once, so the filter can be used to evaluate many resources.
+type Filter struct {
+	expression filter.Expression
+	predicate  predicate
+}
+
+// Compile compiles the given filter expression against the given schema and schema extensions. Each attribute path
+// within the expression needs to refer to an attribute of one of the schemas, otherwise a *PathError is returned. An
+// *ExpressionError is returned if a compare value does not match the type of its attribute.
+func Compile(exp filter.Expression, s schema.Schema, exts ...schema.Schema) (Filter, error) {
+	p, err := compileExpression(append([]schema.Schema{s}, exts...), exp)
+	if err != nil {
+		return Filter{}, err
+	}
+	return Filter{
+		expression: exp,
+		predicate:  p,
+	}, nil
+}
+
+// Expression returns the filter expression that was compiled.
+func (f Filter) Expression() filter.Expression {
+	return f.expression
+}
+
+// Matches checks whether the given resource attributes pass the filter. Extension attributes can either be prefixed with
+// the id of the extension or be nested within a complex value keyed with the id of the extension. Values that do not
+// match the type of their attribute do not pass the attribute expression they are compared in, e.g., a resource with a
+// numeric title passes `title eq "x" or userName eq "a"` if its user name is "a".
+func (f Filter) Matches(resource map[string]interface{}) bool {
+	return f.predicate(resource)
+}
+
+// Predicate returns the compiled filter as a function, which is equal to Matches.
+func (f Filter) Predicate() func(resource map[string]interface{}) bool {
+	return f.Matches
+}
+
+// Validator represents a filter validator.
+type Validator struct {
+	filter     filter.Expression
+	schema     schema.Schema
+	extensions []schema.Schema
+}
+
+// NewFilterValidator constructs a new filter validator.
+func NewFilterValidator(exp filter.Expression, s schema.Schema, exts ...schema.Schema) Validator {
+	return Validator{
+		filter:     exp,
+		schema:     s,
+		extensions: exts,
+	}
+}
+
+// NewValidator constructs a new filter validator.
+func NewValidator(exp string, s schema.Schema, exts ...schema.Schema) (Validator, error) {
+	e, err := filter.ParseFilter([]byte(exp))
+	if err != nil {
+		return Validator{}, err
+	}
+	return Validator{
+		filter:     e,
+		schema:     s,
+		extensions: exts,
+	}, nil
+}
+
+// PassesFilter checks whether given resources passes the filter. A *MismatchError is returned if the resource does not
+// pass the filter, a *PathError or *ExpressionError if the filter can not be evaluated against the reference schemas.
+func (v Validator) PassesFilter(resource map[string]interface{}) error {
+	switch e := v.filter.(type) {
+	case *filter.ValuePath:
+		ref, attr, ok := v.referenceContains(e.AttributePath)
+		if !ok {
+			return &PathError{
+				Path:   e.AttributePath.String(),
+				Reason: "could not find an attribute that matches the attribute path",
+			}
+		}
+		if !attr.MultiValued() {
+			return &PathError{
+				Path:   e.AttributePath.String(),
+				Reason: "value path filters can only be applied to multi-valued attributes",
+			}
+		}
+
+		value, ok := lookup(resource, ref, attr.Name())
+		if !ok {
+			return &MismatchError{
+				Reason: fmt.Sprintf("the resource does not contain the attribute: %s", e.AttributePath),
+			}
+		}
+		valueFilter := Validator{
+			filter: e.ValueFilter,
+			schema: schema.Schema{
+				ID:         ref.ID,
+				Attributes: attr.SubAttributes(),
+			},
+		}
+		switch value := value.(type) {
+		case []interface{}:
+			for _, a := range value {
+				attr, ok := a.(map[string]interface{})
+				if !ok {
+					return &MismatchError{
+						Reason: fmt.Sprintf("the target is not a complex attribute: %s", e.AttributePath),
+					}
+				}
+				if err := valueFilter.PassesFilter(attr); err == nil {
+					// Found an attribute that passed the value filter.
+					return nil
+				}
+			}
+		}
+		return &MismatchError{
+			Reason: fmt.Sprintf("no value passes the value filter: %s", e),
+		}
+	case *filter.AttributeExpression:
+		ref, attr, ok := v.referenceContains(e.AttributePath)
+		if !ok {
+			return &PathError{
+				Path:   e.AttributePath.String(),
+				Reason: "could not find an attribute that matches the attribute path",
+			}
+		}
+
+		value, ok := lookup(resource, ref, attr.Name())
+		if !ok {
+			return &MismatchError{
+				Reason: fmt.Sprintf("the resource does not contain the attribute: %s", e.AttributePath),
+			}
+		}
+
+		var (
+			// cmpAttr will be the attribute to validate the filter against.
+			cmpAttr = attr
+
+			subAttr     schema.CoreAttribute
+			subAttrName = e.AttributePath.SubAttributeName()
+		)
+
+		if subAttrName != "" {
+			if err := validateSubAttribute(attr, subAttrName); err != nil {
+				return err
+			}
+			subAttr, _ = attr.SubAttributes().ContainsAttribute(subAttrName)
+
+			attr, ok := value.(map[string]interface{})
+			if !ok {
+				return &MismatchError{
+					Reason: fmt.Sprintf("the target is not a complex attribute: %s", e.AttributePath),
+				}
+			}
+			value, ok = attr[subAttr.Name()]
+			if !ok {
+				return &MismatchError{
+					Reason: fmt.Sprintf("the resource does not contain the attribute: %s", e.AttributePath),
+				}
+			}
+
+			cmpAttr = subAttr
+		}
+
+		// If the attribute has a non-empty or non-null value or if it contains a non-empty node for complex attributes, there is a match.
+		if e.Operator == filter.PR {
+			// We already found a value.
+			return nil
+		}
+
+		cmp, err := createCompareFunction(e, cmpAttr)
+		if err != nil {
+			return err
+		}
+
+		if !attr.MultiValued() {
+			if err := cmp(value); err != nil {
+				return &MismatchError{
+					Reason: err.Error(),
+				}
+			}
+			return nil
+		}
+
+		switch value := value.(type) {
+		case []interface{}:
+			for _, v := range value {
+				if err := cmp(v); err == nil {
+					return nil
+				}
+			}
+			return &MismatchError{
+				Reason: fmt.Sprintf("no value passes the filter: %s", e),
+			}
+		default:
+			panic(fmt.Sprintf("given value is not a []interface{}: %v", value))
+		}
+	case *filter.LogicalExpression:
+		switch e.Operator {
+		case filter.AND:
+			leftValidator := Validator{
+				e.Left,
+				v.schema,
+				v.extensions,
+			}
+			if err := leftValidator.PassesFilter(resource); err != nil {
+				return err
+			}
+			rightValidator := Validator{
+				e.Right,
+				v.schema,
+				v.extensions,
+			}
+			return rightValidator.PassesFilter(resource)
+		case filter.OR:
+			leftValidator := Validator{
+				e.Left,
+				v.schema,
+				v.extensions,
+			}
+			if err := leftValidator.PassesFilter(resource); err == nil {
+				return nil
+			}
+			rightValidator := Validator{
+				e.Right,
+				v.schema,
+				v.extensions,
+			}
+			return rightValidator.PassesFilter(resource)
+		}
+		return &ExpressionError{
+			Expression: e.String(),
+			Reason:     fmt.Sprintf("unknown logical operator: %s", e.Operator),
+		}
+	case *filter.NotExpression:
+		validator := Validator{
+			e.Expression,
+			v.schema,
+			v.extensions,
+		}
+		err := validator.PassesFilter(resource)
+		if _, ok := err.(*MismatchError); ok {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return &MismatchError{
+			Reason: fmt.Sprintf("the resource passes the negated filter: %s", e.Expression),
+		}
+	default:
+		panic(fmt.Sprintf("unknown expression type: %s", e))
+	}
+}
+
+// Validate checks whether the expression is a valid path within the given reference schemas. A *PathError is returned
+// if an attribute path within the expression does not refer to an attribute of one of the reference schemas.
+func (v Validator) Validate() error {
+	return validateExpression(append([]schema.Schema{v.schema}, v.extensions...), v.filter)
+}
+
+// referenceContains returns the schema and attribute to which the attribute path applies.
+func (v Validator) referenceContains(attrPath filter.AttributePath) (schema.Schema, schema.CoreAttribute, bool) {
+	for _, s := range append([]schema.Schema{v.schema}, v.extensions...) {
+		if uri := attrPath.URI(); uri != "" && s.ID != uri {
+			continue
+		}
+		if attr, ok := s.Attributes.ContainsAttribute(attrPath.AttributeName); ok {
+			return s, attr, true
+		}
+	}
+	return schema.Schema{}, schema.CoreAttribute{}, false
+}
@@ -1,12 +1,66 @@
 package filter_test
 
 import (
+	"errors"
 	"testing"
 
-	internal "github.com/elimity-com/scim/internal/filter"
+	scimfilter "github.com/elimity-com/scim/filter"
 	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
 )
 
+func TestCompile(t *testing.T) {
+	resource := map[string]interface{}{
+		"userName": "di-wu",
+		"active":   true,
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+			"organization": "Elimity",
+		},
+	}
+
+	for _, test := range []struct {
+		filter  string
+		matches bool
+	}{
+		{filter: `userName eq "di-wu"`, matches: true},
+		{filter: `userName eq "admin"`, matches: false},
+		{filter: `organization eq "Elimity"`, matches: true},
+		{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:organization eq "Elimity"`, matches: true},
+		{filter: `active eq true and organization sw "E"`, matches: true},
+		{filter: `not (userName eq "di-wu" or organization eq "Elimity")`, matches: false},
+		{filter: `title pr`, matches: false},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			exp, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := scimfilter.Compile(exp, schema.CoreUserSchema(), schema.ExtensionEnterpriseUser())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Matches(resource) != test.matches {
+				t.Errorf("expected %v, got %v", test.matches, !test.matches)
+			}
+		})
+	}
+
+	t.Run("invalid path", func(t *testing.T) {
+		exp, err := filter.ParseFilter([]byte(`userName eq "di-wu" and invalid eq "value"`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = scimfilter.Compile(exp, schema.CoreUserSchema(), schema.ExtensionEnterpriseUser())
+		var pathErr *scimfilter.PathError
+		if !errors.As(err, &pathErr) {
+			t.Fatalf("expected a path error, got %v", err)
+		}
+		if pathErr.Path != "invalid" {
+			t.Errorf("expected path invalid, got %s", pathErr.Path)
+		}
+	})
+}
+
 func TestPathValidator_Validate(t *testing.T) {
 	// More info: https://tools.ietf.org/html/rfc7644#section-3.5.2
 	t.Run("Valid", func(t *testing.T) {
@@ -26,7 +80,7 @@ func TestPathValidator_Validate(t *testing.T) {
 			`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`,
 			`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.displayName`,
 		} {
-			validator, err := internal.NewPathValidator(f, schema.CoreUserSchema(), schema.ExtensionEnterpriseUser())
+			validator, err := scimfilter.NewPathValidator(f, schema.CoreUserSchema(), schema.ExtensionEnterpriseUser())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -48,7 +102,7 @@ func TestPathValidator_Validate(t *testing.T) {
 			`urn:ietf:params:scim:schemas:core:2.0:User:employeeNumber`,
 			`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:userName`,
 		} {
-			validator, err := internal.NewPathValidator(f, schema.CoreUserSchema(), schema.ExtensionEnterpriseUser())
+			validator, err := scimfilter.NewPathValidator(f, schema.CoreUserSchema(), schema.ExtensionEnterpriseUser())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -112,7 +166,7 @@ func TestValidator_PassesFilter(t *testing.T) {
 				},
 			},
 		} {
-			validator, err := internal.NewValidator(test.filter, schema.CoreUserSchema())
+			validator, err := scimfilter.NewValidator(test.filter, schema.CoreUserSchema())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -157,7 +211,7 @@ func TestValidator_PassesFilter(t *testing.T) {
 			userSchema := schema.CoreUserSchema()
 			userSchema.Attributes = append(userSchema.Attributes, schema.SchemasAttributes())
 			userSchema.Attributes = append(userSchema.Attributes, schema.CommonAttributes()...)
-			validator, err := internal.NewValidator(test.filter, userSchema)
+			validator, err := scimfilter.NewValidator(test.filter, userSchema)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -188,7 +242,7 @@ func TestValidator_PassesFilter(t *testing.T) {
 				filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:organization eq "Elimity"`,
 			},
 		} {
-			validator, err := internal.NewValidator(test.filter, schema.ExtensionEnterpriseUser())
+			validator, err := scimfilter.NewValidator(test.filter, schema.ExtensionEnterpriseUser())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -233,7 +287,7 @@ func TestValidator_Validate(t *testing.T) {
 		`emails[type eq "work" and value co "@example.com"] or ims[type eq "xmpp" and value co "@foo.com"]`,
 		`emails[type eq "work"].value eq "co@example.com"`,
 	} {
-		validator, err := internal.NewValidator(f, userSchema)
+		validator, err := scimfilter.NewValidator(f, userSchema)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/scim2/filter-parser/v2"
+)
+
+// FormatExpression returns the string representation of the given expression, which is accepted by the filter parser.
+// Unlike the String methods of the expressions, string values are escaped as defined in RFC 7159, Section 7, and
+// logical expressions are put in parentheses where needed. The filter parser keeps the escape sequences of string
+// values as they are written.
+func FormatExpression(e filter.Expression) string {
+	switch e := e.(type) {
+	case *filter.AttributeExpression:
+		s := fmt.Sprintf("%s %s", e.AttributePath, e.Operator)
+		if e.Operator != filter.PR {
+			s += " " + formatValue(e.CompareValue)
+		}
+		return s
+	case *filter.LogicalExpression:
+		return fmt.Sprintf("%s %s %s", formatOperand(e.Left, e.Operator), e.Operator, formatOperand(e.Right, e.Operator))
+	case *filter.NotExpression:
+		return fmt.Sprintf("not (%s)", FormatExpression(e.Expression))
+	case *filter.ValuePath:
+		return fmt.Sprintf("%s[%s]", e.AttributePath, FormatExpression(e.ValueFilter))
+	default:
+		return fmt.Sprint(e)
+	}
+}
+
+// FormatPath returns the string representation of the given path of a PATCH operation, which is accepted by the path
+// parser. String values of the value filter are escaped, see FormatExpression.
+func FormatPath(p filter.Path) string {
+	s := p.AttributePath.String()
+	if p.ValueExpression != nil {
+		s += "[" + FormatExpression(p.ValueExpression) + "]"
+	}
+	if p.SubAttribute != nil {
+		s += "." + *p.SubAttribute
+	}
+	return s
+}
+
+// formatOperand returns the string representation of the given operand of a logical expression with the given
+// operator. Logical expressions with another operator are put in parentheses, since "and" takes precedence over "or".
+func formatOperand(e filter.Expression, operator filter.LogicalOperator) string {
+	if l, ok := e.(*filter.LogicalExpression); ok && l.Operator != operator {
+		return "(" + FormatExpression(e) + ")"
+	}
+	return FormatExpression(e)
+}
+
+// formatValue returns the string representation of the given compare value.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// quote returns the given string as a string literal, as defined in RFC 7159, Section 7. Control characters are
+// escaped with uppercase hexadecimal digits, since lowercase digits are not accepted by the filter parser.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
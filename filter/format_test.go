@@ -0,0 +1,71 @@
+package filter_test
+
+import (
+	"testing"
+
+	scimfilter "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestFormatExpression(t *testing.T) {
+	for _, test := range []struct {
+		filter    string
+		formatted string
+	}{
+		{`userName eq "bjensen"`, `userName eq "bjensen"`},
+		{`title pr`, `title pr`},
+		{`meta.lastModified gt "2011-05-13T04:42:34Z"`, `meta.lastModified gt "2011-05-13T04:42:34Z"`},
+		{`active eq true and loginCount ge 10`, `active eq true and loginCount ge 10`},
+		{`manager eq null`, `manager eq null`},
+		{`userName eq "a" or userName eq "b" or userName eq "c"`, `userName eq "a" or userName eq "b" or userName eq "c"`},
+		{`userType eq "Employee" and (emails co "example.com" or emails co "example.org")`, `userType eq "Employee" and (emails co "example.com" or emails co "example.org")`},
+		{`not (userName eq "bjensen")`, `not (userName eq "bjensen")`},
+		{`emails[type eq "work" and value co "@example.com"]`, `emails[type eq "work" and value co "@example.com"]`},
+		{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "1"`, `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "1"`},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			e, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted := scimfilter.FormatExpression(e); formatted != test.formatted {
+				t.Errorf("expected %s, got %s", test.formatted, formatted)
+			}
+		})
+	}
+}
+
+func TestFormatPath(t *testing.T) {
+	// String values are escaped, the path validator keeps them as they are written.
+	for _, test := range []struct {
+		value   string
+		written string
+	}{
+		{`work`, `work`},
+		{`a"b`, `a\"b`},
+		{`a\b`, `a\\b`},
+		{"a\nb\x01", `a\nb\u0001`},
+	} {
+		path := filter.Path{
+			AttributePath: filter.AttributePath{AttributeName: "emails"},
+			ValueExpression: &filter.AttributeExpression{
+				AttributePath: filter.AttributePath{AttributeName: "type"},
+				Operator:      filter.EQ,
+				CompareValue:  test.value,
+			},
+		}
+		formatted := scimfilter.FormatPath(path)
+		validator, err := scimfilter.NewPathValidator(formatted, schema.CoreUserSchema())
+		if err != nil {
+			t.Fatalf("(%s) %v", formatted, err)
+		}
+		if err := validator.Validate(); err != nil {
+			t.Errorf("(%s) %v", formatted, err)
+		}
+		parsed := validator.Path().ValueExpression.(*filter.AttributeExpression)
+		if parsed.CompareValue != test.written {
+			t.Errorf("(%s) expected %q, got %q", formatted, test.written, parsed.CompareValue)
+		}
+	}
+}
@@ -49,7 +49,10 @@ func cmpBinary(e *filter.AttributeExpression, ref string) (func(interface{}) err
 			return nil
 		})
 	case filter.GT, filter.LT, filter.GE, filter.LE:
-		return nil, fmt.Errorf("can not use op %q on binary values", op)
+		return nil, &ExpressionError{
+			Expression: e.String(),
+			Reason:     fmt.Sprintf("can not use op %q on binary values", op),
+		}
 	default:
 		panic(fmt.Sprintf("unknown operator in expression: %s", e))
 	}
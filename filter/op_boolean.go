@@ -70,7 +70,10 @@ func cmpBoolean(e *filter.AttributeExpression, attr schema.CoreAttribute, ref bo
 			return nil
 		})
 	case filter.GT, filter.LT, filter.GE, filter.LE:
-		return nil, fmt.Errorf("can not use op %q on boolean values", op)
+		return nil, &ExpressionError{
+			Expression: e.String(),
+			Reason:     fmt.Sprintf("can not use op %q on boolean values", op),
+		}
 	default:
 		panic(fmt.Sprintf("unknown operator in expression: %s", e))
 	}
@@ -2,7 +2,7 @@ package filter_test
 
 import (
 	"fmt"
-	internal "github.com/elimity-com/scim/internal/filter"
+	scimfilter "github.com/elimity-com/scim/filter"
 	"github.com/elimity-com/scim/schema"
 	"github.com/scim2/filter-parser/v2"
 	"testing"
@@ -43,7 +43,7 @@ func TestValidatorDateTime(t *testing.T) {
 	} {
 		t.Run(string(test.op), func(t *testing.T) {
 			f := exp(test.op)
-			validator, err := internal.NewValidator(f, ref)
+			validator, err := scimfilter.NewValidator(f, ref)
 			if err != nil {
 				t.Fatal(err)
 			}
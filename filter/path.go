@@ -25,6 +25,7 @@ func NewPathValidator(pathFilter string, s schema.Schema, exts ...schema.Schema)
 	}, nil
 }
 
+// Path returns the parsed path.
 func (v PathValidator) Path() filter.Path {
 	return v.path
 }
@@ -56,10 +57,10 @@ func (v PathValidator) validatePath(ref schema.Schema) error {
 	//             ^_____________
 	if v.path.ValueExpression != nil {
 		if err := validateExpression(
-			schema.Schema{
+			[]schema.Schema{{
 				ID:         ref.ID,
 				Attributes: attr.SubAttributes(),
-			},
+			}},
 			v.path.ValueExpression,
 		); err != nil {
 			return err
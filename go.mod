@@ -4,5 +4,6 @@ go 1.16
 
 require (
 	github.com/di-wu/xsd-datetime v1.0.0
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/scim2/filter-parser/v2 v2.1.3
 )
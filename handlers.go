@@ -2,35 +2,258 @@ package scim
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"strings"
 
 	"github.com/elimity-com/scim/errors"
-	f "github.com/elimity-com/scim/internal/filter"
+	f "github.com/elimity-com/scim/filter"
 	"github.com/elimity-com/scim/schema"
 )
 
-func errorHandler(w http.ResponseWriter, _ *http.Request, scimErr *errors.ScimError) {
+// bulkHandler receives an HTTP POST request to the "/Bulk" endpoint to perform a set of operations on resources in a
+// single request. Operations are dispatched to the handlers of the resource types they target and may reference
+// resources created within the same request by their "bulkId".
+func (s Server) bulkHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.Config.SupportBulk {
+		s.errorHandler(w, r, &errors.ScimError{
+			Detail: "Bulk operations are not supported.",
+			Status: http.StatusNotImplemented,
+		})
+		return
+	}
+	if isBulkOperation(r) {
+		s.errorHandler(w, r, &errors.ScimError{
+			ScimType: errors.ScimErrorInvalidSyntax.ScimType,
+			Detail:   errors.ScimErrorInvalidSyntax.Detail + " A bulk operation can not be a bulk request.",
+			Status:   errors.ScimErrorInvalidSyntax.Status,
+		})
+		return
+	}
+
+	maxPayloadSize := s.Config.getMaxBulkPayloadSize()
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(maxPayloadSize)+1))
+	if err != nil {
+		s.errorHandler(w, r, &errors.ScimErrorInvalidSyntax)
+		return
+	}
+	if len(data) > maxPayloadSize {
+		s.errorHandler(w, r, &errors.ScimError{
+			Detail: fmt.Sprintf("The size of the bulk operation exceeds the maxPayloadSize (%d).", maxPayloadSize),
+			Status: http.StatusRequestEntityTooLarge,
+		})
+		return
+	}
+
+	var req bulkRequest
+	if err := unmarshal(data, &req); err != nil {
+		s.errorHandler(w, r, &errors.ScimErrorInvalidSyntax)
+		return
+	}
+
+	if maxOperations := s.Config.getMaxBulkOperations(); len(req.Operations) > maxOperations {
+		s.errorHandler(w, r, &errors.ScimError{
+			Detail: fmt.Sprintf("The number of operations exceeds the maxOperations (%d).", maxOperations),
+			Status: http.StatusRequestEntityTooLarge,
+		})
+		return
+	}
+
+	bulkIDs := make(map[string]bool)
+	for i, op := range req.Operations {
+		req.Operations[i].Method = strings.ToUpper(op.Method)
+		if op.BulkID == "" {
+			continue
+		}
+		if bulkIDs[op.BulkID] {
+			s.errorHandler(w, r, &errors.ScimError{
+				ScimType: errors.ScimErrorInvalidValue.ScimType,
+				Detail:   errors.ScimErrorInvalidValue.Detail + " Duplicate bulkId: " + op.BulkID,
+				Status:   errors.ScimErrorInvalidValue.Status,
+			})
+			return
+		}
+		bulkIDs[op.BulkID] = true
+	}
+
+	var (
+		// resolved maps the bulk identifiers of successfully created resources to their identifiers.
+		resolved  = make(map[string]string)
+		responses = make([]*bulkOperationResponse, len(req.Operations))
+		pending   = make([]int, len(req.Operations))
+		errCount  int
+	)
+	for i := range pending {
+		pending[i] = i
+	}
+
+	// Operations that reference resources which are not yet created are postponed until these resources are
+	// available. This resolves out of order references, circular references are reported as errors.
+	for progress := true; progress && len(pending) != 0; {
+		progress = false
+		var postponed []int
+		for _, i := range pending {
+			if req.FailOnErrors > 0 && errCount >= req.FailOnErrors {
+				break
+			}
+
+			op := req.Operations[i]
+			var wait, unresolvable bool
+			for _, ref := range op.references() {
+				if _, ok := resolved[ref]; ok {
+					continue
+				}
+				if bulkIDs[ref] && ref != op.BulkID {
+					wait = true
+					continue
+				}
+				unresolvable = true
+			}
+
+			var response bulkOperationResponse
+			switch {
+			case unresolvable:
+				response = bulkErrorResponse(op, errors.ScimError{
+					ScimType: errors.ScimErrorInvalidValue.ScimType,
+					Detail:   errors.ScimErrorInvalidValue.Detail + " Unable to resolve the bulkId references of the operation.",
+					Status:   http.StatusConflict,
+				})
+			case wait:
+				postponed = append(postponed, i)
+				continue
+			default:
+				var id string
+				response, id = s.processBulkOperation(r, op, resolved)
+				if id != "" && op.BulkID != "" {
+					resolved[op.BulkID] = id
+				}
+			}
+
+			progress = true
+			responses[i] = &response
+			if response.Response != nil {
+				errCount++
+				// Operations that reference the failed operation can no longer be resolved.
+				delete(bulkIDs, op.BulkID)
+			}
+		}
+		pending = postponed
+	}
+
+	if req.FailOnErrors <= 0 || errCount < req.FailOnErrors {
+		for _, i := range pending {
+			response := bulkErrorResponse(req.Operations[i], errors.ScimError{
+				ScimType: errors.ScimErrorInvalidValue.ScimType,
+				Detail:   errors.ScimErrorInvalidValue.Detail + " Circular bulkId reference detected.",
+				Status:   http.StatusConflict,
+			})
+			responses[i] = &response
+		}
+	}
+
+	operations := make([]bulkOperationResponse, 0, len(responses))
+	for _, response := range responses {
+		if response != nil {
+			operations = append(operations, *response)
+		}
+	}
+
+	raw, err := json.Marshal(bulkResponse{
+		Operations: operations,
+	})
+	if err != nil {
+		s.reportError(r, fmt.Errorf("failed marshaling bulk response: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
+		return
+	}
+
+	_, err = w.Write(raw)
+	if err != nil {
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
+	}
+}
+
+// errorHandler writes the given SCIM error as the response.
+func (s Server) errorHandler(w http.ResponseWriter, r *http.Request, scimErr *errors.ScimError) {
 	raw, err := json.Marshal(scimErr)
 	if err != nil {
-		log.Fatalf("failed marshaling scim error: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling scim error: %w", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	w.WriteHeader(scimErr.Status)
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
+	}
+}
+
+// meHandler receives an HTTP request to the "/Me" endpoint, which is an alias for the resource of the authenticated
+// subject. The request is forwarded to the handler of the resource type the subject is resolved to.
+func (s Server) meHandler(w http.ResponseWriter, r *http.Request) {
+	if s.SubjectResolver == nil {
+		s.errorHandler(w, r, &errors.ScimError{
+			Status: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	name, id, err := s.SubjectResolver.ResolveSubject(r)
+	if err != nil {
+		scimErr := errors.CheckScimError(err, r.Method)
+		s.errorHandler(w, r, &scimErr)
+		return
 	}
+	setErrorContext(r, name, id)
+	r = withSubject(r, id)
+
+	for _, resourceType := range s.ResourceTypes {
+		if resourceType.Name != name {
+			continue
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			s.resourcePostHandler(w, r, resourceType)
+		case http.MethodGet:
+			s.resourceGetHandler(w, r, id, resourceType)
+		case http.MethodPut:
+			s.resourcePutHandler(w, r, id, resourceType)
+		case http.MethodPatch:
+			s.resourcePatchHandler(w, r, id, resourceType)
+		case http.MethodDelete:
+			s.resourceDeleteHandler(w, r, id, resourceType)
+		default:
+			s.errorHandler(w, r, &errors.ScimError{
+				Detail: "Specified endpoint does not exist.",
+				Status: http.StatusNotFound,
+			})
+		}
+		return
+	}
+
+	s.errorHandler(w, r, &errors.ScimError{
+		Detail: fmt.Sprintf("Resource type %s of the authenticated subject not found.", name),
+		Status: http.StatusInternalServerError,
+	})
 }
 
 // resourceDeleteHandler receives an HTTP DELETE request to the resource endpoint, e.g., "/Users/{id}" or "/Groups/{id}",
 // where "{id}" is a resource identifier to delete a known resource.
 func (s Server) resourceDeleteHandler(w http.ResponseWriter, r *http.Request, id string, resourceType ResourceType) {
-	deleteErr := resourceType.Handler.Delete(r, id)
+	if err := resourceType.checkIfMatch(r, id); err != nil {
+		scimErr := errors.CheckScimError(err, http.MethodDelete)
+		s.errorHandler(w, r, &scimErr)
+		return
+	}
+
+	deleteErr := resourceType.handler().Delete(r, id)
 	if deleteErr != nil {
 		scimErr := errors.CheckScimError(deleteErr, http.MethodDelete)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
@@ -40,27 +263,34 @@ func (s Server) resourceDeleteHandler(w http.ResponseWriter, r *http.Request, id
 // resourceGetHandler receives an HTTP GET request to the resource endpoint, e.g., "/Users/{id}" or "/Groups/{id}",
 // where "{id}" is a resource identifier to retrieve a known resource.
 func (s Server) resourceGetHandler(w http.ResponseWriter, r *http.Request, id string, resourceType ResourceType) {
-	resource, getErr := resourceType.Handler.Get(r, id)
+	resource, getErr := resourceType.handler().Get(r, id)
 	if getErr != nil {
 		scimErr := errors.CheckScimError(getErr, http.MethodGet)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
-	raw, err := json.Marshal(resource.response(resourceType))
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && matchETag(ifNoneMatch, resource.Meta.Version, true) {
+		w.Header().Set("Etag", etag(resource.Meta.Version))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	projection := newAttributeProjection(r, resourceType, getAttributes(r.URL.Query(), "attributes"), getAttributes(r.URL.Query(), "excludedAttributes"))
+	raw, err := json.Marshal(projection.project(resource.response(resourceType)))
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling resource: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling resource: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	if resource.Meta.Version != "" {
-		w.Header().Set("Etag", resource.Meta.Version)
+		w.Header().Set("Etag", etag(resource.Meta.Version))
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -69,14 +299,26 @@ func (s Server) resourceGetHandler(w http.ResponseWriter, r *http.Request, id st
 func (s Server) resourcePatchHandler(w http.ResponseWriter, r *http.Request, id string, resourceType ResourceType) {
 	patch, scimErr := resourceType.validatePatch(r)
 	if scimErr != nil {
-		errorHandler(w, r, scimErr)
+		s.errorHandler(w, r, scimErr)
+		return
+	}
+
+	if err := resourceType.checkIfMatch(r, id); err != nil {
+		scimErr := errors.CheckScimError(err, http.MethodPatch)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
-	resource, patchErr := resourceType.Handler.Patch(r, id, patch)
+	if err := resourceType.checkPatchUniqueness(r, id, patch); err != nil {
+		scimErr := errors.CheckScimError(err, http.MethodPatch)
+		s.errorHandler(w, r, &scimErr)
+		return
+	}
+
+	resource, patchErr := resourceType.handler().Patch(r, id, patch)
 	if patchErr != nil {
 		scimErr := errors.CheckScimError(patchErr, http.MethodPatch)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
@@ -85,22 +327,23 @@ func (s Server) resourcePatchHandler(w http.ResponseWriter, r *http.Request, id
 		return
 	}
 
-	raw, err := json.Marshal(resource.response(resourceType))
+	projection := newAttributeProjection(r, resourceType, getAttributes(r.URL.Query(), "attributes"), getAttributes(r.URL.Query(), "excludedAttributes"))
+	raw, err := json.Marshal(projection.project(resource.response(resourceType)))
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling resource: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling resource: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	if resource.Meta.Version != "" {
-		w.Header().Set("Etag", resource.Meta.Version)
+		w.Header().Set("Etag", etag(resource.Meta.Version))
 	}
 
 	w.WriteHeader(http.StatusOK)
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -111,33 +354,41 @@ func (s Server) resourcePostHandler(w http.ResponseWriter, r *http.Request, reso
 
 	attributes, scimErr := resourceType.validate(data, http.MethodPost, r)
 	if scimErr != nil {
-		errorHandler(w, r, scimErr)
+		s.errorHandler(w, r, scimErr)
+		return
+	}
+
+	if err := resourceType.checkUniqueness(r, "", attributes); err != nil {
+		scimErr := errors.CheckScimError(err, http.MethodPost)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
-	resource, postErr := resourceType.Handler.Create(r, attributes)
+	resource, postErr := resourceType.handler().Create(r, attributes)
 	if postErr != nil {
 		scimErr := errors.CheckScimError(postErr, http.MethodPost)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
-	raw, err := json.Marshal(resource.response(resourceType))
+	projection := newAttributeProjection(r, resourceType, getAttributes(r.URL.Query(), "attributes"), getAttributes(r.URL.Query(), "excludedAttributes"))
+	raw, err := json.Marshal(projection.project(resource.response(resourceType)))
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling resource: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling resource: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	if resource.Meta.Version != "" {
-		w.Header().Set("Etag", resource.Meta.Version)
+		w.Header().Set("Etag", etag(resource.Meta.Version))
 	}
 
+	w.Header().Set("Location", s.location(r, resourceType, resource.ID))
 	w.WriteHeader(http.StatusCreated)
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -148,31 +399,44 @@ func (s Server) resourcePutHandler(w http.ResponseWriter, r *http.Request, id st
 
 	attributes, scimErr := resourceType.validate(data, http.MethodPut, r)
 	if scimErr != nil {
-		errorHandler(w, r, scimErr)
+		s.errorHandler(w, r, scimErr)
+		return
+	}
+
+	if err := resourceType.checkIfMatch(r, id); err != nil {
+		scimErr := errors.CheckScimError(err, http.MethodPut)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
-	resource, putError := resourceType.Handler.Replace(r, id, attributes)
+	if err := resourceType.checkUniqueness(r, id, attributes); err != nil {
+		scimErr := errors.CheckScimError(err, http.MethodPut)
+		s.errorHandler(w, r, &scimErr)
+		return
+	}
+
+	resource, putError := resourceType.handler().Replace(r, id, attributes)
 	if putError != nil {
 		scimErr := errors.CheckScimError(putError, http.MethodPut)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
-	raw, err := json.Marshal(resource.response(resourceType))
+	projection := newAttributeProjection(r, resourceType, getAttributes(r.URL.Query(), "attributes"), getAttributes(r.URL.Query(), "excludedAttributes"))
+	raw, err := json.Marshal(projection.project(resource.response(resourceType)))
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling resource: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling resource: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	if resource.Meta.Version != "" {
-		w.Header().Set("Etag", resource.Meta.Version)
+		w.Header().Set("Etag", etag(resource.Meta.Version))
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -189,20 +453,20 @@ func (s Server) resourceTypeHandler(w http.ResponseWriter, r *http.Request, name
 
 	if resourceType.Name != name {
 		scimErr := errors.ScimErrorResourceNotFound(name)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
 	raw, err := json.Marshal(resourceType.getRaw())
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling resource type: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling resource type: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -212,7 +476,7 @@ func (s Server) resourceTypeHandler(w http.ResponseWriter, r *http.Request, name
 func (s Server) resourceTypesHandler(w http.ResponseWriter, r *http.Request) {
 	params, paramsErr := s.parseRequestParams(r)
 	if paramsErr != nil {
-		errorHandler(w, r, paramsErr)
+		s.errorHandler(w, r, paramsErr)
 		return
 	}
 
@@ -229,14 +493,14 @@ func (s Server) resourceTypesHandler(w http.ResponseWriter, r *http.Request) {
 		Resources:    resources,
 	})
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling list response: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling list response: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -245,21 +509,30 @@ func (s Server) resourceTypesHandler(w http.ResponseWriter, r *http.Request) {
 func (s Server) resourcesGetHandler(w http.ResponseWriter, r *http.Request, resourceType ResourceType) {
 	params, paramsErr := s.parseRequestParams(r)
 	if paramsErr != nil {
-		errorHandler(w, r, paramsErr)
+		s.errorHandler(w, r, paramsErr)
 		return
 	}
 
-	page, getError := resourceType.Handler.GetAll(r, params)
+	if params.SortBy != nil {
+		if _, _, ok := sortAttribute(*params.SortBy, resourceType.Schema, resourceType.getSchemaExtensions(r)...); !ok {
+			scimErr := errors.ScimErrorBadParams([]string{"sortBy"})
+			s.errorHandler(w, r, &scimErr)
+			return
+		}
+	}
+
+	page, getError := resourceType.handler().GetAll(r, params)
 	if getError != nil {
 		scimErr := errors.CheckScimError(getError, http.MethodGet)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
 	// return empty slice instead of null if there are no resources.
 	resources := []interface{}{}
+	projection := newAttributeProjection(r, resourceType, params.Attributes, params.ExcludedAttributes)
 	for _, v := range page.Resources {
-		resources = append(resources, v.response(resourceType))
+		resources = append(resources, projection.project(v.response(resourceType)))
 	}
 
 	raw, err := json.Marshal(listResponse{
@@ -269,14 +542,14 @@ func (s Server) resourcesGetHandler(w http.ResponseWriter, r *http.Request, reso
 		ItemsPerPage: params.Count,
 	})
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshalling list response: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshalling list response: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -286,20 +559,20 @@ func (s Server) schemaHandler(w http.ResponseWriter, r *http.Request, id string)
 	getSchema := s.getSchema(id, r)
 	if getSchema.ID != id {
 		scimErr := errors.ScimErrorResourceNotFound(id)
-		errorHandler(w, r, &scimErr)
+		s.errorHandler(w, r, &scimErr)
 		return
 	}
 
 	raw, err := json.Marshal(getSchema)
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling schema: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling schema: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -308,46 +581,129 @@ func (s Server) schemaHandler(w http.ResponseWriter, r *http.Request, id string)
 func (s Server) schemasHandler(w http.ResponseWriter, r *http.Request) {
 	params, paramsErr := s.parseRequestParams(r)
 	if paramsErr != nil {
-		errorHandler(w, r, paramsErr)
+		s.errorHandler(w, r, paramsErr)
 		return
 	}
 
 	var (
-		validator  = f.NewFilterValidator(params.Filter, schema.Definition())
-		start, end = clamp(params.StartIndex-1, params.Count, len(s.getSchemas(r)))
+		schemas    = s.getSchemas(r)
+		compiled   f.Filter
+		start, end = clamp(params.StartIndex-1, params.Count, len(schemas))
 		resources  []interface{}
 	)
 	if params.Filter != nil {
-		if err := validator.Validate(); err != nil {
-			errorHandler(w, r, &errors.ScimErrorInvalidFilter)
+		var err error
+		if compiled, err = f.Compile(params.Filter, schema.Definition()); err != nil {
+			s.errorHandler(w, r, &errors.ScimErrorInvalidFilter)
 			return
 		}
 	}
-	for _, v := range s.getSchemas(r)[start:end] {
+	for _, v := range schemas[start:end] {
 		resource := v.ToMap()
-		if params.Filter != nil {
-			if err := validator.PassesFilter(resource); err != nil {
-				continue
-			}
+		if params.Filter != nil && !compiled.Matches(resource) {
+			continue
 		}
 		resources = append(resources, resource)
 	}
 
 	raw, err := json.Marshal(listResponse{
-		TotalResults: len(s.getSchemas(r)),
+		TotalResults: len(schemas),
 		ItemsPerPage: params.Count,
 		StartIndex:   params.StartIndex,
 		Resources:    resources,
 	})
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling list response: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling list response: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
+		return
+	}
+
+	_, err = w.Write(raw)
+	if err != nil {
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
+	}
+}
+
+// searchHandler receives an HTTP POST request to the "/.search" endpoint at the root of the service provider to query
+// the resources of all resource types at once. Resource types of which the schema does not support the given filter are
+// skipped. The results are paged as if the resources of all resource types were part of a single list, in the order in
+// which the resource types are defined.
+func (s Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	params, paramsErr := s.parseRequestParams(r)
+	if paramsErr != nil {
+		s.errorHandler(w, r, paramsErr)
+		return
+	}
+
+	var (
+		// offset is the number of resources that still need to be skipped.
+		offset       = params.StartIndex - 1
+		remaining    = params.Count
+		totalResults int
+		matched      bool
+		// return empty slice instead of null if there are no resources.
+		resources = []interface{}{}
+	)
+	for _, resourceType := range s.ResourceTypes {
+		extensions := resourceType.getSchemaExtensions(r)
+		if params.Filter != nil {
+			if _, err := f.Compile(params.Filter, resourceType.schemaWithCommon(), extensions...); err != nil {
+				continue
+			}
+		}
+		matched = true
+
+		typeParams := params
+		typeParams.StartIndex = offset + 1
+		typeParams.Count = remaining
+		if params.SortBy != nil {
+			if _, _, ok := sortAttribute(*params.SortBy, resourceType.Schema, extensions...); !ok {
+				typeParams.SortBy = nil
+			}
+		}
+
+		page, getError := resourceType.handler().GetAll(r, typeParams)
+		if getError != nil {
+			scimErr := errors.CheckScimError(getError, http.MethodPost)
+			s.errorHandler(w, r, &scimErr)
+			return
+		}
+
+		projection := newAttributeProjection(r, resourceType, params.Attributes, params.ExcludedAttributes)
+		for _, v := range page.Resources {
+			if remaining == 0 {
+				break
+			}
+			resources = append(resources, projection.project(v.response(resourceType)))
+			remaining--
+		}
+
+		totalResults += page.TotalResults
+		if offset -= page.TotalResults; offset < 0 {
+			offset = 0
+		}
+	}
+
+	if params.Filter != nil && !matched {
+		s.errorHandler(w, r, &errors.ScimErrorInvalidFilter)
+		return
+	}
+
+	raw, err := json.Marshal(listResponse{
+		TotalResults: totalResults,
+		Resources:    resources,
+		StartIndex:   params.StartIndex,
+		ItemsPerPage: params.Count,
+	})
+	if err != nil {
+		s.reportError(r, fmt.Errorf("failed marshalling list response: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
 
@@ -356,13 +712,13 @@ func (s Server) schemasHandler(w http.ResponseWriter, r *http.Request) {
 func (s Server) serviceProviderConfigHandler(w http.ResponseWriter, r *http.Request) {
 	raw, err := json.Marshal(s.Config.getRaw())
 	if err != nil {
-		errorHandler(w, r, &errors.ScimErrorInternal)
-		log.Fatalf("failed marshaling service provider config: %v", err)
+		s.reportError(r, fmt.Errorf("failed marshaling service provider config: %w", err))
+		s.errorHandler(w, r, &errors.ScimErrorInternal)
 		return
 	}
 
 	_, err = w.Write(raw)
 	if err != nil {
-		log.Printf("failed writing response: %v", err)
+		s.reportError(r, fmt.Errorf("failed writing response: %w", err))
 	}
 }
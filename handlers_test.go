@@ -13,6 +13,7 @@ import (
 	"github.com/elimity-com/scim/errors"
 	"github.com/elimity-com/scim/optional"
 	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
 )
 
 func TestInvalidRequests(t *testing.T) {
@@ -162,7 +163,7 @@ func TestServerResourceGetHandler(t *testing.T) {
 
 			assertEqual(t, "application/scim+json", rr.Header().Get("Content-Type"))
 
-			assertEqual(t, tt.expectedVersion, rr.Header().Get("Etag"))
+			assertEqual(t, `"`+tt.expectedVersion+`"`, rr.Header().Get("Etag"))
 
 			var resource map[string]interface{}
 			assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resource))
@@ -376,7 +377,7 @@ func TestServerResourcePatchHandlerValid(t *testing.T) {
 
 	expectedVersion := "v1.patch"
 
-	assertEqual(t, expectedVersion, rr.Header().Get("Etag"))
+	assertEqual(t, `"`+expectedVersion+`"`, rr.Header().Get("Etag"))
 
 	var resource map[string]interface{}
 	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resource))
@@ -434,7 +435,9 @@ func TestServerResourcePatchHandlerValidAzureAdStyleBoolean(t *testing.T) {
 		]
 	}`))
 	rr := httptest.NewRecorder()
-	newTestServer().ServeHTTP(rr, req)
+	s := newTestServer()
+	s.Compatibility = CompatibilityAzureAD()
+	s.ServeHTTP(rr, req)
 
 	assertEqualStatusCode(t, http.StatusOK, rr.Code)
 
@@ -442,7 +445,7 @@ func TestServerResourcePatchHandlerValidAzureAdStyleBoolean(t *testing.T) {
 
 	expectedVersion := "v1.patch"
 
-	assertEqual(t, expectedVersion, rr.Header().Get("Etag"))
+	assertEqual(t, `"`+expectedVersion+`"`, rr.Header().Get("Etag"))
 
 	var resource map[string]interface{}
 	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resource))
@@ -564,8 +567,8 @@ func TestServerResourcePostHandlerValid(t *testing.T) {
 			assertNotNil(t, meta["lastModified"], "last modified")
 			assertEqual(t, fmt.Sprintf("Users/%s", resource["id"]), meta["location"])
 			assertEqual(t, fmt.Sprintf("v%s", resource["id"]), meta["version"])
-			// ETag and version needs to be the same.
-			assertEqual(t, rr.Header().Get("Etag"), meta["version"])
+			// The ETag is the quoted version.
+			assertEqual(t, fmt.Sprintf(`"%s"`, meta["version"]), rr.Header().Get("Etag"))
 		})
 	}
 }
@@ -748,6 +751,25 @@ func TestServerResourcesGetHandler(t *testing.T) {
 	assertEqual(t, 20, len(response.Resources))
 }
 
+func TestServerResourcesGetHandlerEscapedFilter(t *testing.T) {
+	var params ListRequestParams
+	s := newTestServer()
+	s.ResourceTypes[0].Handler = testListHandler{
+		testResourceHandler: newTestResourceHandler().(testResourceHandler),
+		params:              &params,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/Users?filter="+url.QueryEscape(`userName eq "a\"b"`), nil)
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+	// The compare value is passed on as it is written, including its escape sequences.
+	e, ok := params.Filter.(*filter.AttributeExpression)
+	assertTrue(t, ok)
+	assertEqual(t, `a\"b`, e.CompareValue)
+}
+
 func TestServerResourcesGetHandlerMaxCount(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/Users?count=20000", nil)
 	rr := httptest.NewRecorder()
@@ -1065,3 +1087,14 @@ func newTestServer() Server {
 		},
 	}
 }
+
+// testListHandler is a testResourceHandler that records the parameters of the last list request.
+type testListHandler struct {
+	testResourceHandler
+	params *ListRequestParams
+}
+
+func (h testListHandler) GetAll(r *http.Request, params ListRequestParams) (Page, error) {
+	*h.params = params
+	return h.testResourceHandler.GetAll(r, params)
+}
@@ -0,0 +1,15 @@
+package scim
+
+import (
+	"net/http"
+)
+
+// SubjectResolver resolves the authenticated subject of a request to the resource it is represented by. It is used to
+// serve the "/Me" endpoint, as defined in RFC 7644, Section 3.11.
+type SubjectResolver interface {
+	// ResolveSubject returns the name of the resource type and the identifier of the resource of the authenticated
+	// subject of the given request. The identifier is ignored for HTTP POST requests, which are used to create the
+	// resource of the subject (e.g., self-registration). An error is returned if the subject could not be resolved,
+	// e.g., an 401 SCIM error if the request is not authenticated.
+	ResolveSubject(r *http.Request) (resourceType string, id string, err error)
+}
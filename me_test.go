@@ -0,0 +1,78 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elimity-com/scim/errors"
+)
+
+func TestServerMeHandler(t *testing.T) {
+	s := newTestMeServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/Me", nil)
+	req.Header.Set("Authorization", "0002")
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var resource map[string]interface{}
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resource))
+	assertEqual(t, "0002", resource["id"])
+	assertEqual(t, "test02", resource["userName"])
+
+	req = httptest.NewRequest(http.MethodDelete, "/v2/Me", nil)
+	req.Header.Set("Authorization", "0002")
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v2/Users/0002", nil))
+
+	assertEqualStatusCode(t, http.StatusNotFound, rr.Code)
+}
+
+func TestServerMeHandlerPost(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v2/Me", strings.NewReader(`{"userName": "self"}`))
+	req.Header.Set("Authorization", "self")
+	rr := httptest.NewRecorder()
+	newTestMeServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusCreated, rr.Code)
+
+	var resource map[string]interface{}
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resource))
+	assertEqual(t, "http://example.com/v2/Users/"+resource["id"].(string), rr.Header().Get("Location"))
+}
+
+func TestServerMeHandlerUnauthorized(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestMeServer().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/Me", nil))
+
+	assertEqualStatusCode(t, http.StatusUnauthorized, rr.Code)
+}
+
+func newTestMeServer() Server {
+	s := newTestServer()
+	s.SubjectResolver = testSubjectResolver{}
+	return s
+}
+
+// testSubjectResolver resolves the subject based on the identifier in the "Authorization" header.
+type testSubjectResolver struct{}
+
+func (testSubjectResolver) ResolveSubject(r *http.Request) (string, string, error) {
+	id := r.Header.Get("Authorization")
+	if id == "" {
+		return "", "", errors.ScimError{
+			Status: http.StatusUnauthorized,
+		}
+	}
+	return "User", id, nil
+}
@@ -0,0 +1,411 @@
+// Package memstore provides an in-memory implementation of the scim.ResourceHandler interface. It is intended for
+// tests and prototypes: all resources are lost when the process exits.
+package memstore
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elimity-com/scim"
+	"github.com/elimity-com/scim/errors"
+	f "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/optional"
+	"github.com/elimity-com/scim/schema"
+)
+
+// copyValue returns a deep copy of the given attribute value.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case scim.ResourceAttributes:
+		return copyValue(map[string]interface{}(v))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[k] = copyValue(value)
+		}
+		return m
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, value := range v {
+			values[i] = copyValue(value)
+		}
+		return values
+	case []map[string]interface{}:
+		values := make([]interface{}, len(v))
+		for i, value := range v {
+			values[i] = copyValue(value)
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+// newID generates a random (version 4) UUID.
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("memstore: failed generating identifier: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Store is an in-memory resource handler for a single resource type. It is safe for concurrent use.
+//
+// Stored resources get a random identifier, "created" and "lastModified" timestamps and a version that changes on
+// every modification, which is used by the server to evaluate "If-Match" preconditions. Values of attributes that are
+// marked as unique, i.e. with uniqueness "server" or "global", can not be shared by multiple resources (see
+// scim.UniquenessChecker). GetAll supports filtering, sorting and paging. Patch requests are applied with
+// scim.ApplyPatch, accepting the deviations that are switched on by the compatibility of the server.
+type Store struct {
+	// schema is the schema of the resource type, including the "externalId" attribute.
+	schema     schema.Schema
+	extensions []schema.Schema
+	// filterSchema extends schema with the common "id" and "meta" attributes, so resources can be filtered on their
+	// identifier and timestamps.
+	filterSchema schema.Schema
+	unique       []scim.UniqueAttribute
+	newID        func() string
+	now          func() time.Time
+
+	mu        sync.RWMutex
+	resources map[string]record
+	sequence  uint64
+}
+
+// New returns an empty store for resources of the given resource type.
+func New(t scim.ResourceType) *Store {
+	s := t.Schema
+	s.Attributes = append(append(schema.Attributes{}, s.Attributes...), schema.SimpleCoreAttribute(
+		schema.SimpleStringParams(schema.StringParams{
+			CaseExact:  true,
+			Mutability: schema.AttributeMutabilityReadWrite(),
+			Name:       schema.CommonAttributeExternalID,
+			Uniqueness: schema.AttributeUniquenessNone(),
+		}),
+	))
+
+	filterSchema := s
+	filterSchema.Attributes = append(schema.Attributes{}, s.Attributes...)
+	for _, attr := range schema.CommonAttributes() {
+		switch attr.Name() {
+		case schema.CommonAttributeID, schema.CommonAttributeMeta:
+			filterSchema.Attributes = append(filterSchema.Attributes, attr)
+		}
+	}
+
+	var extensions []schema.Schema
+	for _, extension := range t.SchemaExtensions {
+		extensions = append(extensions, extension.Schema)
+	}
+
+	return &Store{
+		schema:       s,
+		extensions:   extensions,
+		filterSchema: filterSchema,
+		unique:       scim.UniqueAttributes(s, extensions...),
+		newID:        newID,
+		now:          time.Now,
+		resources:    make(map[string]record),
+	}
+}
+
+// Create stores the given attributes under a new identifier.
+func (s *Store) Create(_ *http.Request, attributes scim.ResourceAttributes) (scim.Resource, error) {
+	attributes = copyValue(attributes).(map[string]interface{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUniqueness(attributes, ""); err != nil {
+		return scim.Resource{}, err
+	}
+
+	id := s.newID()
+	for _, ok := s.resources[id]; ok; _, ok = s.resources[id] {
+		id = s.newID()
+	}
+
+	now := s.now()
+	s.sequence++
+	rec := record{
+		attributes:   attributes,
+		created:      now,
+		lastModified: now,
+		revision:     1,
+		sequence:     s.sequence,
+	}
+	s.resources[id] = rec
+	return rec.resource(id), nil
+}
+
+// Delete removes the resource with the given identifier.
+func (s *Store) Delete(_ *http.Request, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.resources[id]; !ok {
+		return errors.ScimErrorResourceNotFound(id)
+	}
+	delete(s.resources, id)
+	return nil
+}
+
+// Get returns the resource with the given identifier.
+func (s *Store) Get(_ *http.Request, id string) (scim.Resource, error) {
+	s.mu.RLock()
+	rec, ok := s.resources[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return scim.Resource{}, errors.ScimErrorResourceNotFound(id)
+	}
+	return rec.resource(id), nil
+}
+
+// GetAll returns the page of resources that pass the filter of the given parameters. Resources are ordered by the
+// "sortBy" and "sortOrder" parameters, or in the order in which they were created if no "sortBy" parameter is given.
+func (s *Store) GetAll(_ *http.Request, params scim.ListRequestParams) (scim.Page, error) {
+	var compiled *f.Filter
+	if params.Filter != nil {
+		c, err := f.Compile(params.Filter, s.filterSchema, s.extensions...)
+		if err != nil {
+			return scim.Page{}, errors.ScimError{
+				ScimType: errors.ScimTypeInvalidFilter,
+				Detail:   errors.ScimErrorInvalidFilter.Detail + " " + err.Error(),
+				Status:   errors.ScimErrorInvalidFilter.Status,
+			}
+		}
+		compiled = &c
+	}
+
+	type entry struct {
+		id  string
+		rec record
+	}
+	var entries []entry
+	s.mu.RLock()
+	for id, rec := range s.resources {
+		if compiled == nil || compiled.Matches(rec.filterable(id)) {
+			entries = append(entries, entry{id: id, rec: rec})
+		}
+	}
+	s.mu.RUnlock()
+
+	// Stored attributes are never modified, so they can be read after the lock is released.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].rec.sequence < entries[j].rec.sequence
+	})
+	resources := make([]scim.Resource, len(entries))
+	for i, e := range entries {
+		resources[i] = e.rec.view(e.id)
+	}
+	scim.SortResources(resources, params, s.schema, s.extensions...)
+
+	start := params.StartIndex - 1
+	if start < 0 {
+		start = 0
+	}
+	if start > len(resources) {
+		start = len(resources)
+	}
+	end := len(resources)
+	if params.Count < end-start {
+		end = start + params.Count
+	}
+	if end < start {
+		end = start
+	}
+
+	page := make([]scim.Resource, 0, end-start)
+	for _, r := range resources[start:end] {
+		r.Attributes = copyValue(r.Attributes).(map[string]interface{})
+		page = append(page, r)
+	}
+	return scim.Page{
+		TotalResults: len(resources),
+		Resources:    page,
+	}, nil
+}
+
+// Len returns the number of stored resources.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.resources)
+}
+
+// LookupUnique returns the identifiers of the resources that have the given value for the given attribute, see
+// scim.UniquenessChecker.
+func (s *Store) LookupUnique(_ *http.Request, attr scim.UniqueAttribute, value interface{}) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookupUnique(attr, value), nil
+}
+
+// Patch applies the operations of the given request to the resource with the given identifier. The version of the
+// resource only changes if the operations modified its attributes. If they did not, an empty resource is returned, so
+// the server responds with "204 No Content".
+func (s *Store) Patch(r *http.Request, id string, req scim.PatchRequest) (scim.Resource, error) {
+	var compatibility scim.Compatibility
+	if r != nil {
+		compatibility = scim.CompatibilityFromContext(r.Context())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.resources[id]
+	if !ok {
+		return scim.Resource{}, errors.ScimErrorResourceNotFound(id)
+	}
+
+	patched, changed, err := compatibility.ApplyPatch(s.schema, s.extensions, rec.attributes, req)
+	if err != nil {
+		return scim.Resource{}, err
+	}
+	if !changed {
+		return scim.Resource{}, nil
+	}
+	if err := s.checkUniqueness(patched, id); err != nil {
+		return scim.Resource{}, err
+	}
+
+	rec = rec.update(patched, s.now())
+	s.resources[id] = rec
+	return rec.resource(id), nil
+}
+
+// Replace replaces all the attributes of the resource with the given identifier.
+func (s *Store) Replace(_ *http.Request, id string, attributes scim.ResourceAttributes) (scim.Resource, error) {
+	attributes = copyValue(attributes).(map[string]interface{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.resources[id]
+	if !ok {
+		return scim.Resource{}, errors.ScimErrorResourceNotFound(id)
+	}
+	if err := s.checkUniqueness(attributes, id); err != nil {
+		return scim.Resource{}, err
+	}
+
+	rec = rec.update(attributes, s.now())
+	s.resources[id] = rec
+	return rec.resource(id), nil
+}
+
+// Version returns the current version of the resource with the given identifier, see scim.Versioner.
+func (s *Store) Version(_ *http.Request, id string) (string, error) {
+	s.mu.RLock()
+	rec, ok := s.resources[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return "", errors.ScimErrorResourceNotFound(id)
+	}
+	return rec.version(), nil
+}
+
+// checkUniqueness returns a uniqueness error if one of the unique attributes of the given attributes has a value that
+// is already in use by another resource than the one with the given identifier.
+func (s *Store) checkUniqueness(attributes scim.ResourceAttributes, id string) error {
+	for _, attr := range s.unique {
+		value, ok := attr.Value(attributes)
+		if !ok {
+			continue
+		}
+		for _, other := range s.lookupUnique(attr, value) {
+			if other != id {
+				return errors.ScimErrorNotUnique(attr.Path())
+			}
+		}
+	}
+	return nil
+}
+
+// lookupUnique returns the identifiers of the resources that have the given value for the given attribute.
+func (s *Store) lookupUnique(attr scim.UniqueAttribute, value interface{}) []string {
+	var ids []string
+	for id, rec := range s.resources {
+		if v, ok := attr.Value(rec.attributes); ok && attr.Equal(value, v) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// record is a stored resource. The attributes of a record are never modified, an update results in a new record.
+type record struct {
+	attributes   map[string]interface{}
+	created      time.Time
+	lastModified time.Time
+	// revision is incremented on every modification of the resource.
+	revision int
+	// sequence is the order in which the resource was created.
+	sequence uint64
+}
+
+// filterable returns the attributes of the record, including its identifier and timestamps.
+func (rec record) filterable(id string) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(rec.attributes)+2)
+	for k, v := range rec.attributes {
+		attributes[k] = v
+	}
+	attributes[schema.CommonAttributeID] = id
+	attributes[schema.CommonAttributeMeta] = map[string]interface{}{
+		"created":      rec.created.Format(time.RFC3339),
+		"lastModified": rec.lastModified.Format(time.RFC3339),
+	}
+	return attributes
+}
+
+// resource returns the record as a resource with a copy of its attributes.
+func (rec record) resource(id string) scim.Resource {
+	r := rec.view(id)
+	r.Attributes = copyValue(r.Attributes).(map[string]interface{})
+	return r
+}
+
+// update returns a new revision of the record with the given attributes.
+func (rec record) update(attributes map[string]interface{}, now time.Time) record {
+	return record{
+		attributes:   attributes,
+		created:      rec.created,
+		lastModified: now,
+		revision:     rec.revision + 1,
+		sequence:     rec.sequence,
+	}
+}
+
+// version returns the version of the record, which is a strong entity-tag based on its revision, since every change
+// of the record results in a new revision.
+func (rec record) version() string {
+	return fmt.Sprintf(`"%d"`, rec.revision)
+}
+
+// view returns the record as a resource that shares its attributes with the record, so it must not be modified.
+func (rec record) view(id string) scim.Resource {
+	externalID := optional.String{}
+	if v, ok := rec.attributes[schema.CommonAttributeExternalID].(string); ok {
+		externalID = optional.NewString(v)
+	}
+	created, lastModified := rec.created, rec.lastModified
+	return scim.Resource{
+		ID:         id,
+		ExternalID: externalID,
+		Attributes: rec.attributes,
+		Meta: scim.Meta{
+			Created:      &created,
+			LastModified: &lastModified,
+			Version:      rec.version(),
+		},
+	}
+}
@@ -0,0 +1,338 @@
+package memstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elimity-com/scim"
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/optional"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestStore(t *testing.T) {
+	s := newTestStore()
+
+	created, err := s.Create(nil, scim.ResourceAttributes{
+		"userName":   "alice",
+		"externalId": "a1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != "0001" || created.ExternalID.Value() != "a1" || created.Meta.Version != `"1"` {
+		t.Errorf("unexpected resource: %+v", created)
+	}
+
+	// Modifying a returned resource does not modify the stored resource.
+	created.Attributes["userName"] = "bob"
+	r, err := s.Get(nil, "0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Attributes["userName"] != "alice" {
+		t.Errorf("expected stored userName alice, got %v", r.Attributes["userName"])
+	}
+
+	replaced, err := s.Replace(nil, "0001", scim.ResourceAttributes{
+		"userName": "alice",
+		"nickName": "ali",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.Meta.Version != `"2"` || replaced.ExternalID.Present() {
+		t.Errorf("unexpected resource: %+v", replaced)
+	}
+	if !replaced.Meta.LastModified.After(*replaced.Meta.Created) {
+		t.Error("expected lastModified to be after created")
+	}
+
+	patched, err := s.Patch(nil, "0001", newTestPatchRequest(t, "replace", "nickName", "al"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Attributes["nickName"] != "al" || patched.Meta.Version != `"3"` {
+		t.Errorf("unexpected resource: %+v", patched)
+	}
+
+	// A patch request that does not modify the resource does not change its version.
+	patched, err = s.Patch(nil, "0001", newTestPatchRequest(t, "replace", "nickName", "al"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := s.Version(nil, "0001"); len(patched.Attributes) != 0 || version != `"3"` {
+		t.Errorf("expected an empty resource and unchanged version, got %+v with version %s", patched, version)
+	}
+
+	if _, err := s.Patch(nil, "0001", newTestPatchRequest(t, "replace", "unknown", "value")); err == nil {
+		t.Error("expected an error for a patch of an unknown attribute")
+	}
+
+	if err := s.Delete(nil, "0001"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 0 {
+		t.Errorf("expected an empty store, got %d resources", s.Len())
+	}
+	for _, err := range []error{
+		s.Delete(nil, "0001"),
+		func() error { _, err := s.Get(nil, "0001"); return err }(),
+		func() error { _, err := s.Replace(nil, "0001", scim.ResourceAttributes{}); return err }(),
+		func() error { _, err := s.Version(nil, "0001"); return err }(),
+	} {
+		assertScimError(t, http.StatusNotFound, err)
+	}
+}
+
+func TestStoreConcurrency(t *testing.T) {
+	s := New(newTestResourceType())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Create(nil, scim.ResourceAttributes{"userName": "alice"})
+			errs <- err
+			_, _ = s.GetAll(nil, scim.ListRequestParams{Count: 10, StartIndex: 1})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var created int
+	for err := range errs {
+		if err == nil {
+			created++
+		}
+	}
+	if created != 1 || s.Len() != 1 {
+		t.Errorf("expected exactly one resource to be created, got %d", created)
+	}
+}
+
+func TestStoreGetAll(t *testing.T) {
+	s := newTestStore()
+	for _, userName := range []string{"dave", "carol", "bob", "alice"} {
+		if _, err := s.Create(nil, scim.ResourceAttributes{
+			"userName": userName,
+			"active":   userName != "bob",
+			schema.ExtensionEnterpriseUser().ID: map[string]interface{}{
+				"department": strings.ToUpper(userName[:1]),
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		filter     string
+		sortBy     string
+		startIndex int
+		count      int
+		total      int
+		expected   []string
+	}{
+		{count: 10, total: 4, expected: []string{"dave", "carol", "bob", "alice"}},
+		{sortBy: "userName", count: 10, total: 4, expected: []string{"alice", "bob", "carol", "dave"}},
+		{sortBy: "userName", startIndex: 2, count: 2, total: 4, expected: []string{"bob", "carol"}},
+		{sortBy: "userName", startIndex: 5, count: 2, total: 4},
+		{count: 0, total: 4},
+		{filter: "active eq true", sortBy: "userName", count: 10, total: 3, expected: []string{"alice", "carol", "dave"}},
+		{filter: `userName sw "C"`, count: 10, total: 1, expected: []string{"carol"}},
+		{filter: `id eq "0002"`, count: 10, total: 1, expected: []string{"carol"}},
+		{filter: `meta.created gt "2000-01-01T00:00:00Z"`, count: 1, total: 4, expected: []string{"dave"}},
+		{filter: `meta.lastModified lt "2000-01-01T00:00:00Z"`, count: 10, total: 0},
+		{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department eq "D"`, count: 10, total: 1, expected: []string{"dave"}},
+	} {
+		params := scim.ListRequestParams{
+			Count:      test.count,
+			StartIndex: test.startIndex,
+		}
+		if test.filter != "" {
+			exp, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			params.Filter = exp
+		}
+		if test.sortBy != "" {
+			path, err := filter.ParseAttrPath([]byte(test.sortBy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			params.SortBy = &path
+		}
+
+		page, err := s.GetAll(nil, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var userNames []string
+		for _, r := range page.Resources {
+			userNames = append(userNames, r.Attributes["userName"].(string))
+		}
+		if page.TotalResults != test.total || fmt.Sprint(userNames) != fmt.Sprint(test.expected) {
+			t.Errorf("%+v: got %d resources %v", test, page.TotalResults, userNames)
+		}
+	}
+
+	exp, _ := filter.ParseFilter([]byte(`unknown eq "x"`))
+	_, err := s.GetAll(nil, scim.ListRequestParams{Filter: exp})
+	assertScimError(t, http.StatusBadRequest, err)
+}
+
+func TestStoreServer(t *testing.T) {
+	s := scim.Server{
+		ResourceTypes: []scim.ResourceType{newTestResourceType()},
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/Users", strings.NewReader(`{"userName": "alice"}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resource map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resource); err != nil {
+		t.Fatal(err)
+	}
+	location := "/Users/" + resource["id"].(string)
+	etag := resource["meta"].(map[string]interface{})["version"]
+	if etag != `"1"` {
+		t.Errorf("expected version \"1\", got %v", etag)
+	}
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/Users", strings.NewReader(`{"userName": "ALICE"}`)))
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "userName") {
+		t.Errorf("expected a uniqueness error, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, location, strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "add", "path": "displayName", "value": "Alice"}]
+	}`))
+	req.Header.Set("If-Match", etag.(string))
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"displayName":"Alice"`) {
+		t.Errorf("expected the resource to be patched, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// A patch request that does not modify the resource results in "204 No Content" and keeps the version.
+	req = httptest.NewRequest(http.MethodPatch, location, strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "add", "path": "displayName", "value": "Alice"}]
+	}`))
+	req.Header.Set("If-Match", `"2"`)
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The version of the resource has changed.
+	req = httptest.NewRequest(http.MethodDelete, location, nil)
+	req.Header.Set("If-Match", etag.(string))
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, `/Users?filter=displayName+eq+"alice"`, nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"totalResults":1`) {
+		t.Errorf("expected one resource, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestStoreUniqueness(t *testing.T) {
+	s := newTestStore()
+	if _, err := s.Create(nil, scim.ResourceAttributes{"userName": "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(nil, scim.ResourceAttributes{"userName": "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.Create(nil, scim.ResourceAttributes{"userName": "Alice"})
+	assertScimError(t, http.StatusConflict, err)
+	_, err = s.Replace(nil, "0002", scim.ResourceAttributes{"userName": "ALICE"})
+	assertScimError(t, http.StatusConflict, err)
+	_, err = s.Patch(nil, "0002", newTestPatchRequest(t, "replace", "userName", "alice"))
+	assertScimError(t, http.StatusConflict, err)
+
+	// A resource does not conflict with itself.
+	if _, err := s.Replace(nil, "0001", scim.ResourceAttributes{"userName": "Alice"}); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete(nil, "0001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(nil, scim.ResourceAttributes{"userName": "alice"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func assertScimError(t *testing.T, status int, err error) {
+	t.Helper()
+	scimErr, ok := err.(errors.ScimError)
+	if !ok {
+		t.Errorf("expected a SCIM error, got %v", err)
+		return
+	}
+	if scimErr.Status != status {
+		t.Errorf("expected status %d, got %d: %s", status, scimErr.Status, scimErr.Detail)
+	}
+}
+
+func newTestPatchRequest(t *testing.T, op, path string, value interface{}) scim.PatchRequest {
+	t.Helper()
+	p, err := filter.ParsePath([]byte(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scim.PatchRequest{
+		Operations: []scim.PatchOperation{{Op: op, Path: &p, Value: value}},
+	}
+}
+
+func newTestResourceType() scim.ResourceType {
+	t := scim.ResourceType{
+		ID:       optional.NewString("User"),
+		Name:     "User",
+		Endpoint: "/Users",
+		Schema:   schema.CoreUserSchema(),
+		SchemaExtensions: []scim.SchemaExtension{
+			{Schema: schema.ExtensionEnterpriseUser()},
+		},
+	}
+	t.Handler = New(t)
+	return t
+}
+
+// newTestStore returns a store with sequential identifiers and a clock that advances a second on every call.
+func newTestStore() *Store {
+	s := New(newTestResourceType())
+	var id int
+	s.newID = func() string {
+		id++
+		return fmt.Sprintf("%04d", id)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return s
+}
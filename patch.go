@@ -1,6 +1,11 @@
 package scim
 
-import "github.com/scim2/filter-parser/v2"
+import (
+	"encoding/json"
+
+	f "github.com/elimity-com/scim/filter"
+	"github.com/scim2/filter-parser/v2"
+)
 
 const (
 	// PatchOperationAdd is used to add a new attribute value to an existing resource.
@@ -22,8 +27,35 @@ type PatchOperation struct {
 	Value interface{}
 }
 
+// MarshalJSON converts the operation to its corresponding json representation, of which the path is a string.
+func (op PatchOperation) MarshalJSON() ([]byte, error) {
+	raw := map[string]interface{}{
+		"op": op.Op,
+	}
+	if op.Path != nil {
+		raw["path"] = f.FormatPath(*op.Path)
+	}
+	if op.Value != nil {
+		raw["value"] = op.Value
+	}
+	return json.Marshal(raw)
+}
+
 // PatchRequest represents a resource PATCH request.
 type PatchRequest struct {
 	Schemas    []string
 	Operations []PatchOperation
 }
+
+// MarshalJSON converts the request to its corresponding json representation. The schema of a PATCH request is used if
+// the request has no schemas.
+func (r PatchRequest) MarshalJSON() ([]byte, error) {
+	schemas := r.Schemas
+	if len(schemas) == 0 {
+		schemas = []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"}
+	}
+	return json.Marshal(map[string]interface{}{
+		"schemas":    schemas,
+		"Operations": r.Operations,
+	})
+}
@@ -0,0 +1,766 @@
+package scim
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/elimity-com/scim/errors"
+	f "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// ApplyPatch applies the operations of the given PATCH request to a copy of the given attributes, following the rules
+// defined in RFC 7644, Section 3.5.2. The given schema and extensions are used to resolve the attributes that are
+// targeted by the operations. Attributes of extensions are nested within an object named after the extension's id.
+//
+// Returns the patched attributes and whether they differ from the given attributes. The operations are evaluated
+// until they are all applied or until an error is encountered, in which case a SCIM error is returned, e.g., a
+// "noTarget" error if a value filter did not match any value or a "mutability" error if a read-only or an immutable
+// attribute is modified. The given attributes are never modified.
+func ApplyPatch(s schema.Schema, extensions []schema.Schema, attributes ResourceAttributes, req PatchRequest) (ResourceAttributes, bool, error) {
+	return Compatibility{}.ApplyPatch(s, extensions, attributes, req)
+}
+
+// checkPatchMutability checks whether the attribute may be modified based on its mutability. Immutable attributes may
+// only be modified if they are not yet assigned.
+func checkPatchMutability(attr schema.CoreAttribute, assigned bool) error {
+	switch mutability := attr.Mutability(); {
+	case mutability == "readOnly", mutability == "immutable" && assigned:
+		return errors.ScimError{
+			ScimType: errors.ScimErrorMutability.ScimType,
+			Detail:   errors.ScimErrorMutability.Detail + fmt.Sprintf(" The attribute %s is %s.", attr.Name(), mutability),
+			Status:   errors.ScimErrorMutability.Status,
+		}
+	default:
+		return nil
+	}
+}
+
+// containsPatchValue reports whether the given list of values contains the given value.
+func containsPatchValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyPatchValue returns a deep copy of the given value.
+func copyPatchValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyPatchValue(e)
+		}
+		return m
+	case ResourceAttributes:
+		return copyPatchValue(map[string]interface{}(v))
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = copyPatchValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// deletePatchAttribute removes the attribute with the given (case insensitive) name.
+func deletePatchAttribute(m map[string]interface{}, name string) {
+	if key, ok := patchAttributeKey(m, name); ok {
+		delete(m, key)
+	}
+}
+
+// filterPatchValue returns the complex value that is described by the given value filter, if the filter only compares
+// sub-attributes with "eq", e.g., `type eq "work"` describes {"type": "work"}.
+func filterPatchValue(expr filter.Expression) (map[string]interface{}, bool) {
+	switch e := expr.(type) {
+	case *filter.AttributeExpression:
+		if e.Operator != filter.EQ || e.AttributePath.SubAttributeName() != "" {
+			return nil, false
+		}
+		return map[string]interface{}{e.AttributePath.AttributeName: e.CompareValue}, true
+	case *filter.LogicalExpression:
+		if e.Operator != filter.AND {
+			return nil, false
+		}
+		left, ok := filterPatchValue(e.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := filterPatchValue(e.Right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	default:
+		return nil, false
+	}
+}
+
+// getPatchAttribute returns the value of the attribute with the given (case insensitive) name.
+func getPatchAttribute(m map[string]interface{}, name string) (interface{}, bool) {
+	key, ok := patchAttributeKey(m, name)
+	if !ok {
+		return nil, false
+	}
+	return m[key], true
+}
+
+// isEmptyPatchValue reports whether given value is considered to be unassigned. Unassigned attributes, the null value,
+// or empty array (in the case of a multi-valued attribute) SHALL be considered to be equivalent in "state".
+func isEmptyPatchValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// isPrimary reports whether the given complex value is marked as the primary value.
+func isPrimary(m map[string]interface{}) bool {
+	primary, _ := getPatchAttribute(m, "primary")
+	return primary == true
+}
+
+// patchAttributeKey returns the key of the attribute with the given (case insensitive) name.
+func patchAttributeKey(m map[string]interface{}, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// patchErrorInvalidPath returns an "invalidPath" SCIM error with the given details.
+func patchErrorInvalidPath(detail string) errors.ScimError {
+	return errors.ScimError{
+		ScimType: errors.ScimErrorInvalidPath.ScimType,
+		Detail:   errors.ScimErrorInvalidPath.Detail + " " + detail,
+		Status:   errors.ScimErrorInvalidPath.Status,
+	}
+}
+
+// patchErrorInvalidValue returns an "invalidValue" SCIM error with the given details.
+func patchErrorInvalidValue(detail string) errors.ScimError {
+	return errors.ScimError{
+		ScimType: errors.ScimErrorInvalidValue.ScimType,
+		Detail:   errors.ScimErrorInvalidValue.Detail + " " + detail,
+		Status:   errors.ScimErrorInvalidValue.Status,
+	}
+}
+
+// patchErrorNoTarget returns a "noTarget" SCIM error with the given details.
+func patchErrorNoTarget(detail string) errors.ScimError {
+	return errors.ScimError{
+		ScimType: errors.ScimErrorNoTarget.ScimType,
+		Detail:   errors.ScimErrorNoTarget.Detail + " " + detail,
+		Status:   errors.ScimErrorNoTarget.Status,
+	}
+}
+
+// patchValues converts the given value of a multi-valued attribute to a list of values.
+func patchValues(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// setPatchAttribute sets the value of the attribute with the given (case insensitive) name. The attribute is removed
+// if the value is considered to be unassigned.
+func setPatchAttribute(m map[string]interface{}, name string, value interface{}) {
+	if isEmptyPatchValue(value) {
+		deletePatchAttribute(m, name)
+		return
+	}
+	if key, ok := patchAttributeKey(m, name); ok {
+		name = key
+	}
+	m[name] = value
+}
+
+// updatePrimary ensures that the "primary" attribute is only set for the value at the given index, if it is set for
+// that value. The primary attribute value "true" MUST appear no more than once.
+func updatePrimary(values []interface{}, index int) {
+	if m, ok := values[index].(map[string]interface{}); !ok || !isPrimary(m) {
+		return
+	}
+	for i, v := range values {
+		if m, ok := v.(map[string]interface{}); ok && i != index && isPrimary(m) {
+			setPatchAttribute(m, "primary", false)
+		}
+	}
+}
+
+// patcher applies PATCH operations to the attributes of a resource.
+type patcher struct {
+	compatibility Compatibility
+	schema        schema.Schema
+	extensions    []schema.Schema
+}
+
+// add adds the given value to the attribute, as defined in RFC 7644, Section 3.5.2.1.
+func (p patcher) add(container map[string]interface{}, attr schema.CoreAttribute, value interface{}) error {
+	current, exists := getPatchAttribute(container, attr.Name())
+	if err := checkPatchMutability(attr, exists && !isEmptyPatchValue(current)); err != nil {
+		return err
+	}
+
+	switch {
+	case attr.MultiValued():
+		// New values are added to the existing values, values that already exist are ignored.
+		values, _ := copyPatchValue(patchValues(current)).([]interface{})
+		for _, v := range patchValues(value) {
+			if attr.HasSubAttributes() {
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return patchErrorInvalidValue(fmt.Sprintf("The values of %s must be complex values.", attr.Name()))
+				}
+				if err := p.checkSubAttributes(attr, m, nil); err != nil {
+					return err
+				}
+			}
+			if !containsPatchValue(values, v) {
+				values = append(values, v)
+				updatePrimary(values, len(values)-1)
+			}
+		}
+		setPatchAttribute(container, attr.Name(), values)
+	case attr.HasSubAttributes():
+		return p.merge(container, attr, value)
+	default:
+		setPatchAttribute(container, attr.Name(), value)
+	}
+	return nil
+}
+
+// addFiltered adds a new value that is described by the value filter of an "add" operation that did not match any
+// values, see Compatibility.AddFilteredValues. The given value is assigned to the sub-attribute, if any, or merged into
+// the new value.
+func (p patcher) addFiltered(container map[string]interface{}, attr schema.CoreAttribute, element map[string]interface{}, subAttrName string, subAttr schema.CoreAttribute, value interface{}) error {
+	if subAttrName != "" {
+		element[subAttr.Name()] = value
+	} else {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return patchErrorInvalidValue(fmt.Sprintf("The values of %s must be complex values.", attr.Name()))
+		}
+		for k, v := range m {
+			element[k] = copyPatchValue(v)
+		}
+	}
+	if err := p.checkSubAttributes(attr, element, nil); err != nil {
+		return err
+	}
+	added := make(map[string]interface{}, len(element))
+	for k, v := range element {
+		subAttr, _ := attr.SubAttributes().ContainsAttribute(k)
+		setPatchAttribute(added, subAttr.Name(), v)
+	}
+
+	current, _ := getPatchAttribute(container, attr.Name())
+	values, _ := copyPatchValue(patchValues(current)).([]interface{})
+	values = append(values, added)
+	updatePrimary(values, len(values)-1)
+	setPatchAttribute(container, attr.Name(), values)
+	return nil
+}
+
+// apply applies the given operation to the given attributes.
+func (p patcher) apply(attributes map[string]interface{}, op PatchOperation) error {
+	operation := strings.ToLower(op.Op)
+	switch operation {
+	case PatchOperationAdd, PatchOperationReplace, PatchOperationRemove:
+	default:
+		return patchErrorInvalidValue(fmt.Sprintf("Unrecognized operation type: %s.", op.Op))
+	}
+
+	if op.Path == nil {
+		if operation == PatchOperationRemove {
+			return patchErrorNoTarget("A remove operation requires a path.")
+		}
+		return p.applyWithoutPath(attributes, operation, op.Value)
+	}
+
+	container, attr, err := p.resolve(attributes, op.Path.AttributePath, operation != PatchOperationRemove)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		// The extension that is targeted is not present, there is nothing to remove.
+		return nil
+	}
+	defer p.cleanup(attributes)
+
+	subAttrName := op.Path.AttributePath.SubAttributeName()
+	if subAttrName == "" {
+		subAttrName = op.Path.SubAttributeName()
+	}
+	var subAttr schema.CoreAttribute
+	if subAttrName != "" {
+		var ok bool
+		if subAttr, ok = attr.SubAttributes().ContainsAttribute(subAttrName); !ok {
+			return patchErrorInvalidPath(fmt.Sprintf("The attribute %s has no sub-attribute named %s.", attr.Name(), subAttrName))
+		}
+	}
+
+	if op.Path.ValueExpression != nil {
+		return p.applyFilter(container, attr, op.Path.ValueExpression, subAttrName, subAttr, operation, op.Value)
+	}
+
+	if subAttrName != "" {
+		return p.applySubAttribute(container, attr, subAttr, operation, op.Value)
+	}
+
+	switch {
+	case operation == PatchOperationAdd:
+		return p.add(container, attr, op.Value)
+	case operation == PatchOperationReplace:
+		return p.replace(container, attr, op.Value)
+	case op.Value != nil && attr.MultiValued() && p.compatibility.RemoveValues:
+		return p.removeValues(container, attr, op.Value)
+	default:
+		return p.remove(container, attr)
+	}
+}
+
+// applyFilter applies the given operation to the values of a multi-valued attribute that match the given value filter.
+func (p patcher) applyFilter(container map[string]interface{}, attr schema.CoreAttribute, expr filter.Expression, subAttrName string, subAttr schema.CoreAttribute, operation string, value interface{}) error {
+	if !attr.MultiValued() || !attr.HasSubAttributes() {
+		return patchErrorInvalidPath(fmt.Sprintf("Value filters can only be applied to multi-valued complex attributes, %s is not.", attr.Name()))
+	}
+
+	current, _ := getPatchAttribute(container, attr.Name())
+	values, _ := copyPatchValue(patchValues(current)).([]interface{})
+	validator := f.NewFilterValidator(expr, schema.Schema{
+		ID:         p.schema.ID,
+		Attributes: attr.SubAttributes(),
+	})
+
+	// The mutability of sub-attributes is checked for each of the matched values.
+	if err := checkPatchMutability(attr, subAttrName == "" && len(values) != 0); err != nil {
+		return err
+	}
+
+	var (
+		matched   bool
+		remaining = make([]interface{}, 0, len(values))
+	)
+	for _, v := range values {
+		element, ok := v.(map[string]interface{})
+		if !ok || validator.PassesFilter(element) != nil {
+			remaining = append(remaining, v)
+			continue
+		}
+		matched = true
+
+		switch {
+		case operation == PatchOperationRemove && subAttrName == "":
+			continue
+		case operation == PatchOperationRemove:
+			if err := checkPatchMutability(subAttr, true); err != nil {
+				return err
+			}
+			deletePatchAttribute(element, subAttrName)
+		case subAttrName != "":
+			current, exists := getPatchAttribute(element, subAttrName)
+			if err := checkPatchMutability(subAttr, exists && !isEmptyPatchValue(current)); err != nil {
+				return err
+			}
+			setPatchAttribute(element, subAttr.Name(), value)
+		default:
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return patchErrorInvalidValue(fmt.Sprintf("The values of %s must be complex values.", attr.Name()))
+			}
+			if err := p.checkSubAttributes(attr, m, element); err != nil {
+				return err
+			}
+			if operation == PatchOperationReplace {
+				element = make(map[string]interface{})
+			}
+			for k, e := range m {
+				setPatchAttribute(element, k, copyPatchValue(e))
+			}
+		}
+		if len(element) != 0 {
+			remaining = append(remaining, element)
+			updatePrimary(remaining, len(remaining)-1)
+		}
+	}
+
+	if !matched {
+		if operation == PatchOperationRemove {
+			// Removing values that do not exist does not change the resource.
+			return nil
+		}
+		if element, ok := filterPatchValue(expr); ok && operation == PatchOperationAdd && p.compatibility.AddFilteredValues {
+			return p.addFiltered(container, attr, element, subAttrName, subAttr, value)
+		}
+		return patchErrorNoTarget(fmt.Sprintf("The value filter of %s did not match any values.", attr.Name()))
+	}
+	setPatchAttribute(container, attr.Name(), remaining)
+	return nil
+}
+
+// applyPatch applies the operations of the given PATCH request to a copy of the given attributes, see ApplyPatch.
+func (p patcher) applyPatch(attributes ResourceAttributes, req PatchRequest) (ResourceAttributes, bool, error) {
+	patched, _ := copyPatchValue(map[string]interface{}(attributes)).(map[string]interface{})
+	if patched == nil {
+		patched = make(map[string]interface{})
+	}
+	for i, op := range req.Operations {
+		if err := p.apply(patched, op); err != nil {
+			if scimErr, ok := err.(errors.ScimError); ok {
+				scimErr.Detail += fmt.Sprintf(" Operation number: %d.", i+1)
+				return attributes, false, scimErr
+			}
+			return attributes, false, err
+		}
+	}
+
+	changed := len(attributes) != len(patched) || !reflect.DeepEqual(map[string]interface{}(attributes), patched)
+	if len(attributes) == 0 && len(patched) == 0 {
+		changed = false
+	}
+	return patched, changed, nil
+}
+
+// applySubAttribute applies the given operation to the given sub-attribute of a complex attribute. If the attribute is
+// multi-valued, the operation is applied to all of its values.
+func (p patcher) applySubAttribute(container map[string]interface{}, attr, subAttr schema.CoreAttribute, operation string, value interface{}) error {
+	current, exists := getPatchAttribute(container, attr.Name())
+
+	var elements []map[string]interface{}
+	switch {
+	case attr.MultiValued():
+		values, _ := copyPatchValue(patchValues(current)).([]interface{})
+		for _, v := range values {
+			if element, ok := v.(map[string]interface{}); ok {
+				elements = append(elements, element)
+			}
+		}
+		defer func() {
+			remaining := make([]interface{}, 0, len(values))
+			for _, v := range values {
+				if !isEmptyPatchValue(v) {
+					remaining = append(remaining, v)
+				}
+			}
+			setPatchAttribute(container, attr.Name(), remaining)
+		}()
+	default:
+		element, ok := copyPatchValue(current).(map[string]interface{})
+		if !ok || element == nil {
+			if exists && current != nil {
+				return patchErrorInvalidValue(fmt.Sprintf("The value of %s is not a complex value.", attr.Name()))
+			}
+			if operation == PatchOperationRemove {
+				return nil
+			}
+			element = make(map[string]interface{})
+		}
+		elements = append(elements, element)
+		defer setPatchAttribute(container, attr.Name(), element)
+	}
+
+	for _, element := range elements {
+		current, exists := getPatchAttribute(element, subAttr.Name())
+		if err := checkPatchMutability(subAttr, exists && !isEmptyPatchValue(current)); err != nil {
+			return err
+		}
+		if operation == PatchOperationRemove {
+			deletePatchAttribute(element, subAttr.Name())
+			continue
+		}
+		setPatchAttribute(element, subAttr.Name(), value)
+	}
+	return nil
+}
+
+// applyValue adds or replaces the value of the attribute with the given path.
+func (p patcher) applyValue(attributes map[string]interface{}, path filter.AttributePath, operation string, value interface{}) error {
+	container, attr, err := p.resolve(attributes, path, true)
+	if err != nil {
+		return err
+	}
+	if subAttrName := path.SubAttributeName(); subAttrName != "" {
+		subAttr, ok := attr.SubAttributes().ContainsAttribute(subAttrName)
+		if !ok {
+			return patchErrorInvalidPath(fmt.Sprintf("The attribute %s has no sub-attribute named %s.", attr.Name(), subAttrName))
+		}
+		return p.applySubAttribute(container, attr, subAttr, operation, value)
+	}
+	if operation == PatchOperationAdd {
+		return p.add(container, attr, value)
+	}
+	return p.replace(container, attr, value)
+}
+
+// applyWithoutPath applies an "add" or "replace" operation without a path. The value contains the attributes that are
+// to be added or replaced, attributes of extensions are grouped by the id of the extension.
+func (p patcher) applyWithoutPath(attributes map[string]interface{}, operation string, value interface{}) error {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return patchErrorInvalidValue("The value of an operation without a path must be a complex value.")
+	}
+	defer p.cleanup(attributes)
+
+	for k, v := range m {
+		var extension *schema.Schema
+		for _, ext := range p.extensions {
+			if strings.EqualFold(k, ext.ID) {
+				ext := ext
+				extension = &ext
+				break
+			}
+		}
+
+		if extension != nil {
+			extValues, ok := v.(map[string]interface{})
+			if !ok {
+				return patchErrorInvalidValue(fmt.Sprintf("The value of the extension %s must be a complex value.", extension.ID))
+			}
+			for name, v := range extValues {
+				path := filter.AttributePath{
+					URIPrefix:     &extension.ID,
+					AttributeName: name,
+				}
+				if err := p.applyValue(attributes, path, operation, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, err := filter.ParseAttrPath([]byte(k))
+		if err != nil {
+			return patchErrorInvalidPath(fmt.Sprintf("Invalid attribute name: %s.", k))
+		}
+		if err := p.applyValue(attributes, path, operation, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSubAttributes checks whether the given complex value only contains known sub-attributes that may be modified.
+// The current value is used to check whether immutable sub-attributes are already assigned.
+func (p patcher) checkSubAttributes(attr schema.CoreAttribute, value, current map[string]interface{}) error {
+	for k, v := range value {
+		subAttr, ok := attr.SubAttributes().ContainsAttribute(k)
+		if !ok {
+			return patchErrorInvalidValue(fmt.Sprintf("The attribute %s has no sub-attribute named %s.", attr.Name(), k))
+		}
+		existing, exists := getPatchAttribute(current, k)
+		// Validated values contain all sub-attributes, of which the unassigned ones are nil.
+		if v == nil || exists && reflect.DeepEqual(existing, v) {
+			continue
+		}
+		if err := checkPatchMutability(subAttr, exists && !isEmptyPatchValue(existing)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanup removes the extensions that no longer contain any attributes.
+func (p patcher) cleanup(attributes map[string]interface{}) {
+	for _, extension := range p.extensions {
+		if v, ok := getPatchAttribute(attributes, extension.ID); ok && isEmptyPatchValue(v) {
+			deletePatchAttribute(attributes, extension.ID)
+		}
+	}
+}
+
+// merge merges the sub-attributes of the given complex value into the current value of a singular complex attribute.
+// Sub-attributes that are not specified are left unchanged.
+func (p patcher) merge(container map[string]interface{}, attr schema.CoreAttribute, value interface{}) error {
+	if value == nil {
+		setPatchAttribute(container, attr.Name(), nil)
+		return nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return patchErrorInvalidValue(fmt.Sprintf("The value of %s must be a complex value.", attr.Name()))
+	}
+
+	current, _ := getPatchAttribute(container, attr.Name())
+	element, _ := copyPatchValue(current).(map[string]interface{})
+	if element == nil {
+		element = make(map[string]interface{})
+	}
+	if err := p.checkSubAttributes(attr, m, element); err != nil {
+		return err
+	}
+	for k, v := range m {
+		subAttr, _ := attr.SubAttributes().ContainsAttribute(k)
+		setPatchAttribute(element, subAttr.Name(), copyPatchValue(v))
+	}
+	setPatchAttribute(container, attr.Name(), element)
+	return nil
+}
+
+// remove removes the attribute, as defined in RFC 7644, Section 3.5.2.2.
+func (p patcher) remove(container map[string]interface{}, attr schema.CoreAttribute) error {
+	current, exists := getPatchAttribute(container, attr.Name())
+	if !exists || isEmptyPatchValue(current) {
+		return nil
+	}
+	if err := checkPatchMutability(attr, true); err != nil {
+		return err
+	}
+	deletePatchAttribute(container, attr.Name())
+	return nil
+}
+
+// removeValues removes the given values from a multi-valued attribute, see Compatibility.RemoveValues. Complex values
+// are removed if they match all sub-attributes of one of the given complex values.
+func (p patcher) removeValues(container map[string]interface{}, attr schema.CoreAttribute, value interface{}) error {
+	matches := func(v interface{}) bool {
+		for _, removed := range patchValues(value) {
+			m, ok := removed.(map[string]interface{})
+			element, isComplex := v.(map[string]interface{})
+			if !ok || !isComplex {
+				if reflect.DeepEqual(v, removed) {
+					return true
+				}
+				continue
+			}
+
+			match := true
+			for k, e := range m {
+				if current, _ := getPatchAttribute(element, k); !reflect.DeepEqual(current, e) {
+					match = false
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+		return false
+	}
+
+	current, _ := getPatchAttribute(container, attr.Name())
+	values := patchValues(current)
+	remaining := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if !matches(v) {
+			remaining = append(remaining, v)
+		}
+	}
+	if len(remaining) == len(values) {
+		return nil
+	}
+	if err := checkPatchMutability(attr, true); err != nil {
+		return err
+	}
+	setPatchAttribute(container, attr.Name(), remaining)
+	return nil
+}
+
+// replace replaces the value of the attribute, as defined in RFC 7644, Section 3.5.2.3.
+func (p patcher) replace(container map[string]interface{}, attr schema.CoreAttribute, value interface{}) error {
+	current, exists := getPatchAttribute(container, attr.Name())
+	if exists && reflect.DeepEqual(current, value) {
+		return nil
+	}
+	if err := checkPatchMutability(attr, exists && !isEmptyPatchValue(current)); err != nil {
+		return err
+	}
+
+	switch {
+	case attr.MultiValued():
+		values := patchValues(copyPatchValue(value))
+		for i, v := range values {
+			if attr.HasSubAttributes() {
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return patchErrorInvalidValue(fmt.Sprintf("The values of %s must be complex values.", attr.Name()))
+				}
+				if err := p.checkSubAttributes(attr, m, nil); err != nil {
+					return err
+				}
+				updatePrimary(values, i)
+			}
+		}
+		setPatchAttribute(container, attr.Name(), values)
+	case attr.HasSubAttributes():
+		return p.merge(container, attr, value)
+	default:
+		setPatchAttribute(container, attr.Name(), value)
+	}
+	return nil
+}
+
+// resolve returns the map that contains the attribute with the given path and its definition. Attributes of
+// extensions are contained within an object named after the id of the extension, which is created if it does not
+// exist yet and create is true. The returned map is nil if it does not exist.
+func (p patcher) resolve(attributes map[string]interface{}, path filter.AttributePath, create bool) (map[string]interface{}, schema.CoreAttribute, error) {
+	uri := path.URI()
+	if uri == "" || strings.EqualFold(uri, p.schema.ID) {
+		for _, attrs := range []schema.Attributes{p.schema.Attributes, schema.CommonAttributes()} {
+			if attr, ok := attrs.ContainsAttribute(path.AttributeName); ok {
+				return attributes, attr, nil
+			}
+		}
+		if uri != "" {
+			return nil, schema.CoreAttribute{}, patchErrorInvalidPath(fmt.Sprintf("Unknown attribute: %s.", path.AttributeName))
+		}
+	}
+
+	for _, extension := range p.extensions {
+		if uri != "" && !strings.EqualFold(uri, extension.ID) {
+			continue
+		}
+		attr, ok := extension.Attributes.ContainsAttribute(path.AttributeName)
+		if !ok {
+			continue
+		}
+
+		value, _ := getPatchAttribute(attributes, extension.ID)
+		container, ok := value.(map[string]interface{})
+		if !ok || container == nil {
+			if value != nil && !ok {
+				return nil, schema.CoreAttribute{}, patchErrorInvalidValue(fmt.Sprintf("The value of the extension %s must be a complex value.", extension.ID))
+			}
+			if !create {
+				return nil, attr, nil
+			}
+			container = make(map[string]interface{})
+			attributes[extension.ID] = container
+		}
+		return container, attr, nil
+	}
+	return nil, schema.CoreAttribute{}, patchErrorInvalidPath(fmt.Sprintf("Unknown attribute: %s.", path.String()))
+}
@@ -0,0 +1,314 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const enterprise = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	newUser := func() ResourceAttributes {
+		return ResourceAttributes{
+			"userName": "alice",
+			"name": map[string]interface{}{
+				"givenName":  "Alice",
+				"familyName": "Smith",
+			},
+			"emails": []interface{}{
+				map[string]interface{}{"value": "alice@work.com", "type": "work", "primary": true},
+				map[string]interface{}{"value": "alice@home.com", "type": "home"},
+			},
+			enterprise: map[string]interface{}{
+				"employeeNumber": "42",
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		op       string
+		path     string
+		value    interface{}
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "add simple attribute",
+			op:       "add",
+			path:     "displayName",
+			value:    "Alice Smith",
+			expected: `{"displayName":"Alice Smith","emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "add multi-valued attribute with new primary",
+			op:       "add",
+			path:     "emails",
+			value:    []interface{}{map[string]interface{}{"value": "alice@other.com", "primary": true}},
+			expected: `{"emails":[{"primary":false,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"},{"primary":true,"value":"alice@other.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "add existing value",
+			op:       "add",
+			path:     "emails",
+			value:    map[string]interface{}{"value": "alice@home.com", "type": "home"},
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+		},
+		{
+			name:     "add complex attribute merges sub-attributes",
+			op:       "add",
+			path:     "name",
+			value:    map[string]interface{}{"middleName": "Jane"},
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice","middleName":"Jane"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "add without path",
+			op:       "add",
+			value:    map[string]interface{}{"nickName": "Al", enterprise: map[string]interface{}{"department": "Research"}},
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"nickName":"Al","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Research","employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "add extension attribute",
+			op:       "add",
+			path:     enterprise + ":costCenter",
+			value:    "4130",
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"costCenter":"4130","employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "replace sub-attribute",
+			op:       "replace",
+			path:     "name.givenName",
+			value:    "Alicia",
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alicia"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "replace with value filter",
+			op:       "replace",
+			path:     `emails[type eq "work"].value`,
+			value:    "alice@job.com",
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@job.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "replace multi-valued attribute",
+			op:       "replace",
+			path:     "emails",
+			value:    []interface{}{map[string]interface{}{"value": "alice@new.com"}},
+			expected: `{"emails":[{"value":"alice@new.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "replace with the same value",
+			op:       "replace",
+			path:     "userName",
+			value:    "alice",
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+		},
+		{
+			name:     "remove with value filter",
+			op:       "remove",
+			path:     `emails[type eq "home"]`,
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "remove sub-attribute of all values",
+			op:       "remove",
+			path:     "emails.type",
+			expected: `{"emails":[{"primary":true,"value":"alice@work.com"},{"value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "remove last extension attribute",
+			op:       "remove",
+			path:     enterprise + ":employeeNumber",
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"userName":"alice"}`,
+			changed:  true,
+		},
+		{
+			name:     "remove unassigned attribute",
+			op:       "remove",
+			path:     "nickName",
+			expected: `{"emails":[{"primary":true,"type":"work","value":"alice@work.com"},{"type":"home","value":"alice@home.com"}],"name":{"familyName":"Smith","givenName":"Alice"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"42"},"userName":"alice"}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			user := newUser()
+			patched, changed, err := ApplyPatch(schema.CoreUserSchema(), []schema.Schema{schema.ExtensionEnterpriseUser()}, user, PatchRequest{
+				Operations: []PatchOperation{newTestPatchOperation(t, test.op, test.path, test.value)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, _ := json.Marshal(patched)
+			assertEqual(t, test.expected, string(raw))
+			assertEqual(t, test.changed, changed)
+
+			// The given attributes must be left untouched.
+			original, _ := json.Marshal(newUser())
+			raw, _ = json.Marshal(user)
+			assertEqual(t, string(original), string(raw))
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	group := ResourceAttributes{
+		"displayName": "Tour Guides",
+		"members": []interface{}{
+			map[string]interface{}{"value": "0001", "type": "User"},
+		},
+	}
+
+	for _, test := range []struct {
+		name     string
+		op       string
+		path     string
+		value    interface{}
+		expected errors.ScimError
+	}{
+		{
+			name:     "no target",
+			op:       "replace",
+			path:     `members[value eq "0002"].type`,
+			value:    "Group",
+			expected: errors.ScimErrorNoTarget,
+		},
+		{
+			name:     "remove without path",
+			op:       "remove",
+			expected: errors.ScimErrorNoTarget,
+		},
+		{
+			name:     "immutable sub-attribute",
+			op:       "replace",
+			path:     `members[value eq "0001"].value`,
+			value:    "0002",
+			expected: errors.ScimErrorMutability,
+		},
+		{
+			name:     "read-only sub-attribute",
+			op:       "add",
+			path:     "members",
+			value:    map[string]interface{}{"value": "0002", "display": "Bob"},
+			expected: errors.ScimErrorMutability,
+		},
+		{
+			name:     "read-only attribute",
+			op:       "replace",
+			path:     "id",
+			value:    "0002",
+			expected: errors.ScimErrorMutability,
+		},
+		{
+			name:     "unknown attribute",
+			op:       "add",
+			value:    map[string]interface{}{"unknown": "value"},
+			expected: errors.ScimErrorInvalidPath,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := ApplyPatch(schema.CoreGroupSchema(), nil, group, PatchRequest{
+				Operations: []PatchOperation{newTestPatchOperation(t, test.op, test.path, test.value)},
+			})
+			scimErr, ok := err.(errors.ScimError)
+			if !ok {
+				t.Fatalf("expected a scim error, got %v", err)
+			}
+			assertEqualSCIMErrors(t, &test.expected, &scimErr)
+		})
+	}
+}
+
+func TestApplyPatchMembers(t *testing.T) {
+	group := ResourceAttributes{
+		"displayName": "Tour Guides",
+	}
+
+	patched, changed, err := ApplyPatch(schema.CoreGroupSchema(), nil, group, PatchRequest{
+		Operations: []PatchOperation{
+			newTestPatchOperation(t, "add", "members", []interface{}{
+				map[string]interface{}{"value": "0001"},
+				map[string]interface{}{"value": "0002"},
+				map[string]interface{}{"value": "0003"},
+			}),
+			newTestPatchOperation(t, "remove", `members[value eq "0002" or value eq "0003"]`, nil),
+			newTestPatchOperation(t, "remove", `members[value eq "0004"]`, nil),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, changed)
+
+	raw, _ := json.Marshal(patched)
+	assertEqual(t, `{"displayName":"Tour Guides","members":[{"value":"0001"}]}`, string(raw))
+
+	patched, changed, err = ApplyPatch(schema.CoreGroupSchema(), nil, patched, PatchRequest{
+		Operations: []PatchOperation{
+			newTestPatchOperation(t, "remove", `members[value eq "0001"]`, nil),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, changed)
+	raw, _ = json.Marshal(patched)
+	assertEqual(t, `{"displayName":"Tour Guides"}`, string(raw))
+}
+
+func TestApplyPatchUnassignedSubAttributes(t *testing.T) {
+	group := ResourceAttributes{
+		"displayName": "Tour Guides",
+		"members":     []interface{}{map[string]interface{}{"value": "0001"}},
+	}
+
+	// Validated values contain all sub-attributes, e.g., the readOnly display of which the value is nil.
+	patched, changed, err := ApplyPatch(schema.CoreGroupSchema(), nil, group, PatchRequest{
+		Operations: []PatchOperation{
+			newTestPatchOperation(t, "add", "members", []interface{}{
+				map[string]interface{}{"value": "0002", "display": nil, "$ref": nil, "type": nil},
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, changed)
+	assertLen(t, patched["members"].([]interface{}), 2)
+
+	_, _, err = ApplyPatch(schema.CoreGroupSchema(), nil, group, PatchRequest{
+		Operations: []PatchOperation{
+			newTestPatchOperation(t, "add", "members", []interface{}{
+				map[string]interface{}{"value": "0002", "display": "Bob"},
+			}),
+		},
+	})
+	scimErr, ok := err.(errors.ScimError)
+	assertTrue(t, ok)
+	assertEqual(t, errors.ScimErrorMutability.ScimType, scimErr.ScimType)
+}
+
+func newTestPatchOperation(t *testing.T, op, path string, value interface{}) PatchOperation {
+	operation := PatchOperation{
+		Op:    op,
+		Value: value,
+	}
+	if path != "" {
+		p, err := filter.ParsePath([]byte(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		operation.Path = &p
+	}
+	return operation
+}
@@ -0,0 +1,167 @@
+package scim
+
+import (
+	"fmt"
+
+	"github.com/elimity-com/scim/errors"
+	f "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// PatchBuilder builds a PATCH request of which the paths of the operations are valid paths within a schema and its
+// extensions. The paths are validated as they would be by the server, so the built request is accepted by servers
+// with the same schemas, as far as the paths are concerned.
+//
+//	req, err := scim.NewPatchBuilder(schema.CoreGroupSchema()).
+//		Replace("displayName", "Admins").
+//		AddMembers("2819c223").
+//		RemoveMember("902c246b").
+//		Build()
+//
+// Invalid operations are reported by Build, all at once.
+type PatchBuilder struct {
+	schema     schema.Schema
+	extensions []schema.Schema
+
+	operations []PatchOperation
+	violations errors.Violations
+}
+
+// NewPatchBuilder returns a builder of PATCH requests for resources of the given schema and extensions. Like the server,
+// the builder accepts paths of the common attributes that can be modified, i.e., "externalId".
+func NewPatchBuilder(s schema.Schema, extensions ...schema.Schema) *PatchBuilder {
+	return &PatchBuilder{
+		schema:     ResourceType{Schema: s}.schemaWithCommon(),
+		extensions: extensions,
+	}
+}
+
+// Add adds an "add" operation that adds the given value to the attribute with the given path, e.g., "emails" or
+// "name.givenName".
+func (b *PatchBuilder) Add(path string, value interface{}) *PatchBuilder {
+	return b.operation(PatchOperationAdd, path, value)
+}
+
+// AddAttributes adds an "add" operation without path, that adds the given attribute values to the resource.
+func (b *PatchBuilder) AddAttributes(values map[string]interface{}) *PatchBuilder {
+	return b.operation(PatchOperationAdd, "", values)
+}
+
+// AddMembers adds an "add" operation that adds members with the given identifiers to the "members" attribute of a
+// group.
+func (b *PatchBuilder) AddMembers(ids ...string) *PatchBuilder {
+	members := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, map[string]interface{}{"value": id})
+	}
+	return b.Add("members", members)
+}
+
+// Build returns the PATCH request, or a SCIM error that lists the invalid operations, see errors.Violations.
+func (b *PatchBuilder) Build() (PatchRequest, error) {
+	if err := b.violations.Err(); err != nil {
+		return PatchRequest{}, *err
+	}
+	if len(b.operations) == 0 {
+		return PatchRequest{}, errors.ScimError{
+			ScimType: errors.ScimErrorInvalidValue.ScimType,
+			Detail:   errors.ScimErrorInvalidValue.Detail + " Zero operations found in request body.",
+			Status:   errors.ScimErrorInvalidValue.Status,
+		}
+	}
+	return PatchRequest{
+		Schemas:    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		Operations: append([]PatchOperation(nil), b.operations...),
+	}, nil
+}
+
+// Remove adds a "remove" operation that removes the attribute with the given path, e.g., "nickName" or
+// `emails[type eq "work"]`.
+func (b *PatchBuilder) Remove(path string) *PatchBuilder {
+	return b.operation(PatchOperationRemove, path, nil)
+}
+
+// RemoveMember adds a "remove" operation that removes the member with the given identifier from the "members"
+// attribute of a group.
+func (b *PatchBuilder) RemoveMember(id string) *PatchBuilder {
+	return b.RemoveWhere("members", &filter.AttributeExpression{
+		AttributePath: filter.AttributePath{AttributeName: "value"},
+		Operator:      filter.EQ,
+		CompareValue:  id,
+	})
+}
+
+// RemoveWhere adds a "remove" operation that removes the values of the given multi-valued attribute that match the
+// given filter, e.g., the emails of which the type is "work".
+func (b *PatchBuilder) RemoveWhere(attribute string, where filter.Expression) *PatchBuilder {
+	return b.operationWhere(PatchOperationRemove, attribute, where, "", nil)
+}
+
+// Replace adds a "replace" operation that replaces the value of the attribute with the given path with the given
+// value.
+func (b *PatchBuilder) Replace(path string, value interface{}) *PatchBuilder {
+	return b.operation(PatchOperationReplace, path, value)
+}
+
+// ReplaceAttributes adds a "replace" operation without path, that replaces the values of the given attributes.
+func (b *PatchBuilder) ReplaceAttributes(values map[string]interface{}) *PatchBuilder {
+	return b.operation(PatchOperationReplace, "", values)
+}
+
+// ReplaceWhere adds a "replace" operation that replaces the given sub-attribute of the values of the given
+// multi-valued attribute that match the given filter, e.g., the value of the emails of which the type is "work". The
+// matching values are replaced as a whole if the sub-attribute is empty.
+func (b *PatchBuilder) ReplaceWhere(attribute string, where filter.Expression, subAttribute string, value interface{}) *PatchBuilder {
+	return b.operationWhere(PatchOperationReplace, attribute, where, subAttribute, value)
+}
+
+// operation adds an operation with the given path and value, if the path is a valid path within the schemas of the
+// builder. Otherwise the violation is reported by Build.
+func (b *PatchBuilder) operation(op, path string, value interface{}) *PatchBuilder {
+	violation := func(scimType errors.ScimType, detail string) *PatchBuilder {
+		b.violations = append(b.violations, errors.Violation{
+			Path:     fmt.Sprintf("Operations[%d]", len(b.operations)+len(b.violations)),
+			ScimType: scimType,
+			Detail:   detail,
+		})
+		return b
+	}
+
+	if op != PatchOperationRemove && value == nil {
+		return violation(errors.ScimTypeInvalidValue, "The value of an add or replace operation can not be null.")
+	}
+	operation := PatchOperation{
+		Op:    op,
+		Value: value,
+	}
+	if path != "" {
+		validator, err := f.NewPathValidator(path, b.schema, b.extensions...)
+		if err == nil {
+			err = validator.Validate()
+		}
+		if err != nil {
+			return violation(errors.ScimTypeInvalidPath, "Invalid path: "+err.Error())
+		}
+		p := validator.Path()
+		operation.Path = &p
+	}
+	b.operations = append(b.operations, operation)
+	return b
+}
+
+// operationWhere adds an operation of which the path is the given sub-attribute, if any, of the values of the given
+// multi-valued attribute that match the given filter. The path keeps the given filter instead of the parsed one, since
+// the parser keeps the escape sequences of string values, which would be escaped again when the path is formatted.
+func (b *PatchBuilder) operationWhere(op, attribute string, where filter.Expression, subAttribute string, value interface{}) *PatchBuilder {
+	path := fmt.Sprintf("%s[%s]", attribute, f.FormatExpression(where))
+	if subAttribute != "" {
+		path += "." + subAttribute
+	}
+	n := len(b.operations)
+	b.operation(op, path, value)
+	if len(b.operations) > n {
+		b.operations[n].Path.ValueExpression = where
+	}
+	return b
+}
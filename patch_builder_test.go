@@ -0,0 +1,107 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestPatchBuilder(t *testing.T) {
+	work := &filter.AttributeExpression{
+		AttributePath: filter.AttributePath{AttributeName: "type"},
+		Operator:      filter.EQ,
+		CompareValue:  "work",
+	}
+	req, err := NewPatchBuilder(schema.CoreUserSchema(), schema.ExtensionEnterpriseUser()).
+		Add("emails", []interface{}{map[string]interface{}{"type": "work", "value": "bjensen@example.com"}}).
+		ReplaceWhere("emails", work, "value", "babs@example.com").
+		Replace("urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber", "701984").
+		ReplaceAttributes(map[string]interface{}{"displayName": "Babs Jensen"}).
+		RemoveWhere("emails", work).
+		Remove("nickName").
+		Replace("externalId", "bjensen").
+		Build()
+	assertTrue(t, err == nil)
+
+	raw, err := json.Marshal(req)
+	assertTrue(t, err == nil)
+	var wire struct {
+		Schemas    []string
+		Operations []map[string]interface{}
+	}
+	assertUnmarshalNoError(t, json.Unmarshal(raw, &wire))
+	assertEqual(t, "urn:ietf:params:scim:api:messages:2.0:PatchOp", wire.Schemas[0])
+	assertLen(t, wire.Operations, 7)
+	assertEqual(t, `emails[type eq "work"].value`, wire.Operations[1]["path"])
+	_, ok := wire.Operations[3]["path"]
+	assertTrue(t, !ok)
+	_, ok = wire.Operations[5]["value"]
+	assertTrue(t, !ok)
+
+	// The built request is accepted by the server.
+	resourceType := newTestServer().ResourceTypes[1]
+	resourceType.Schema = schema.CoreUserSchema()
+	resourceType.SchemaExtensions = []SchemaExtension{{Schema: schema.ExtensionEnterpriseUser()}}
+	validated, scimErr := resourceType.validatePatch(httptest.NewRequest(http.MethodPatch, "/Users/0001", bytes.NewReader(raw)))
+	assertTrue(t, scimErr == nil)
+	assertLen(t, validated.Operations, 7)
+}
+
+func TestPatchBuilderInvalid(t *testing.T) {
+	_, err := NewPatchBuilder(schema.CoreUserSchema()).
+		Replace("userName", "bjensen").
+		Add("invalid", "value").
+		Replace("displayName", nil).
+		Build()
+	scimErr, ok := err.(errors.ScimError)
+	if !ok {
+		t.Fatalf("expected a scim error, got %v", err)
+	}
+	assertEqual(t, errors.ScimTypeInvalidPath, scimErr.ScimType)
+	assertTrue(t, strings.Contains(scimErr.Detail, "Operations[1]: Invalid path"))
+	assertTrue(t, strings.Contains(scimErr.Detail, "Operations[2]: The value of an add or replace operation can not be null."))
+
+	_, err = NewPatchBuilder(schema.CoreUserSchema()).Build()
+	assertTrue(t, err != nil)
+}
+
+func TestPatchBuilderMembers(t *testing.T) {
+	s := newTestServer()
+	req, err := NewPatchBuilder(schema.CoreGroupSchema()).
+		AddMembers("0001", "0002").
+		RemoveMember(`a"b`).
+		Build()
+	assertTrue(t, err == nil)
+	raw, _ := json.Marshal(req)
+
+	validated, scimErr := s.ResourceTypes[2].validatePatch(httptest.NewRequest(http.MethodPatch, "/Groups/0001", bytes.NewReader(raw)))
+	assertTrue(t, scimErr == nil)
+	assertEqual(t, `members[value eq "a\"b"]`, filterPath(req.Operations[1]))
+	// The server keeps the escape sequences of the value filter as they are written.
+	assertEqual(t, `a\"b`, validated.Operations[1].Path.ValueExpression.(*filter.AttributeExpression).CompareValue)
+
+	// The built request can be applied as it is.
+	patched, _, err := ApplyPatch(schema.CoreGroupSchema(), nil, ResourceAttributes{
+		"displayName": "Admins",
+		"members":     []interface{}{map[string]interface{}{"value": `a"b`}, map[string]interface{}{"value": "0003"}},
+	}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLen(t, patched["members"].([]interface{}), 3)
+}
+
+// filterPath returns the formatted path of the given operation.
+func filterPath(op PatchOperation) string {
+	raw, _ := json.Marshal(op)
+	var wire struct{ Path string }
+	_ = json.Unmarshal(raw, &wire)
+	return wire.Path
+}
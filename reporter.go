@@ -0,0 +1,81 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// setErrorContext annotates the error context of the given request with the resource type and the identifier of the
+// resource it targets. It has no effect on requests that are not served by a Server.
+func setErrorContext(r *http.Request, resourceType, id string) {
+	if c, ok := r.Context().Value(errorContextKey{}).(*errorContext); ok {
+		c.resourceType = resourceType
+		c.id = id
+	}
+}
+
+// withErrorContext returns a shallow copy of the given request with an empty error context.
+func withErrorContext(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), errorContextKey{}, &errorContext{}))
+}
+
+// ErrorReport describes a failure that occurred while serving a request, such as a response that could not be
+// marshaled or written, or a panic of a resource handler.
+type ErrorReport struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request URL.
+	Path string
+	// ResourceType is the name of the resource type the request targets, if any.
+	ResourceType string
+	// ID is the identifier of the resource the request targets, if any.
+	ID string
+	// Err is the error that occurred. A *PanicError is reported for recovered panics.
+	Err error
+}
+
+// ErrorReporter reports failures that occurred while serving a request. These failures are not returned to the client
+// in detail, instead the client receives an internal server error if the response was not written yet.
+type ErrorReporter interface {
+	// ReportError reports the given failure that occurred while serving the given request.
+	ReportError(r *http.Request, report ErrorReport)
+}
+
+// PanicError is reported when a panic is recovered while serving a request.
+type PanicError struct {
+	// Value is the value that was passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// errorContext holds the resource type and the identifier of the resource a request targets, which are included in
+// the reported failures of that request.
+type errorContext struct {
+	resourceType string
+	id           string
+}
+
+// errorContextKey is the context key of the error context of a request.
+type errorContextKey struct{}
+
+// logErrorReporter reports failures with the standard logger. It is used if the server has no error reporter.
+type logErrorReporter struct{}
+
+func (logErrorReporter) ReportError(_ *http.Request, report ErrorReport) {
+	msg := fmt.Sprintf("scim: %s %s", report.Method, report.Path)
+	if report.ResourceType != "" {
+		msg += fmt.Sprintf(" (resource type: %s", report.ResourceType)
+		if report.ID != "" {
+			msg += fmt.Sprintf(", id: %s", report.ID)
+		}
+		msg += ")"
+	}
+	log.Printf("%s: %v", msg, report.Err)
+}
@@ -0,0 +1,94 @@
+package scim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerErrorReporterMarshalFailure(t *testing.T) {
+	s := newTestServer()
+	reporter := &testErrorReporter{}
+	s.ErrorReporter = reporter
+	s.ResourceTypes[0].Handler.(testResourceHandler).data["0001"].resourceAttributes["displayName"] = make(chan int)
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v2/Users/0001", nil))
+
+	assertEqualStatusCode(t, http.StatusInternalServerError, rr.Code)
+	assertLen(t, reporter.reports, 1)
+	report := reporter.reports[0]
+	assertEqual(t, http.MethodGet, report.Method)
+	assertEqual(t, "/v2/Users/0001", report.Path)
+	assertEqual(t, "User", report.ResourceType)
+	assertEqual(t, "0001", report.ID)
+	assertStringStartsWith(t, "failed marshaling resource", report.Err.Error())
+}
+
+func TestServerErrorReporterPanic(t *testing.T) {
+	s := newTestServer()
+	reporter := &testErrorReporter{}
+	s.ErrorReporter = reporter
+	s.ResourceTypes[2].Handler = testPanicHandler{
+		ResourceHandler: s.ResourceTypes[2].Handler,
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v2/Groups/0001", nil))
+
+	assertEqualStatusCode(t, http.StatusInternalServerError, rr.Code)
+	assertLen(t, reporter.reports, 1)
+	report := reporter.reports[0]
+	assertEqual(t, "Group", report.ResourceType)
+	assertEqual(t, "0001", report.ID)
+	panicErr, ok := report.Err.(*PanicError)
+	if !ok {
+		t.Fatalf("expected a panic error, got %v", report.Err)
+	}
+	assertEqual(t, "delete", panicErr.Value)
+	assertTrue(t, strings.Contains(string(panicErr.Stack), "testPanicHandler"))
+}
+
+func TestServerErrorReporterPanicBulk(t *testing.T) {
+	s := newTestServer()
+	s.Config.SupportBulk = true
+	reporter := &testErrorReporter{}
+	s.ErrorReporter = reporter
+	s.ResourceTypes[2].Handler = testPanicHandler{
+		ResourceHandler: s.ResourceTypes[2].Handler,
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v2/Bulk", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+		"Operations": [
+			{"method": "DELETE", "path": "/Groups/0001"},
+			{"method": "DELETE", "path": "/Users/0001"}
+		]
+	}`)))
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+	assertTrue(t, strings.Contains(rr.Body.String(), `"status":"500"`))
+	assertTrue(t, strings.Contains(rr.Body.String(), `"status":"204"`))
+	assertLen(t, reporter.reports, 1)
+	assertEqual(t, "/Groups/0001", reporter.reports[0].Path)
+}
+
+// testErrorReporter collects all reported failures.
+type testErrorReporter struct {
+	reports []ErrorReport
+}
+
+func (e *testErrorReporter) ReportError(_ *http.Request, report ErrorReport) {
+	e.reports = append(e.reports, report)
+}
+
+// testPanicHandler panics on deletion of resources of the wrapped handler.
+type testPanicHandler struct {
+	ResourceHandler
+}
+
+func (h testPanicHandler) Delete(r *http.Request, id string) error {
+	panic("delete")
+}
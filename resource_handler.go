@@ -13,14 +13,29 @@ import (
 
 // ListRequestParams request parameters sent to the API via a "GetAll" route.
 type ListRequestParams struct {
+	// Attributes is a list of attribute names that SHALL be returned, overriding the set of attributes that would be
+	// returned by default. Attributes that are not listed are omitted from the response by the server.
+	Attributes []string
+
 	// Count specifies the desired maximum number of query results per page. A negative value SHALL be interpreted as "0".
 	// A value of "0" indicates that no resource results are to be returned except for "totalResults".
 	Count int
 
+	// ExcludedAttributes is a list of attribute names that SHALL be removed from the set of attributes that would be
+	// returned by default. Attributes that are always returned can not be excluded.
+	ExcludedAttributes []string
+
 	// Filter represents the parsed and tokenized filter query parameter.
 	// It is an optional parameter and thus will be nil when the parameter is not present.
 	Filter filter.Expression
 
+	// SortBy represents the parsed attribute path of the "sortBy" query parameter, which specifies the attribute whose
+	// value SHALL be used to order the returned responses. It is nil when the parameter is not present.
+	SortBy *filter.AttributePath
+
+	// SortOrder specifies the order in which the "sortBy" parameter is applied. Defaults to "ascending".
+	SortOrder SortOrder
+
 	// StartIndex The 1-based index of the first query result. A value less than 1 SHALL be interpreted as 1.
 	StartIndex int
 }
@@ -6,14 +6,54 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/elimity-com/scim/errors"
-	"github.com/elimity-com/scim/internal/filter"
+	"github.com/elimity-com/scim/filter"
 	"github.com/elimity-com/scim/optional"
 	"github.com/elimity-com/scim/schema"
 )
 
+// unknownAttributes returns the names of the keys of the given values that do not refer to one of the given
+// attributes or, for complex values, to one of the sub-attributes of its attribute, e.g., "emails[1].tpye". Values of
+// the wrong type are left to the validation of the schema.
+func unknownAttributes(attributes schema.Attributes, values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var unknown []string
+	for _, k := range keys {
+		attr, ok := attributes.ContainsAttribute(k)
+		if !ok {
+			unknown = append(unknown, k)
+			continue
+		}
+		if !attr.HasSubAttributes() {
+			continue
+		}
+
+		switch v := values[k].(type) {
+		case map[string]interface{}:
+			for _, name := range unknownAttributes(attr.SubAttributes(), v) {
+				unknown = append(unknown, k+"."+name)
+			}
+		case []interface{}:
+			for i, v := range v {
+				if m, ok := v.(map[string]interface{}); ok {
+					for _, name := range unknownAttributes(attr.SubAttributes(), m) {
+						unknown = append(unknown, fmt.Sprintf("%s[%d].%s", k, i, name))
+					}
+				}
+			}
+		}
+	}
+	return unknown
+}
+
 // unmarshal unifies the unmarshal of the requests.
 func unmarshal(data []byte, v interface{}) error {
 	d := json.NewDecoder(bytes.NewReader(data))
@@ -39,6 +79,99 @@ type ResourceType struct {
 
 	// Handler is the set of callback method that connect the SCIM server with a provider of the resource type.
 	Handler ResourceHandler
+	// ContextHandler is used instead of Handler if it is not nil. Its methods receive a context and the information of
+	// the request instead of the HTTP request itself.
+	ContextHandler ContextResourceHandler
+
+	// Strict enables the strict validation of the bodies of POST and PUT requests. Attributes, sub-attributes and
+	// schema extensions that are not defined by the resource type are rejected instead of being ignored, and the
+	// "schemas" attribute needs to list the schema and each schema extension that is present in the body.
+	Strict bool
+}
+
+// checkIfMatch evaluates the "If-Match" precondition of the given request against the current version of the
+// resource with the given identifier. Returns an 412 SCIM error if the version does not match.
+func (t ResourceType) checkIfMatch(r *http.Request, id string) error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+
+	var version string
+	if versioner, ok := t.versioner(); ok {
+		v, err := versioner.Version(r, id)
+		if err != nil {
+			return err
+		}
+		version = v
+	} else {
+		resource, err := t.handler().Get(r, id)
+		if err != nil {
+			return err
+		}
+		version = resource.Meta.Version
+	}
+
+	if !matchETag(ifMatch, version, false) {
+		return errors.ScimErrorPreconditionFailed
+	}
+	return nil
+}
+
+// checkPatchUniqueness applies the given PATCH request to the current attributes of the resource with the given
+// identifier and checks the uniqueness of the result, see checkUniqueness. Only requests of which an operation targets
+// a unique attribute are checked. Requests that can not be applied are left to the handler, which may support paths or
+// values that ApplyPatch does not.
+func (t ResourceType) checkPatchUniqueness(r *http.Request, id string, req PatchRequest) error {
+	if _, ok := t.uniquenessChecker(); !ok {
+		return nil
+	}
+	extensions := t.getSchemaExtensions(r)
+	attributes := UniqueAttributes(t.Schema, extensions...)
+	var touched bool
+	for _, op := range req.Operations {
+		touched = touched || touchesUniqueAttribute(op, attributes)
+	}
+	if !touched {
+		return nil
+	}
+
+	resource, err := t.handler().Get(r, id)
+	if err != nil {
+		return err
+	}
+	patched, changed, err := CompatibilityFromContext(r.Context()).ApplyPatch(t.schemaWithCommon(), extensions, resource.Attributes, req)
+	if err != nil || !changed {
+		return nil
+	}
+	return t.checkUniqueness(r, id, patched)
+}
+
+// checkUniqueness checks whether the values of the unique attributes of the given attributes are not in use by another
+// resource than the one with the given identifier, if the handler implements the UniquenessChecker interface. Returns
+// an 409 SCIM error naming the attribute of which the value is in use.
+func (t ResourceType) checkUniqueness(r *http.Request, id string, attributes ResourceAttributes) error {
+	checker, ok := t.uniquenessChecker()
+	if !ok {
+		return nil
+	}
+
+	for _, attr := range UniqueAttributes(t.Schema, t.getSchemaExtensions(r)...) {
+		value, ok := attr.Value(attributes)
+		if !ok {
+			continue
+		}
+		ids, err := checker.LookupUnique(r, attr, value)
+		if err != nil {
+			return err
+		}
+		for _, other := range ids {
+			if other != id {
+				return errors.ScimErrorNotUnique(attr.Path())
+			}
+		}
+	}
+	return nil
 }
 
 func (t ResourceType) getRaw() map[string]interface{} {
@@ -67,15 +200,22 @@ func (t ResourceType) getRawSchemaExtensions() []map[string]interface{} {
 func (t ResourceType) getSchemaExtensions(r *http.Request) []schema.Schema {
 	var extensions []schema.Schema
 	for _, e := range t.SchemaExtensions {
-		if e.LoadDynamically {
-			extensions = append(extensions, e.SchemaLoader.LoadSchema(r))
-		} else {
-			extensions = append(extensions, e.Schema)
-		}
+		extensions = append(extensions, loadSchema(r, e))
 	}
 	return extensions
 }
 
+// handler returns the resource handler of the resource type, adapting the context handler if it is set.
+func (t ResourceType) handler() ResourceHandler {
+	if t.ContextHandler != nil {
+		return contextResourceHandler{
+			handler:      t.ContextHandler,
+			resourceType: t.Name,
+		}
+	}
+	return t.Handler
+}
+
 func (t ResourceType) schemaWithCommon() schema.Schema {
 	s := t.Schema
 
@@ -93,50 +233,70 @@ func (t ResourceType) schemaWithCommon() schema.Schema {
 	return s
 }
 
+// uniquenessChecker returns the uniqueness checker of the handler of the resource type, if the handler implements the
+// UniquenessChecker interface, or the ContextUniquenessChecker interface for a context handler.
+func (t ResourceType) uniquenessChecker() (UniquenessChecker, bool) {
+	if t.ContextHandler != nil {
+		checker, ok := t.ContextHandler.(ContextUniquenessChecker)
+		if !ok {
+			return nil, false
+		}
+		return contextUniquenessChecker{
+			checker:      checker,
+			resourceType: t.Name,
+		}, true
+	}
+	checker, ok := t.Handler.(UniquenessChecker)
+	return checker, ok
+}
+
 func (t ResourceType) validate(raw []byte, method string, r *http.Request) (ResourceAttributes, *errors.ScimError) {
 	var m map[string]interface{}
 	if err := unmarshal(raw, &m); err != nil {
 		return ResourceAttributes{}, &errors.ScimErrorInvalidSyntax
 	}
 
-	attributes, scimErr := t.schemaWithCommon().Validate(m)
-	if scimErr != nil {
-		return ResourceAttributes{}, scimErr
+	// All violations are collected, so they can be reported at once.
+	var violations errors.Violations
+	if t.Strict {
+		violations = append(violations, t.validateStrict(m, r)...)
 	}
 
+	quirks := CompatibilityFromContext(r.Context()).quirks()
+	attributes, coreViolations := t.schemaWithCommon().ValidateAll(m, quirks)
+	violations = append(violations, coreViolations...)
+
 	for _, extension := range t.SchemaExtensions {
 		extensionField := m[extension.Schema.ID]
 		if extensionField == nil {
 			if extension.Required {
-				err := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidValue.ScimType,
-					Detail:   errors.ScimErrorInvalidValue.Detail + " Missing extension name: " + extension.Schema.Name.Value() + ", Extension ID: " + extension.Schema.ID,
-					Status:   errors.ScimErrorInvalidValue.Status,
-				}
-				return ResourceAttributes{}, &err
+				violations = append(violations, errors.Violation{
+					Path:     extension.Schema.ID,
+					ScimType: errors.ScimTypeInvalidValue,
+					Detail:   "Missing extension name: " + extension.Schema.Name.Value() + ".",
+				})
 			}
 			continue
 		}
 
-		var extensionAttributes map[string]interface{}
-		var scimErr *errors.ScimError
-
-		if extension.LoadDynamically {
-			extensionAttributes, scimErr = extension.SchemaLoader.LoadSchema(r).Validate(extensionField)
-		} else {
-			extensionAttributes, scimErr = extension.Schema.Validate(extensionField)
-		}
-		if scimErr != nil {
-			return ResourceAttributes{}, scimErr
+		extensionAttributes, extensionViolations := loadSchema(r, extension).ValidateAll(extensionField, quirks)
+		if len(extensionViolations) != 0 {
+			violations = append(violations, extensionViolations.WithPrefix(extension.Schema.ID+":")...)
+			continue
 		}
 
-		attributes[extension.Schema.ID] = extensionAttributes
+		if attributes != nil {
+			attributes[extension.Schema.ID] = extensionAttributes
+		}
 	}
 
+	if len(violations) != 0 {
+		return ResourceAttributes{}, violations.Err()
+	}
 	return attributes, nil
 }
 
-func (t ResourceType) validateOperationValue(op PatchOperation, r *http.Request) (map[string]interface{}, *errors.ScimError) {
+func (t ResourceType) validateOperationValue(op PatchOperation, r *http.Request) (map[string]interface{}, errors.Violations) {
 	var (
 		path             = op.Path
 		attributeName    = path.AttributePath.AttributeName
@@ -164,21 +324,18 @@ func (t ResourceType) validateOperationValue(op PatchOperation, r *http.Request)
 	}
 
 	// Check if it's a patch on an extension.
+	quirks := CompatibilityFromContext(r.Context()).quirks()
 	if attributeName != "" {
 		if id := path.AttributePath.URI(); id != "" {
 			for _, ext := range t.SchemaExtensions {
 				if strings.EqualFold(id, ext.Schema.ID) {
-					if ext.LoadDynamically {
-						return ext.SchemaLoader.LoadSchema(r).ValidatePatchOperation(op.Op, mapValue, true)
-					} else {
-						return ext.Schema.ValidatePatchOperation(op.Op, mapValue, true)
-					}
+					return loadSchema(r, ext).ValidatePatchOperationAll(op.Op, mapValue, true, quirks)
 				}
 			}
 		}
 	}
 
-	return t.schemaWithCommon().ValidatePatchOperationValue(op.Op, mapValue)
+	return t.schemaWithCommon().ValidatePatchOperationAll(op.Op, mapValue, false, quirks)
 }
 
 // validatePatch parse and validate PATCH request.
@@ -220,75 +377,72 @@ func (t ResourceType) validatePatch(r *http.Request) (PatchRequest, *errors.Scim
 		return PatchRequest{}, &err
 	}
 
-	// Evaluation continues until all operations are successfully applied or until an error condition is encountered.
+	// All operations are validated, so the violations of all operations can be reported at once.
 	patchReq := PatchRequest{
 		Schemas: req.Schemas,
 	}
+	var violations errors.Violations
+	compatibility := CompatibilityFromContext(r.Context())
 	for index, v := range req.Operations {
+		operation := fmt.Sprintf("Operations[%d]", index)
+		invalidOperationPath := func(err error) errors.Violation {
+			return errors.Violation{
+				Path:     operation,
+				ScimType: errors.ScimErrorInvalidPath.ScimType,
+				Detail:   "Invalid path: " + err.Error(),
+			}
+		}
+
 		validator, err := filter.NewPathValidator(v.Path, t.schemaWithCommon(), t.getSchemaExtensions(r)...)
 		switch v.Op = strings.ToLower(v.Op); v.Op {
 		case PatchOperationAdd, PatchOperationReplace:
-			// Removed to allow Null values through on Add and Replace operations - to accomodate an Azure AD known issue when we may need to send a different default value in case of encountering a null - eg. a whitespace, and then logically translate it to a null which normally would be a removal but in those cases it comes through as a replace or add - PATCH - additional field - add and wipe using whitespace - test passed
-			// if v.Value == nil {
-			// 	err := errors.ScimError{
-			// 		ScimType: errors.ScimErrorInvalidFilter.ScimType,
-			// 		Detail:   errors.ScimErrorInvalidFilter.Detail + " Operation number: " + fmt.Sprint(index+1) + ", has a null value.",
-			// 		Status:   errors.ScimErrorInvalidFilter.Status,
-			// 	}
-			// 	return PatchRequest{}, &err
-			// }
+			if v.Value == nil && !compatibility.NullPatchValues {
+				violations = append(violations, errors.Violation{
+					Path:     operation,
+					ScimType: errors.ScimErrorInvalidValue.ScimType,
+					Detail:   "The value of an add or replace operation can not be null.",
+				})
+				continue
+			}
 			if v.Path != "" && err != nil {
-				err2 := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidPath.ScimType,
-					Detail:   errors.ScimErrorInvalidPath.Detail + " Operation number: " + fmt.Sprint(index+1) + ", has failed validation. " + err.Error(),
-					Status:   errors.ScimErrorInvalidPath.Status,
-				}
-				return PatchRequest{}, &err2
+				violations = append(violations, invalidOperationPath(err))
+				continue
 			}
 		case PatchOperationRemove:
 			if err != nil {
-				err2 := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidPath.ScimType,
-					Detail:   errors.ScimErrorInvalidPath.Detail + " Operation number: " + fmt.Sprint(index+1) + ", has failed validation. " + err.Error(),
-					Status:   errors.ScimErrorInvalidPath.Status,
-				}
-				return PatchRequest{}, &err2
+				violations = append(violations, invalidOperationPath(err))
+				continue
 			}
 		default:
-			err := errors.ScimError{
+			violations = append(violations, errors.Violation{
+				Path:     operation,
 				ScimType: errors.ScimErrorInvalidFilter.ScimType,
-				Detail:   errors.ScimErrorInvalidFilter.Detail + " Operation number: " + fmt.Sprint(index+1) + ", has an unrecognized operation type.",
-				Status:   errors.ScimErrorInvalidFilter.Status,
-			}
-			return PatchRequest{}, &err
+				Detail:   "Unrecognized operation type.",
+			})
+			continue
 		}
 		op := PatchOperation{
 			Op:    strings.ToLower(v.Op),
 			Value: v.Value,
 		}
+		if v.Path == "" && compatibility.IgnoreReadOnlyValues {
+			op.Value = t.withoutReadOnlyValues(op.Value, r)
+		}
 
 		// If err is nil, then it means that there is a valid path.
 		if err == nil {
 			if err := validator.Validate(); err != nil {
-				err2 := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidPath.ScimType,
-					Detail:   errors.ScimErrorInvalidPath.Detail + " Operation number: " + fmt.Sprint(index+1) + ", has failed validation. " + err.Error(),
-					Status:   errors.ScimErrorInvalidPath.Status,
-				}
-				return PatchRequest{}, &err2
+				violations = append(violations, invalidOperationPath(err))
+				continue
 			}
 			p := validator.Path()
 			op.Path = &p
 
-			val, err := t.validateOperationValue(op, r)
+			val, valueViolations := t.validateOperationValue(op, r)
 
-			if err != nil {
-				err2 := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidValue.ScimType,
-					Detail:   errors.ScimErrorInvalidValue.Detail + " Operation number: " + fmt.Sprint(index+1) + ", has failed validation. " + err.Error(),
-					Status:   errors.ScimErrorInvalidValue.Status,
-				}
-				return PatchRequest{}, &err2
+			if len(valueViolations) != 0 {
+				violations = append(violations, valueViolations.WithPrefix(operation+".")...)
+				continue
 			} else {
 				// set the value here - this is to support any coercion of auto-correction that may have happend in the validator itself - eg. coercing text booleans, back into boolean types - allowance for Azure AD not complying with the SCIM specification in certain places
 				if op.Path.AttributePath.SubAttributeName() == "" {
@@ -316,9 +470,115 @@ func (t ResourceType) validatePatch(r *http.Request) (PatchRequest, *errors.Scim
 		patchReq.Operations = append(patchReq.Operations, op)
 	}
 
+	if len(violations) != 0 {
+		return PatchRequest{}, violations.Err()
+	}
 	return patchReq, nil
 }
 
+// validateStrict checks whether the given resource only contains attributes that are defined by the schema or the
+// schema extensions of the resource type, and whether its "schemas" attribute lists the schemas that are used.
+func (t ResourceType) validateStrict(resource map[string]interface{}, r *http.Request) errors.Violations {
+	var violations errors.Violations
+	invalidSyntax := func(path, detail string) {
+		violations = append(violations, errors.Violation{
+			Path:     path,
+			ScimType: errors.ScimTypeInvalidSyntax,
+			Detail:   detail,
+		})
+	}
+	unknown := func(prefix string, names []string) {
+		for _, name := range names {
+			if strings.HasPrefix(strings.ToLower(name), "urn:") {
+				invalidSyntax(prefix+name, "Undeclared schema extension.")
+				continue
+			}
+			violations = append(violations, errors.Violation{
+				Path:     prefix + name,
+				ScimType: errors.ScimTypeInvalidValue,
+				Detail:   "Unknown attribute.",
+			})
+		}
+	}
+
+	extensions := t.getSchemaExtensions(r)
+	var schemas interface{}
+	var present []string
+	values := make(map[string]interface{})
+	for k, v := range resource {
+		switch {
+		case strings.EqualFold(k, "schemas"):
+			schemas = v
+			continue
+		case strings.EqualFold(k, schema.CommonAttributeID), strings.EqualFold(k, schema.CommonAttributeMeta):
+			// Read-only common attributes are ignored.
+			continue
+		}
+
+		var extension *schema.Schema
+		for i, e := range extensions {
+			if strings.EqualFold(k, e.ID) {
+				extension = &extensions[i]
+			}
+		}
+		if extension == nil {
+			values[k] = v
+			continue
+		}
+
+		present = append(present, extension.ID)
+		if m, ok := v.(map[string]interface{}); ok {
+			unknown(extension.ID+":", unknownAttributes(extension.Attributes, m))
+		}
+	}
+	unknown("", unknownAttributes(t.schemaWithCommon().Attributes, values))
+
+	list, ok := schemas.([]interface{})
+	if !ok {
+		invalidSyntax("schemas", "The schemas attribute is missing or is not a list of schema URIs.")
+		return violations
+	}
+	var uris []string
+	for i, v := range list {
+		uri, ok := v.(string)
+		if !ok {
+			invalidSyntax(fmt.Sprintf("schemas[%d]", i), "Schema URI is not a string.")
+			continue
+		}
+		declared := strings.EqualFold(uri, t.Schema.ID)
+		for _, e := range extensions {
+			declared = declared || strings.EqualFold(uri, e.ID)
+		}
+		if !declared {
+			invalidSyntax(fmt.Sprintf("schemas[%d]", i), "Undeclared schema: "+uri+".")
+		}
+		uris = append(uris, strings.ToLower(uri))
+	}
+	for _, id := range append([]string{t.Schema.ID}, present...) {
+		if !contains(uris, strings.ToLower(id)) {
+			invalidSyntax("schemas", "The schemas attribute does not contain the schema: "+id+".")
+		}
+	}
+	return violations
+}
+
+// versioner returns the versioner of the handler of the resource type, if the handler implements the Versioner
+// interface, or the ContextVersioner interface for a context handler.
+func (t ResourceType) versioner() (Versioner, bool) {
+	if t.ContextHandler != nil {
+		versioner, ok := t.ContextHandler.(ContextVersioner)
+		if !ok {
+			return nil, false
+		}
+		return contextVersioner{
+			versioner:    versioner,
+			resourceType: t.Name,
+		}, true
+	}
+	versioner, ok := t.Handler.(Versioner)
+	return versioner, ok
+}
+
 // SchemaExtension is one of the resource type's schema extensions.
 type SchemaExtension struct {
 	// Schema is the URI of an extended schema, e.g., "urn:edu:2.0:Staff".
@@ -338,3 +598,37 @@ type SchemaLoader interface {
 	// loads the schema in an arbitrary way - request is included for context
 	LoadSchema(r *http.Request) schema.Schema
 }
+
+// withoutReadOnlyValues returns a copy of the given value of an operation without a path, without the attributes that
+// are read-only, see Compatibility.IgnoreReadOnlyValues. Attributes of extensions are grouped by the id of the
+// extension. Values that are not complex are returned as is.
+func (t ResourceType) withoutReadOnlyValues(value interface{}, r *http.Request) interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	without := func(m map[string]interface{}, attributes ...schema.Attributes) map[string]interface{} {
+		values := make(map[string]interface{}, len(m))
+	Values:
+		for k, v := range m {
+			for _, attrs := range attributes {
+				if attr, ok := attrs.ContainsAttribute(k); ok && attr.Mutability() == "readOnly" {
+					continue Values
+				}
+			}
+			values[k] = v
+		}
+		return values
+	}
+
+	values := without(m, t.Schema.Attributes, schema.CommonAttributes())
+	for _, extension := range t.getSchemaExtensions(r) {
+		if key, ok := patchAttributeKey(values, extension.ID); ok {
+			if extensionValues, ok := values[key].(map[string]interface{}); ok {
+				values[key] = without(extensionValues, extension.Attributes)
+			}
+		}
+	}
+	return values
+}
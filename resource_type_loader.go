@@ -0,0 +1,57 @@
+package scim
+
+import (
+	"net/http"
+	"time"
+)
+
+// ResourceTypeCache is a resource type loader that caches the resource types of another loader per tenant, see
+// TenantFromContext. Failed loads are not cached. It is safe for concurrent use.
+type ResourceTypeCache struct {
+	loader        ResourceTypeLoader
+	resourceTypes *cache
+}
+
+// NewResourceTypeCache returns a resource type loader that caches the resource types that are loaded by the given
+// loader for the given duration. Resource types are cached until they are invalidated if the duration is zero.
+func NewResourceTypeCache(loader ResourceTypeLoader, ttl time.Duration) *ResourceTypeCache {
+	return &ResourceTypeCache{
+		loader:        loader,
+		resourceTypes: newCache(ttl),
+	}
+}
+
+// Invalidate removes the resource types of the given tenant from the cache, so they are loaded again on the next
+// request. The tenant is empty for servers that serve a single tenant.
+func (c *ResourceTypeCache) Invalidate(tenant string) {
+	c.resourceTypes.invalidate(tenant)
+}
+
+// InvalidateAll removes the resource types of all tenants from the cache.
+func (c *ResourceTypeCache) InvalidateAll() {
+	c.resourceTypes.invalidateAll()
+}
+
+// LoadResourceTypes returns the cached resource types of the tenant of the given request, or loads them with the
+// underlying loader if they are not cached or expired.
+func (c *ResourceTypeCache) LoadResourceTypes(r *http.Request) ([]ResourceType, error) {
+	tenant := TenantFromContext(r.Context())
+	if cached, ok := c.resourceTypes.get(tenant); ok {
+		return cached.([]ResourceType), nil
+	}
+
+	resourceTypes, err := c.loader.LoadResourceTypes(r)
+	if err != nil {
+		return nil, err
+	}
+	c.resourceTypes.set(tenant, resourceTypes)
+	return resourceTypes, nil
+}
+
+// ResourceTypeLoader loads resource types for each request, so resource types can be added, changed or removed
+// without restarting the server, e.g., based on the configuration of a tenant.
+type ResourceTypeLoader interface {
+	// LoadResourceTypes returns the resource types that are served in addition to the resource types of the server.
+	// They are used to route the request and are listed by the "/ResourceTypes" and "/Schemas" endpoints.
+	LoadResourceTypes(r *http.Request) ([]ResourceType, error)
+}
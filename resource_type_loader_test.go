@@ -0,0 +1,82 @@
+package scim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/optional"
+	"github.com/elimity-com/scim/schema"
+)
+
+func TestServerResourceTypeLoader(t *testing.T) {
+	loader := &testResourceTypeLoader{}
+	resourceTypes := NewResourceTypeCache(loader, 0)
+	s := newTestServer()
+	s.ResourceTypeLoader = resourceTypes
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+
+	assertEqualStatusCode(t, http.StatusOK, get("/v2/Users/0001").Code)
+	assertEqualStatusCode(t, http.StatusNotFound, get("/v2/Entitlements/0001").Code)
+
+	// Changes only take effect once the cached resource types are invalidated.
+	loader.enabled = true
+	assertEqualStatusCode(t, http.StatusNotFound, get("/v2/Entitlements/0001").Code)
+	resourceTypes.Invalidate("")
+	assertEqualStatusCode(t, http.StatusOK, get("/v2/Entitlements/0001").Code)
+	assertEqualStatusCode(t, http.StatusOK, get("/v2/ResourceTypes/Entitlement").Code)
+	assertTrue(t, strings.Contains(get("/v2/ResourceTypes").Body.String(), `"totalResults":4`))
+	assertEqualStatusCode(t, http.StatusOK, get("/v2/Schemas/urn:example:params:scim:schemas:core:2.0:Entitlement").Code)
+	assertEqual(t, 2, loader.loads)
+
+	// Failed loads are reported to the client and not cached.
+	loader.err = errors.ScimErrorInternal
+	resourceTypes.InvalidateAll()
+	assertEqualStatusCode(t, http.StatusInternalServerError, get("/v2/Users/0001").Code)
+	loader.err = nil
+	assertEqualStatusCode(t, http.StatusOK, get("/v2/Entitlements/0001").Code)
+	assertEqual(t, 4, loader.loads)
+
+	// The resource types of the server are not modified.
+	assertLen(t, s.ResourceTypes, 3)
+}
+
+// testResourceTypeLoader loads an "Entitlement" resource type if it is enabled.
+type testResourceTypeLoader struct {
+	enabled bool
+	err     error
+	loads   int
+}
+
+func (l *testResourceTypeLoader) LoadResourceTypes(_ *http.Request) ([]ResourceType, error) {
+	l.loads++
+	if l.err != nil {
+		return nil, l.err
+	}
+	if !l.enabled {
+		return nil, nil
+	}
+	return []ResourceType{{
+		ID:       optional.NewString("Entitlement"),
+		Name:     "Entitlement",
+		Endpoint: "/Entitlements",
+		Schema: schema.Schema{
+			ID:   "urn:example:params:scim:schemas:core:2.0:Entitlement",
+			Name: optional.NewString("Entitlement"),
+			Attributes: []schema.CoreAttribute{
+				schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+					Name:     "value",
+					Required: true,
+				})),
+			},
+		},
+		Handler: newTestResourceHandler(),
+	}}, nil
+}
@@ -0,0 +1,182 @@
+package scim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elimity-com/scim/errors"
+)
+
+func TestResourceTypeStrict(t *testing.T) {
+	const (
+		userSchema      = `"urn:ietf:params:scim:schemas:core:2.0:User"`
+		extensionSchema = `"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+	)
+
+	s := newTestServer()
+	for i := range s.ResourceTypes {
+		s.ResourceTypes[i].Strict = true
+	}
+
+	for _, test := range []struct {
+		name     string
+		endpoint string
+		body     string
+		scimType errors.ScimType
+		detail   string
+	}{
+		{
+			name:     "valid",
+			endpoint: "/Users",
+			body:     `{"schemas": [` + userSchema + `], "id": "0001", "userName": "test", "name": {"givenName": "Test"}}`,
+		},
+		{
+			name:     "valid extension",
+			endpoint: "/EnterpriseUsers",
+			body:     `{"schemas": [` + userSchema + `, ` + extensionSchema + `], "userName": "test", ` + extensionSchema + `: {"employeeNumber": "1"}}`,
+		},
+		{
+			name:     "unknown attribute",
+			endpoint: "/Users",
+			body:     `{"schemas": [` + userSchema + `], "usrName": "test"}`,
+			scimType: errors.ScimTypeInvalidValue,
+			detail:   "usrName: Unknown attribute.",
+		},
+		{
+			name:     "unknown sub-attribute",
+			endpoint: "/Users",
+			body:     `{"schemas": [` + userSchema + `], "userName": "test", "name": {"givenNme": "Test"}}`,
+			scimType: errors.ScimTypeInvalidValue,
+			detail:   "name.givenNme: Unknown attribute.",
+		},
+		{
+			name:     "unknown extension attribute",
+			endpoint: "/EnterpriseUsers",
+			body:     `{"schemas": [` + userSchema + `, ` + extensionSchema + `], "userName": "test", ` + extensionSchema + `: {"employeeNr": "1"}}`,
+			scimType: errors.ScimTypeInvalidValue,
+			detail:   "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNr: Unknown attribute.",
+		},
+		{
+			name:     "undeclared extension",
+			endpoint: "/Users",
+			body:     `{"schemas": [` + userSchema + `], "userName": "test", ` + extensionSchema + `: {"employeeNumber": "1"}}`,
+			scimType: errors.ScimTypeInvalidSyntax,
+			detail:   "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User: Undeclared schema extension.",
+		},
+		{
+			name:     "missing schemas",
+			endpoint: "/Users",
+			body:     `{"userName": "test"}`,
+			scimType: errors.ScimTypeInvalidSyntax,
+			detail:   "The schemas attribute is missing",
+		},
+		{
+			name:     "undeclared schema",
+			endpoint: "/Users",
+			body:     `{"schemas": [` + userSchema + `, ` + extensionSchema + `], "userName": "test"}`,
+			scimType: errors.ScimTypeInvalidSyntax,
+			detail:   "schemas[1]: Undeclared schema: urn:ietf:params:scim:schemas:extension:enterprise:2.0:User.",
+		},
+		{
+			name:     "missing extension schema",
+			endpoint: "/EnterpriseUsers",
+			body:     `{"schemas": [` + userSchema + `], "userName": "test", ` + extensionSchema + `: {"employeeNumber": "1"}}`,
+			scimType: errors.ScimTypeInvalidSyntax,
+			detail:   "The schemas attribute does not contain the schema: urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, method := range []string{http.MethodPost, http.MethodPut} {
+				target := "/v2" + test.endpoint
+				if method == http.MethodPut {
+					target += "/0001"
+				}
+				rr := httptest.NewRecorder()
+				s.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(test.body)))
+
+				if test.scimType == "" {
+					assertTrue(t, rr.Code == http.StatusCreated || rr.Code == http.StatusOK)
+					continue
+				}
+				assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+				var scimErr errors.ScimError
+				assertUnmarshalNoError(t, scimErr.UnmarshalJSON(rr.Body.Bytes()))
+				assertEqual(t, test.scimType, scimErr.ScimType)
+				assertTrue(t, strings.Contains(scimErr.Detail, test.detail))
+			}
+		})
+	}
+
+	// Unknown attributes are ignored if the resource type is not strict.
+	s = newTestServer()
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v2/Users", strings.NewReader(`{"userName": "test", "usrName": "test"}`)))
+	assertEqualStatusCode(t, http.StatusCreated, rr.Code)
+}
+
+func TestResourceTypeViolations(t *testing.T) {
+	s := newTestServer()
+
+	for _, test := range []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		violations []string
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			target: "/v2/EnterpriseUsers",
+			body: `{
+				"active": "maybe",
+				"name": {"givenName": 1, "familyName": true},
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": 1}
+			}`,
+			violations: []string{
+				"userName: Required attribute is missing.",
+				"active: Boolean attribute not the right type.",
+				"Name.familyName: String attribute value is not of the right type.",
+				"Name.givenName: String attribute value is not of the right type.",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber: String attribute value is not of the right type.",
+			},
+		},
+		{
+			name:   "patch",
+			method: http.MethodPatch,
+			target: "/v2/Users/0001",
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+				"Operations": [
+					{"op": "replace", "path": "active", "value": "maybe"},
+					{"op": "replace", "path": "userName", "value": "test"},
+					{"op": "move", "path": "userName"},
+					{"op": "remove", "path": "usrName"}
+				]
+			}`,
+			violations: []string{
+				"Operations[0].active: Boolean attribute not the right type.",
+				"Operations[2]: Unrecognized operation type.",
+				"Operations[3]: Invalid path:",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+			assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+
+			var scimErr errors.ScimError
+			assertUnmarshalNoError(t, scimErr.UnmarshalJSON(rr.Body.Bytes()))
+			assertEqual(t, errors.ScimTypeInvalidValue, scimErr.ScimType)
+			assertStringStartsWith(t, errors.ScimErrorInvalidValue.Detail, scimErr.Detail)
+			for _, violation := range test.violations {
+				if !strings.Contains(scimErr.Detail, violation) {
+					t.Errorf("expected violation %q in %q", violation, scimErr.Detail)
+				}
+			}
+		})
+	}
+}
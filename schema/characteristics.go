@@ -6,16 +6,59 @@ import (
 	"regexp"
 )
 
+// attributeNameRegex matches names that start w/ a A-Za-z followed by a A-Za-z0-9, a dollar sign, a hyphen or an
+// underscore.
+var attributeNameRegex = regexp.MustCompile(`^[A-Za-z][\w$-]*$`)
+
 func checkAttributeName(name string) {
-	// starts w/ a A-Za-z followed by a A-Za-z0-9, a dollar sign, a hyphen or an underscore
-	match, err := regexp.MatchString(`^[A-Za-z][\w$-]*$`, name)
-	if err != nil {
-		panic(err)
+	if !validAttributeName(name) {
+		panic(fmt.Sprintf("invalid attribute name %q", name))
 	}
+}
 
-	if !match {
-		panic(fmt.Sprintf("invalid attribute name %q", name))
+// parseMutability returns the mutability with the given keyword, e.g., "readOnly".
+func parseMutability(keyword string) (attributeMutability, error) {
+	for v := attributeMutabilityReadWrite; v <= attributeMutabilityWriteOnly; v++ {
+		if v.String() == keyword {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mutability %q", keyword)
+}
+
+// parseReturned returns the returned characteristic with the given keyword, e.g., "always".
+func parseReturned(keyword string) (attributeReturned, error) {
+	for v := attributeReturnedDefault; v <= attributeReturnedRequest; v++ {
+		if v.String() == keyword {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown returned %q", keyword)
+}
+
+// parseType returns the type with the given keyword, e.g., "dateTime".
+func parseType(keyword string) (attributeType, error) {
+	for v := attributeDataTypeDecimal; v <= attributeDataTypeString; v++ {
+		if v.String() == keyword {
+			return v, nil
+		}
 	}
+	return 0, fmt.Errorf("unknown type %q", keyword)
+}
+
+// parseUniqueness returns the uniqueness with the given keyword, e.g., "server".
+func parseUniqueness(keyword string) (attributeUniqueness, error) {
+	for v := attributeUniquenessNone; v <= attributeUniquenessServer; v++ {
+		if v.String() == keyword {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown uniqueness %q", keyword)
+}
+
+// validAttributeName checks whether the given name is a valid attribute name.
+func validAttributeName(name string) bool {
+	return attributeNameRegex.MatchString(name)
 }
 
 // AttributeDataType is a single keyword indicating the derived data type from JSON.
@@ -136,18 +179,35 @@ const (
 )
 
 func (a attributeMutability) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a attributeMutability) String() string {
 	switch a {
 	case attributeMutabilityImmutable:
-		return json.Marshal("immutable")
+		return "immutable"
 	case attributeMutabilityReadOnly:
-		return json.Marshal("readOnly")
+		return "readOnly"
 	case attributeMutabilityWriteOnly:
-		return json.Marshal("writeOnly")
+		return "writeOnly"
 	default:
-		return json.Marshal("readWrite")
+		return "readWrite"
 	}
 }
 
+func (a *attributeMutability) UnmarshalJSON(data []byte) error {
+	var keyword string
+	if err := json.Unmarshal(data, &keyword); err != nil {
+		return err
+	}
+	v, err := parseMutability(keyword)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
 type attributeReturned int
 
 const (
@@ -158,18 +218,35 @@ const (
 )
 
 func (a attributeReturned) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a attributeReturned) String() string {
 	switch a {
 	case attributeReturnedAlways:
-		return json.Marshal("always")
+		return "always"
 	case attributeReturnedNever:
-		return json.Marshal("never")
+		return "never"
 	case attributeReturnedRequest:
-		return json.Marshal("request")
+		return "request"
 	default:
-		return json.Marshal("default")
+		return "default"
 	}
 }
 
+func (a *attributeReturned) UnmarshalJSON(data []byte) error {
+	var keyword string
+	if err := json.Unmarshal(data, &keyword); err != nil {
+		return err
+	}
+	v, err := parseReturned(keyword)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
 type attributeType int
 
 const (
@@ -209,6 +286,19 @@ func (a attributeType) String() string {
 	}
 }
 
+func (a *attributeType) UnmarshalJSON(data []byte) error {
+	var keyword string
+	if err := json.Unmarshal(data, &keyword); err != nil {
+		return err
+	}
+	v, err := parseType(keyword)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
 type attributeUniqueness int
 
 const (
@@ -218,12 +308,29 @@ const (
 )
 
 func (a attributeUniqueness) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a attributeUniqueness) String() string {
 	switch a {
 	case attributeUniquenessGlobal:
-		return json.Marshal("global")
+		return "global"
 	case attributeUniquenessServer:
-		return json.Marshal("server")
+		return "server"
 	default:
-		return json.Marshal("none")
+		return "none"
+	}
+}
+
+func (a *attributeUniqueness) UnmarshalJSON(data []byte) error {
+	var keyword string
+	if err := json.Unmarshal(data, &keyword); err != nil {
+		return err
+	}
+	v, err := parseUniqueness(keyword)
+	if err != nil {
+		return err
 	}
+	*a = v
+	return nil
 }
@@ -28,9 +28,7 @@ type CoreAttribute struct {
 	uniqueness      attributeUniqueness
 }
 
-var (
-	validBooleanStrings = map[string]bool{"True": true, "False": false, "true": true, "false": false}
-)
+var validBooleanStrings = map[string]bool{"True": true, "False": false, "true": true, "false": false}
 
 // ComplexCoreAttribute creates a complex attribute based on given parameters.
 func ComplexCoreAttribute(params ComplexParams) CoreAttribute {
@@ -126,8 +124,7 @@ func (a CoreAttribute) MultiValued() bool {
 
 // Mutability returns the mutability of the attribute.
 func (a CoreAttribute) Mutability() string {
-	raw, _ := a.mutability.MarshalJSON()
-	return string(raw)
+	return a.mutability.String()
 }
 
 // Name returns the case insensitive name of the attribute.
@@ -147,8 +144,7 @@ func (a CoreAttribute) Required() bool {
 
 // Returned returns when the attribute need to be returned.
 func (a CoreAttribute) Returned() string {
-	raw, _ := a.returned.MarshalJSON()
-	return string(raw)
+	return a.returned.String()
 }
 
 // SubAttributes returns the sub attributes.
@@ -158,8 +154,7 @@ func (a CoreAttribute) SubAttributes() Attributes {
 
 // Uniqueness returns the attributes uniqueness.
 func (a CoreAttribute) Uniqueness() string {
-	raw, _ := a.uniqueness.MarshalJSON()
-	return string(raw)
+	return a.uniqueness.String()
 }
 
 func (a *CoreAttribute) getRawAttributes() map[string]interface{} {
@@ -198,7 +193,7 @@ func (a *CoreAttribute) getRawAttributes() map[string]interface{} {
 	return attributes
 }
 
-func (a CoreAttribute) validate(attribute interface{}) (interface{}, *errors.ScimError) {
+func (a CoreAttribute) validate(path string, attribute interface{}, quirks Quirks) (interface{}, errors.Violations) {
 	// whether or not the attribute is required.
 	if attribute == nil {
 		if !a.required {
@@ -206,12 +201,7 @@ func (a CoreAttribute) validate(attribute interface{}) (interface{}, *errors.Sci
 		}
 
 		// the attribute is not present but required.
-		err := errors.ScimError{
-			ScimType: errors.ScimErrorInvalidValue.ScimType,
-			Detail:   errors.ScimErrorInvalidValue.Detail + " Attribute name: " + a.name,
-			Status:   errors.ScimErrorInvalidValue.Status,
-		}
-		return nil, &err
+		return nil, invalidValue(path, "Required attribute is missing.")
 	}
 
 	// whether the value of the attribute can be (re)defined
@@ -221,79 +211,65 @@ func (a CoreAttribute) validate(attribute interface{}) (interface{}, *errors.Sci
 	}
 
 	if !a.multiValued {
-		return a.validateSingular(attribute)
+		return a.validateSingular(path, attribute, quirks)
 	}
 
 	switch arr := attribute.(type) {
 	case map[string]interface{}:
 		// return false if the multivalued attribute is empty.
 		if a.required && len(arr) == 0 {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Multivalued attribute was empty. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Multivalued attribute was empty.")
 		}
 
+		var violations errors.Violations
 		validMap := make(map[string]interface{}, len(arr))
 		for k, v := range arr {
 			for _, sub := range a.subAttributes {
 				if !strings.EqualFold(sub.name, k) {
 					continue
 				}
-				_, scimErr := sub.validate(v)
-				if scimErr != nil {
-					return nil, scimErr
+				if _, subViolations := sub.validate(path+"."+sub.name, v, quirks); len(subViolations) != 0 {
+					violations = append(violations, subViolations...)
+					continue
 				}
 				validMap[sub.name] = v
 			}
 		}
+		if len(violations) != 0 {
+			return nil, violations
+		}
 		return validMap, nil
 
 	case []interface{}:
 		// return false if the multivalued attribute is empty.
 		if a.required && len(arr) == 0 {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Multivalued attribute was empty. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Multivalued attribute was empty.")
 		}
 
+		var violations errors.Violations
 		attributes := make([]interface{}, len(arr))
 		for i, ele := range arr {
-			attr, scimErr := a.validateSingular(ele)
-			if scimErr != nil {
-				return nil, scimErr
-			}
+			attr, elementViolations := a.validateSingular(fmt.Sprintf("%s[%d]", path, i), ele, quirks)
+			violations = append(violations, elementViolations...)
 			attributes[i] = attr
 		}
+		if len(violations) != 0 {
+			return nil, violations
+		}
 		return attributes, nil
 
 	default:
 		// return false if the multivalued attribute is not a slice.
-		err := errors.ScimError{
-			ScimType: errors.ScimErrorInvalidValue.ScimType,
-			Detail:   errors.ScimErrorInvalidValue.Detail + " Multivalued attribute was not an array. Attribute name: " + a.name,
-			Status:   errors.ScimErrorInvalidValue.Status,
-		}
-		return nil, &err
+		return nil, invalidValue(path, "Multivalued attribute was not an array.")
 	}
 }
 
-func (a CoreAttribute) validateSingular(attribute interface{}) (interface{}, *errors.ScimError) {
+func (a CoreAttribute) validateSingular(path string, attribute interface{}, quirks Quirks) (interface{}, errors.Violations) {
 	switch a.typ {
 	case attributeDataTypeBinary:
 		bin, ok := attribute.(string)
 		if !ok {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Binary attribute not the right type. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Binary attribute not the right type.")
 		}
 
 		match, err := regexp.MatchString(`^([A-Za-z0-9+/]{4})*([A-Za-z0-9+/]{3}=|[A-Za-z0-9+/]{2}==)?$`, bin)
@@ -302,102 +278,40 @@ func (a CoreAttribute) validateSingular(attribute interface{}) (interface{}, *er
 		}
 
 		if !match {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Attribute contains illegal characters for type: binary. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Attribute contains illegal characters for type: binary.")
 		}
 
 		return bin, nil
 	case attributeDataTypeBoolean:
-		b, ok := attribute.(bool)
-
-		// Azure AD sends booleans as strings in their PATCH operations - officially unrecognized bug, bug reported by a lot of people around the web - needs special handling like other places
-		if !ok {
-			s, s_ok := attribute.(string)
-
-			// if a string, check if it's one of the right values
-			if s_ok {
-				if v, found := validBooleanStrings[s]; found {
-					b = v     // set the value to the b variable which we ultimately return
-					ok = true // overwrite the ok flag to prevent failure
-					return b, nil
-				} else {
-					ok = false // failure - our attempt to parse the string gracefully failed
-				}
-			} else {
-				ok = false // failure - our attempt to parse the string gracefully failed
-			}
-		}
-
-		if !ok {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Boolean attribute not the right type. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
+		switch b := attribute.(type) {
+		case bool:
+			return b, nil
+		case string:
+			if v, ok := validBooleanStrings[b]; ok && quirks.BooleanStrings {
+				return v, nil
 			}
-			return nil, &err
 		}
-
-		return b, nil
+		return nil, invalidValue(path, "Boolean attribute not the right type.")
 	case attributeDataTypeComplex:
 		complex, ok := attribute.(map[string]interface{})
 		if !ok {
-			// manager must receive special treatment on behalf of Azure AD who submit the following style requests (non-compliant)
-			/*
-				{
-					"schemas": [
-						"urn:ietf:params:scim:api:messages:2.0:PatchOp"
-					],
-					"Operations": [
-						{
-							"op": "Add",
-							"path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager", // <- this should end with manager.value
-							"value": "274" // or this should be an object like this: "value": { "value": 274 }
-						}
-					]
-				}
-			*/
-			if strings.EqualFold(strings.ToLower(a.name), "manager") {
-				if manager, ok := attribute.(string); ok {
-					return manager, nil // return the manager string
-				} else {
-					err := errors.ScimError{
-						ScimType: errors.ScimErrorInvalidValue.ScimType,
-						Detail:   errors.ScimErrorInvalidValue.Detail + " Complex attribute does not have the right structure. Attribute name: " + a.name,
-						Status:   errors.ScimErrorInvalidValue.Status,
-					}
-
-					return nil, &err
-				}
-			} else {
-				err := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidValue.ScimType,
-					Detail:   errors.ScimErrorInvalidValue.Detail + " Complex attribute does not have the right structure. Attribute name: " + a.name,
-					Status:   errors.ScimErrorInvalidValue.Status,
-				}
-
-				return nil, &err
+			if manager, ok := attribute.(string); ok && quirks.ManagerStrings && strings.EqualFold(a.name, "manager") {
+				return manager, nil
 			}
+			return nil, invalidValue(path, "Complex attribute does not have the right structure.")
 		}
 
+		var violations errors.Violations
 		attributes := make(map[string]interface{})
 
 		for _, sub := range a.subAttributes {
 			var hit interface{}
-			var found bool
+			var found, duplicate bool
 
 			for k, v := range complex {
 				if strings.EqualFold(sub.name, k) {
 					if found {
-						err := errors.ScimError{
-							ScimType: errors.ScimErrorInvalidValue.ScimType,
-							Detail:   errors.ScimErrorInvalidValue.Detail + " Duplicate attribute found inside of the complex attribute: " + a.name + ". Duplicate attribute name: " + sub.name,
-							Status:   errors.ScimErrorInvalidValue.Status,
-						}
-						return nil, &err
+						duplicate = true
 					}
 
 					found = true
@@ -405,32 +319,27 @@ func (a CoreAttribute) validateSingular(attribute interface{}) (interface{}, *er
 				}
 			}
 
-			attr, scimErr := sub.validate(hit)
-			if scimErr != nil {
-				return nil, scimErr
+			if duplicate {
+				violations = append(violations, invalidValue(path+"."+sub.name, "Duplicate attribute found inside of the complex attribute.")...)
+				continue
 			}
 
+			attr, subViolations := sub.validate(path+"."+sub.name, hit, quirks)
+			violations = append(violations, subViolations...)
 			attributes[sub.name] = attr
 		}
+		if len(violations) != 0 {
+			return nil, violations
+		}
 		return attributes, nil
 	case attributeDataTypeDateTime:
 		date, ok := attribute.(string)
 		if !ok {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Date time attribute does not have the right type. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Date time attribute does not have the right type.")
 		}
 		_, err := datetime.Parse(date)
 		if err != nil {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Date time attribute value is not in the right format - please ensure use supply date time in YYYY-MM-DDTHH:mm:ssZ format. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Date time attribute value is not in the right format - please ensure use supply date time in YYYY-MM-DDTHH:mm:ssZ format.")
 		}
 
 		return date, nil
@@ -439,79 +348,44 @@ func (a CoreAttribute) validateSingular(attribute interface{}) (interface{}, *er
 		case json.Number:
 			f, err := n.Float64()
 			if err != nil {
-				err := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidValue.ScimType,
-					Detail:   errors.ScimErrorInvalidValue.Detail + " Decimal attribute value failed to parse as a decimal. Attribute name: " + a.name,
-					Status:   errors.ScimErrorInvalidValue.Status,
-				}
-				return nil, &err
+				return nil, invalidValue(path, "Decimal attribute value failed to parse as a decimal.")
 			}
 
 			return f, nil
 		case float64:
 			return n, nil
 		default:
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Decimal attribute value failed submitted with wrong type. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Decimal attribute value failed submitted with wrong type.")
 		}
 	case attributeDataTypeInteger:
 		switch n := attribute.(type) {
 		case json.Number:
 			i, err := n.Int64()
 			if err != nil {
-				err := errors.ScimError{
-					ScimType: errors.ScimErrorInvalidValue.ScimType,
-					Detail:   errors.ScimErrorInvalidValue.Detail + " Integer attribute value failed to parse as an integer. Attribute name: " + a.name,
-					Status:   errors.ScimErrorInvalidValue.Status,
-				}
-				return nil, &err
+				return nil, invalidValue(path, "Integer attribute value failed to parse as an integer.")
 			}
 
 			return i, nil
 		case int, int8, int16, int32, int64:
 			return n, nil
 		default:
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Integer attribute value failed to parse as an integer. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Integer attribute value failed to parse as an integer.")
 		}
 	case attributeDataTypeReference:
 		s, ok := attribute.(string)
 		if !ok {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Reference attribute value is not of the right type. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "Reference attribute value is not of the right type.")
 		}
 
 		return s, nil
 	case attributeDataTypeString:
 		s, ok := attribute.(string)
 		if !ok {
-			err := errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " String attribute value is not of the right type. Attribute name: " + a.name,
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, &err
+			return nil, invalidValue(path, "String attribute value is not of the right type.")
 		}
 
 		return s, nil
 	default:
-		err := errors.ScimError{
-			ScimType: errors.ScimErrorInvalidValue.ScimType,
-			Detail:   errors.ScimErrorInvalidValue.Detail + " Unrecognized attribute type. Attribute name: " + a.name,
-			Status:   errors.ScimErrorInvalidValue.Status,
-		}
-		return nil, &err
+		return nil, invalidValue(path, "Unrecognized attribute type.")
 	}
 }
@@ -0,0 +1,18 @@
+package schema
+
+import (
+	"fmt"
+)
+
+// DefinitionError is returned when a schema definition is invalid, e.g., when a schema is converted from its json
+// representation.
+type DefinitionError struct {
+	// Path is the path of the invalid definition within the schema, e.g., "attributes[0].subAttributes[1]".
+	Path string
+	// Reason describes why the definition is invalid.
+	Reason string
+}
+
+func (e *DefinitionError) Error() string {
+	return fmt.Sprintf("invalid schema definition %s: %s", e.Path, e.Reason)
+}
@@ -0,0 +1,12 @@
+package schema
+
+// Quirks are deviations of the SCIM specification by clients that are accepted when attribute values are validated.
+// The zero value accepts none of them.
+type Quirks struct {
+	// BooleanStrings accepts the strings "true", "True", "false" and "False" as boolean values, which are converted to
+	// booleans.
+	BooleanStrings bool
+	// ManagerStrings accepts a string as value of the complex "manager" attribute, instead of a complex value with a
+	// "value" sub-attribute.
+	ManagerStrings bool
+}
@@ -20,6 +20,15 @@ func cannotBePatched(op string, attr CoreAttribute) bool {
 	return isImmutable(op, attr) || isReadOnly(attr)
 }
 
+// invalidValue returns an invalidValue violation of the attribute value at the given path.
+func invalidValue(path, detail string) errors.Violations {
+	return errors.Violations{{
+		Path:     path,
+		ScimType: errors.ScimTypeInvalidValue,
+		Detail:   detail,
+	}}
+}
+
 func isImmutable(op string, attr CoreAttribute) bool {
 	return attr.mutability == attributeMutabilityImmutable && (op == "replace" || op == "remove")
 }
@@ -64,24 +73,76 @@ func (s Schema) ToMap() map[string]interface{} {
 	}
 }
 
+// UnmarshalJSON converts the json representation of a schema to its corresponding schema struct. Omitted attribute
+// characteristics get their default value. A DefinitionError is returned if the definition is invalid, e.g., if an
+// attribute name is invalid or used twice.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Attributes  []json.RawMessage `json:"attributes"`
+		Description *string           `json:"description"`
+		ID          string            `json:"id"`
+		Name        *string           `json:"name"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.ID == "" {
+		return &DefinitionError{Path: "id", Reason: "the id of a schema is required"}
+	}
+
+	attributes, err := unmarshalAttributes("attributes", raw.Attributes, false)
+	if err != nil {
+		return err
+	}
+	schema := Schema{
+		Attributes: attributes,
+		ID:         raw.ID,
+	}
+	if raw.Description != nil {
+		schema.Description = optional.NewString(*raw.Description)
+	}
+	if raw.Name != nil {
+		schema.Name = optional.NewString(*raw.Name)
+	}
+	*s = schema
+	return nil
+}
+
 // Validate validates given resource based on the schema. Does NOT validate mutability.
 // NOTE: only used in POST and PUT requests where attributes MAY be (re)defined.
 func (s Schema) Validate(resource interface{}) (map[string]interface{}, *errors.ScimError) {
-	return s.validate(resource, false)
+	attributes, violations := s.validate(resource, false, Quirks{})
+	return attributes, violations.Err()
+}
+
+// ValidateAll validates given resource based on the schema, like Validate, but returns all violations instead of a
+// single SCIM error. The given quirks are accepted, see Quirks. The attributes are only returned if there are no
+// violations.
+func (s Schema) ValidateAll(resource interface{}, quirks Quirks) (map[string]interface{}, errors.Violations) {
+	return s.validate(resource, false, quirks)
 }
 
 // ValidateMutability validates given resource based on the schema, including strict immutability checks.
 func (s Schema) ValidateMutability(resource interface{}) (map[string]interface{}, *errors.ScimError) {
-	return s.validate(resource, true)
+	attributes, violations := s.validate(resource, true, Quirks{})
+	return attributes, violations.Err()
 }
 
 // ValidatePatchOperation validates an individual operation and its related value.
 func (s Schema) ValidatePatchOperation(operation string, operationValue map[string]interface{}, isExtension bool) (map[string]interface{}, *errors.ScimError) {
+	value, violations := s.ValidatePatchOperationAll(operation, operationValue, isExtension, Quirks{})
+	return value, violations.Err()
+}
+
+// ValidatePatchOperationAll validates an individual operation and its related value, like ValidatePatchOperation,
+// but returns all violations instead of a single SCIM error. The given quirks are accepted, see Quirks. The value is
+// only returned if there are no violations.
+func (s Schema) ValidatePatchOperationAll(operation string, operationValue map[string]interface{}, isExtension bool, quirks Quirks) (map[string]interface{}, errors.Violations) {
 	var value map[string]interface{} = make(map[string]interface{})
+	var violations errors.Violations
 
 	for k, v := range operationValue {
 		var attr *CoreAttribute
-		var scimErr *errors.ScimError
 
 		for _, attribute := range s.Attributes {
 			if strings.EqualFold(attribute.name, k) {
@@ -97,27 +158,23 @@ func (s Schema) ValidatePatchOperation(operation string, operationValue map[stri
 		// Attribute does not exist in the schema, thus it is an invalid request.
 		// Immutable attrs can only be added and Readonly attrs cannot be patched
 		if attr == nil || cannotBePatched(operation, *attr) {
-			scimErr = &errors.ScimError{
-				ScimType: errors.ScimErrorInvalidValue.ScimType,
-				Detail:   errors.ScimErrorInvalidValue.Detail + " Attribute " + attr.name + " does not exist in the schema, or is immutable in the schema, and therefore cannot be patched.",
-				Status:   errors.ScimErrorInvalidValue.Status,
-			}
-			return nil, scimErr
+			violations = append(violations, invalidValue(k, "Attribute does not exist in the schema, or is immutable in the schema, and therefore cannot be patched.")...)
+			continue
 		}
 
-		var newValue interface{}
-		newValue, scimErr = attr.validate(v)
-
-		// set the value to return
-		if scimErr == nil {
-			value[k] = newValue
+		newValue, valueViolations := attr.validate(k, v, quirks)
+		if len(valueViolations) != 0 {
+			violations = append(violations, valueViolations...)
+			continue
 		}
 
-		if scimErr != nil {
-			return nil, scimErr
-		}
+		// set the value to return
+		value[k] = newValue
 	}
 
+	if len(violations) != 0 {
+		return nil, violations
+	}
 	return value, nil
 }
 
@@ -136,48 +193,58 @@ func (s Schema) getRawAttributes() []map[string]interface{} {
 	return attributes
 }
 
-func (s Schema) validate(resource interface{}, checkMutability bool) (map[string]interface{}, *errors.ScimError) {
+func (s Schema) validate(resource interface{}, checkMutability bool, quirks Quirks) (map[string]interface{}, errors.Violations) {
 	core, ok := resource.(map[string]interface{})
 	if !ok {
-		return nil, &errors.ScimErrorInvalidSyntax
+		return nil, errors.Violations{{
+			ScimType: errors.ScimTypeInvalidSyntax,
+			Detail:   "The resource is not a JSON object.",
+		}}
 	}
 
+	var violations errors.Violations
 	attributes := make(map[string]interface{})
 	for _, attribute := range s.Attributes {
 		var hit interface{}
-		var found bool
+		var found, duplicate bool
 		for k, v := range core {
 			if strings.EqualFold(attribute.name, k) {
 				// duplicate found
 				if found {
-					err := errors.ScimError{
-						ScimType: errors.ScimErrorDuplicateAttributeFound.ScimType,
-						Detail:   errors.ScimErrorDuplicateAttributeFound.Detail + " Attribute name: " + attribute.name,
-						Status:   errors.ScimErrorDuplicateAttributeFound.Status,
-					}
-					return nil, &err
+					duplicate = true
 				}
 				found = true
 				hit = v
 			}
 		}
 
+		if duplicate {
+			violations = append(violations, errors.Violation{
+				Path:     attribute.name,
+				ScimType: errors.ScimErrorDuplicateAttributeFound.ScimType,
+				Detail:   "Duplicate attribute found.",
+			})
+			continue
+		}
+
 		// An immutable attribute SHALL NOT be updated.
 		if found && checkMutability &&
 			attribute.mutability == attributeMutabilityImmutable {
-			err := errors.ScimError{
+			violations = append(violations, errors.Violation{
+				Path:     attribute.name,
 				ScimType: errors.ScimErrorMutability.ScimType,
-				Detail:   errors.ScimErrorMutability.Detail + " Attribute name: " + attribute.name,
-				Status:   errors.ScimErrorMutability.Status,
-			}
-			return nil, &err
+				Detail:   "Immutable attribute can not be updated.",
+			})
+			continue
 		}
 
-		attr, scimErr := attribute.validate(hit)
-		if scimErr != nil {
-			return nil, scimErr
-		}
+		attr, attributeViolations := attribute.validate(attribute.name, hit, quirks)
+		violations = append(violations, attributeViolations...)
 		attributes[attribute.name] = attr
 	}
+
+	if len(violations) != 0 {
+		return nil, violations
+	}
 	return attributes, nil
 }
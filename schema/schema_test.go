@@ -2,7 +2,9 @@ package schema
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/elimity-com/scim/optional"
@@ -107,6 +109,83 @@ func TestResourceInvalid(t *testing.T) {
 	}
 }
 
+func TestValidateAll(t *testing.T) {
+	_, violations := testSchema.ValidateAll(map[string]interface{}{
+		"booleans": []interface{}{true, "yes"},
+		"complex": []interface{}{
+			map[string]interface{}{"sub": "present"},
+			map[string]interface{}{"sub": true},
+		},
+		"integer": "1",
+	}, Quirks{})
+
+	var paths []string
+	for _, v := range violations {
+		paths = append(paths, v.Path)
+	}
+	if fmt.Sprint(paths) != "[required booleans[1] complex[1].sub integer]" {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+
+	scimErr := violations.Err()
+	if scimErr == nil || !strings.Contains(scimErr.Detail, "complex[1].sub: String attribute value is not of the right type.") {
+		t.Errorf("unexpected error: %v", scimErr)
+	}
+}
+
+func TestValidateQuirks(t *testing.T) {
+	manager := Schema{
+		ID: "manager",
+		Attributes: []CoreAttribute{
+			ComplexCoreAttribute(ComplexParams{
+				Name: "manager",
+				SubAttributes: []SimpleParams{
+					SimpleStringParams(StringParams{Name: "value"}),
+				},
+			}),
+		},
+	}
+
+	for _, test := range []struct {
+		name     string
+		schema   Schema
+		resource map[string]interface{}
+		quirks   Quirks
+		expected map[string]interface{}
+	}{
+		{
+			name:     "boolean strings",
+			schema:   testSchema,
+			resource: map[string]interface{}{"required": "present", "booleans": []interface{}{"True", "false"}},
+			quirks:   Quirks{BooleanStrings: true},
+			expected: map[string]interface{}{"booleans": []interface{}{true, false}},
+		},
+		{
+			name:     "manager strings",
+			schema:   manager,
+			resource: map[string]interface{}{"manager": "0001"},
+			quirks:   Quirks{ManagerStrings: true},
+			expected: map[string]interface{}{"manager": "0001"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, violations := test.schema.ValidateAll(test.resource, Quirks{}); len(violations) == 0 {
+				t.Error("expected violations without quirks")
+			}
+
+			attributes, violations := test.schema.ValidateAll(test.resource, test.quirks)
+			if len(violations) != 0 {
+				t.Fatalf("unexpected violations: %v", violations)
+			}
+			for k, v := range test.expected {
+				if fmt.Sprint(attributes[k]) != fmt.Sprint(v) {
+					t.Errorf("expected %s to be %v, got %v", k, v, attributes[k])
+				}
+			}
+		})
+	}
+}
+
 func TestValidValidation(t *testing.T) {
 	for _, test := range []map[string]interface{}{
 		{
@@ -0,0 +1,569 @@
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	datetime "github.com/di-wu/xsd-datetime"
+	"github.com/elimity-com/scim/optional"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DecodeStruct decodes the given attributes, e.g., the scim.ResourceAttributes of a resource, into the struct the given
+// pointer points to. The fields of the struct are the attributes of the schema that is derived by FromStruct.
+// Attribute names are case insensitive, so an error is returned if an attribute is given under multiple names that only
+// differ in case, e.g., "userName" and "username". Attributes that are not defined by the struct are ignored. Nil
+// pointers to embedded structs are allocated if one of their fields is decoded.
+func DecodeStruct(attributes map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("can not decode into %T, a pointer to a struct is required", v)
+	}
+	fields, err := structFields(rv.Elem().Type(), "", false)
+	if err != nil {
+		return err
+	}
+	return decodeStruct(attributes, rv.Elem(), fields, "")
+}
+
+// EncodeStruct encodes the given struct, or pointer to a struct, into the attributes of the schema that is derived by
+// FromStruct, e.g., to be used as the scim.ResourceAttributes of a resource. Attributes of which the field is a nil
+// pointer, slice or map are omitted, as are the fields of nil pointers to embedded structs and zero values of fields
+// with the "omitempty" option.
+func EncodeStruct(v interface{}) (map[string]interface{}, error) {
+	rv, ok := indirect(reflect.ValueOf(v))
+	if !ok || rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can not encode %T, a struct is required", v)
+	}
+	fields, err := structFields(rv.Type(), "", false)
+	if err != nil {
+		return nil, err
+	}
+	return encodeStruct(rv, fields, "")
+}
+
+// FromStruct derives a schema with the given id from the type of the given struct, or pointer to a struct. Each
+// exported field is an attribute, of which the name and characteristics are defined by the "scim" tag of the field:
+//
+//	type User struct {
+//		UserName string    `scim:"userName,required,uniqueness=server"`
+//		Name     Name      `scim:"name"`
+//		Emails   []Email   `scim:"emails"`
+//		Birthday time.Time `scim:"birthday,omitempty"`
+//		Password string    `scim:"-"`
+//	}
+//
+// The first element of the tag is the name of the attribute, which defaults to the name of the field with a lowercase
+// first letter. The other elements are options:
+//   - "required" and "caseExact" set the corresponding characteristic.
+//   - "mutability", "returned" and "uniqueness" set the corresponding characteristic to the keyword after an equals
+//     sign, e.g., "mutability=readOnly".
+//   - "canonicalValues" sets the canonical values, separated by a vertical bar, e.g., "canonicalValues=work|home".
+//   - "type=reference" makes a string a reference, of which the reference types are set by "referenceTypes", e.g.,
+//     "referenceTypes=User|Group".
+//   - "omitempty" omits the attribute when encoding a zero value, see EncodeStruct.
+//
+// The type of an attribute is derived from the type of its field: strings are strings, booleans are booleans,
+// integers are integers, floats are decimals, time.Time is a dateTime, []byte is binary and structs are complex.
+// Other slices are multi-valued, pointers are dereferenced and the fields of embedded structs, or of embedded pointers
+// to exported structs, are promoted. Fields with a "-" tag are ignored. A DefinitionError is returned if a field can
+// not be converted to an attribute.
+func FromStruct(v interface{}, id string) (Schema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return Schema{}, fmt.Errorf("can not derive a schema from %T, a struct is required", v)
+	}
+	fields, err := structFields(t, "", false)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	attributes := make(Attributes, len(fields))
+	for i, f := range fields {
+		attributes[i] = f.attribute
+	}
+	return Schema{
+		Attributes: attributes,
+		ID:         id,
+		Name:       optionalName(t.Name()),
+	}, nil
+}
+
+// allocate allocates the pointers of the given value, if they are nil, and returns the value they point to.
+func allocate(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// attributePath returns the path of the attribute with the given name within the attribute with the given path.
+func attributePath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// decimalValue returns the given number as a float, e.g., a number that is decoded from its json representation.
+func decimalValue(value interface{}) (float64, bool) {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+// decodeStruct decodes the given attributes into the given struct, of which the fields are the given fields.
+func decodeStruct(attributes map[string]interface{}, v reflect.Value, fields []structField, path string) error {
+	for _, f := range fields {
+		p := attributePath(path, f.attribute.name)
+		var key string
+		for k := range attributes {
+			if !strings.EqualFold(f.attribute.name, k) {
+				continue
+			}
+			if key != "" {
+				// Both names are sorted, so the error does not depend on the iteration order of the map.
+				if k < key {
+					key, k = k, key
+				}
+				return fmt.Errorf("invalid value of attribute %s: it is given as both %q and %q", p, key, k)
+			}
+			key = k
+		}
+		if value := attributes[key]; key != "" && value != nil {
+			fv, _ := fieldByIndex(v, f.index, true)
+			if err := f.decode(value, fv, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeStruct encodes the given struct, of which the fields are the given fields, into attributes.
+func encodeStruct(v reflect.Value, fields []structField, path string) (map[string]interface{}, error) {
+	attributes := make(map[string]interface{})
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.index, false)
+		if !ok || f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		value, err := f.encode(fv, attributePath(path, f.attribute.name))
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			attributes[f.attribute.name] = value
+		}
+	}
+	return attributes, nil
+}
+
+// fieldByIndex returns the nested field of the given struct with the given index sequence. Nil pointers to embedded
+// structs are allocated if alloc is true, otherwise false is returned if one of them is encountered.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() && !alloc {
+				return reflect.Value{}, false
+			}
+			v = allocate(v)
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// indirect dereferences the given value until it is not a pointer. It returns false if a nil pointer is encountered.
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+// integerValue returns the given number as an integer, e.g., a number that is decoded from its json representation.
+func integerValue(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	case float64:
+		return int64(n), n == math.Trunc(n) && math.Abs(n) < math.MaxInt64
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), rv.Uint() <= math.MaxInt64
+	default:
+		return 0, false
+	}
+}
+
+// optionalName returns the given name of a struct type as an optional string, which is not present if it is empty.
+func optionalName(name string) optional.String {
+	if name == "" {
+		return optional.String{}
+	}
+	return optional.NewString(name)
+}
+
+// structFields returns the fields of the given struct type that are attributes, of which the path is the given path.
+// The fields of a complex attribute can not be complex themselves.
+func structFields(t reflect.Type, path string, isSubAttribute bool) ([]structField, error) {
+	var fields []structField
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("scim")
+		if tag == "-" {
+			continue
+		}
+
+		embeddedType := sf.Type
+		if embeddedType.Kind() == reflect.Ptr {
+			embeddedType = embeddedType.Elem()
+		}
+		if sf.Anonymous && !hasTag && embeddedType.Kind() == reflect.Struct && embeddedType != timeType {
+			if sf.Type.Kind() == reflect.Ptr && sf.PkgPath != "" {
+				continue // A pointer to an unexported struct can not be allocated.
+			}
+			embedded, err := structFields(embeddedType, path, isSubAttribute)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range embedded {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // Unexported field.
+		}
+
+		f, err := newStructField(sf, tag, path, isSubAttribute)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+
+	for _, f := range fields {
+		name := strings.ToLower(f.attribute.name)
+		if names[name] {
+			return nil, &DefinitionError{
+				Path:   attributePath(path, f.attribute.name),
+				Reason: fmt.Sprintf("duplicate attribute name %q", f.attribute.name),
+			}
+		}
+		names[name] = true
+	}
+	return fields, nil
+}
+
+// structField is a field of a struct that is an attribute.
+type structField struct {
+	// index is the index sequence of the field, see reflect.Value.FieldByIndex.
+	index     []int
+	attribute CoreAttribute
+	// fields are the fields of the struct of a complex attribute.
+	fields    []structField
+	omitEmpty bool
+}
+
+// newStructField returns the attribute of the given struct field with the given "scim" tag, of which the parent has
+// the given path.
+func newStructField(sf reflect.StructField, tag, path string, isSubAttribute bool) (structField, error) {
+	options := strings.Split(tag, ",")
+	name := options[0]
+	if name == "" {
+		r, n := utf8.DecodeRuneInString(sf.Name)
+		name = string(unicode.ToLower(r)) + sf.Name[n:]
+	}
+	f := structField{
+		index:     sf.Index,
+		attribute: CoreAttribute{name: name},
+	}
+	p := attributePath(path, name)
+	definitionError := func(format string, a ...interface{}) error {
+		return &DefinitionError{Path: p, Reason: fmt.Sprintf(format, a...)}
+	}
+	if !validAttributeName(name) && !(isSubAttribute && name == "$ref") {
+		return structField{}, definitionError("invalid attribute name %q", name)
+	}
+
+	t := sf.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		f.attribute.multiValued = true
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	switch {
+	case t == timeType:
+		f.attribute.typ = attributeDataTypeDateTime
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		f.attribute.typ, f.attribute.caseExact = attributeDataTypeBinary, true
+	case t.Kind() == reflect.String:
+		f.attribute.typ = attributeDataTypeString
+	case t.Kind() == reflect.Bool:
+		f.attribute.typ = attributeDataTypeBoolean
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		f.attribute.typ = attributeDataTypeInteger
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		f.attribute.typ = attributeDataTypeDecimal
+	case t.Kind() == reflect.Struct:
+		if isSubAttribute {
+			return structField{}, definitionError("a sub-attribute can not be complex")
+		}
+		fields, err := structFields(t, p, true)
+		if err != nil {
+			return structField{}, err
+		}
+		f.attribute.typ, f.fields = attributeDataTypeComplex, fields
+		f.attribute.subAttributes = make(Attributes, len(fields))
+		for i, sub := range fields {
+			f.attribute.subAttributes[i] = sub.attribute
+		}
+	default:
+		return structField{}, definitionError("unsupported type %s", sf.Type)
+	}
+
+	for _, option := range options[1:] {
+		key, value := option, ""
+		if i := strings.Index(option, "="); i != -1 {
+			key, value = option[:i], option[i+1:]
+		}
+
+		var err error
+		switch key {
+		case "canonicalValues":
+			f.attribute.canonicalValues = strings.Split(value, "|")
+		case "caseExact":
+			f.attribute.caseExact = true
+		case "mutability":
+			f.attribute.mutability, err = parseMutability(value)
+		case "omitempty":
+			f.omitEmpty = true
+		case "referenceTypes":
+			for _, referenceType := range strings.Split(value, "|") {
+				f.attribute.referenceTypes = append(f.attribute.referenceTypes, AttributeReferenceType(referenceType))
+			}
+		case "required":
+			f.attribute.required = true
+		case "returned":
+			f.attribute.returned, err = parseReturned(value)
+		case "type":
+			if value != attributeDataTypeReference.String() || f.attribute.typ != attributeDataTypeString {
+				err = fmt.Errorf("type %q is not supported for %s", value, sf.Type)
+			}
+			f.attribute.typ, f.attribute.caseExact = attributeDataTypeReference, true
+		case "uniqueness":
+			f.attribute.uniqueness, err = parseUniqueness(value)
+		default:
+			err = fmt.Errorf("unknown option %q", option)
+		}
+		if err != nil {
+			return structField{}, definitionError(err.Error())
+		}
+	}
+	if f.attribute.referenceTypes != nil && f.attribute.typ != attributeDataTypeReference {
+		return structField{}, definitionError("only reference attributes can have reference types")
+	}
+	return f, nil
+}
+
+// decode decodes the given value of the attribute with the given path into the given field value.
+func (f structField) decode(value interface{}, v reflect.Value, path string) error {
+	if !f.attribute.multiValued {
+		return f.decodeSingular(value, v, path)
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid value of attribute %s: a list is required, got %T", path, value)
+	}
+	v = allocate(v)
+	slice := reflect.MakeSlice(v.Type(), 0, len(values))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := f.decodeSingular(value, elem, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	v.Set(slice)
+	return nil
+}
+
+// decodeSingular decodes the given singular value of the attribute with the given path into the given value.
+func (f structField) decodeSingular(value interface{}, v reflect.Value, path string) error {
+	v = allocate(v)
+	invalid := func() error {
+		return fmt.Errorf("invalid value of attribute %s: %s value expected, got %T", path, f.attribute.typ, value)
+	}
+	switch f.attribute.typ {
+	case attributeDataTypeBinary:
+		s, ok := value.(string)
+		if !ok {
+			return invalid()
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("invalid value of attribute %s: %w", path, err)
+		}
+		v.SetBytes(b)
+	case attributeDataTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return invalid()
+		}
+		v.SetBool(b)
+	case attributeDataTypeComplex:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid()
+		}
+		return decodeStruct(m, v, f.fields, path)
+	case attributeDataTypeDateTime:
+		s, ok := value.(string)
+		if !ok {
+			return invalid()
+		}
+		t, err := datetime.Parse(s)
+		if err != nil {
+			return fmt.Errorf("invalid value of attribute %s: %w", path, err)
+		}
+		v.Set(reflect.ValueOf(t))
+	case attributeDataTypeDecimal:
+		n, ok := decimalValue(value)
+		if !ok || v.OverflowFloat(n) {
+			return invalid()
+		}
+		v.SetFloat(n)
+	case attributeDataTypeInteger:
+		n, ok := integerValue(value)
+		if !ok {
+			return invalid()
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(n) {
+				return fmt.Errorf("invalid value of attribute %s: %d overflows %s", path, n, v.Type())
+			}
+			v.SetInt(n)
+		default:
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return fmt.Errorf("invalid value of attribute %s: %d overflows %s", path, n, v.Type())
+			}
+			v.SetUint(uint64(n))
+		}
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return invalid()
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+// encode encodes the given field value of the attribute with the given path. It returns nil if the attribute is
+// omitted.
+func (f structField) encode(v reflect.Value, path string) (interface{}, error) {
+	v, ok := indirect(v)
+	if !ok {
+		return nil, nil
+	}
+	if !f.attribute.multiValued {
+		return f.encodeSingular(v, path)
+	}
+
+	if v.IsNil() {
+		return nil, nil
+	}
+	values := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem, ok := indirect(v.Index(i))
+		if !ok {
+			continue
+		}
+		value, err := f.encodeSingular(elem, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// encodeSingular encodes the given singular value of the attribute with the given path. Integers are encoded as int64
+// and decimals as float64 values, the types that the schema validation returns.
+func (f structField) encodeSingular(v reflect.Value, path string) (interface{}, error) {
+	switch f.attribute.typ {
+	case attributeDataTypeBinary:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+	case attributeDataTypeBoolean:
+		return v.Bool(), nil
+	case attributeDataTypeComplex:
+		return encodeStruct(v, f.fields, path)
+	case attributeDataTypeDateTime:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	case attributeDataTypeDecimal:
+		return v.Float(), nil
+	case attributeDataTypeInteger:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return v.Int(), nil
+		default:
+			if v.Uint() > math.MaxInt64 {
+				return nil, fmt.Errorf("invalid value of attribute %s: %d overflows int64", path, v.Uint())
+			}
+			return int64(v.Uint()), nil
+		}
+	default:
+		return v.String(), nil
+	}
+}
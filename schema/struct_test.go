@@ -0,0 +1,265 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMeta is exported, since only embedded pointers to exported structs are promoted.
+type TestMeta struct {
+	ExternalID string `scim:"externalId"`
+}
+
+type testDevice struct {
+	testResource
+	SerialNumber string     `scim:"serialNumber,required,uniqueness=server,caseExact"`
+	DisplayName  *string    `scim:",omitempty"`
+	Active       bool       `scim:"active"`
+	Ports        []testPort `scim:"ports"`
+	Owner        testOwner  `scim:"owner,omitempty"`
+	Firmware     []byte     `scim:"firmware,returned=request"`
+	Tags         []string   `scim:"tags,canonicalValues=managed|shared"`
+	Weight       float64    `scim:"weight,omitempty"`
+	secret       string
+	Ignored      string `scim:"-"`
+}
+
+type testOwner struct {
+	Value string `scim:"value,mutability=immutable"`
+	Ref   string `scim:"$ref,type=reference,referenceTypes=User|Group"`
+}
+
+type testPort struct {
+	Number uint8  `scim:"number"`
+	Kind   string `scim:"kind"`
+}
+
+type testResource struct {
+	Registered time.Time `scim:"registered,mutability=readOnly,returned=always"`
+}
+
+func TestEncodeDecodeStruct(t *testing.T) {
+	s, err := FromStruct(testDevice{}, "urn:example:schemas:Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "Printer"
+	device := testDevice{
+		testResource: testResource{Registered: time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		SerialNumber: "A1",
+		DisplayName:  &name,
+		Active:       true,
+		Ports:        []testPort{{Number: 1, Kind: "usb"}, {Number: 2, Kind: "hdmi"}},
+		Firmware:     []byte{0xca, 0xfe},
+		Tags:         []string{"managed"},
+		secret:       "secret",
+		Ignored:      "ignored",
+	}
+
+	attributes, err := EncodeStruct(&device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, omitted := range []string{"owner", "weight", "secret", "Ignored"} {
+		if _, ok := attributes[omitted]; ok {
+			t.Errorf("expected %s to be omitted: %v", omitted, attributes)
+		}
+	}
+	if attributes["registered"] != "2011-05-13T04:42:34Z" || attributes["firmware"] != "yv4=" {
+		t.Errorf("unexpected attributes: %v", attributes)
+	}
+
+	// The encoded attributes are valid within the derived schema.
+	validated, scimErr := s.Validate(attributes)
+	if scimErr != nil {
+		t.Fatal(scimErr)
+	}
+	var fromValidated testDevice
+	if err := DecodeStruct(validated, &fromValidated); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(device.Ports, fromValidated.Ports) {
+		t.Errorf("expected ports %v, got %v", device.Ports, fromValidated.Ports)
+	}
+
+	// The encoded attributes, and their json representation, decode to the same struct.
+	raw, _ := json.Marshal(attributes)
+	var decoded map[string]interface{}
+	_ = json.Unmarshal(raw, &decoded)
+	for _, attributes := range []map[string]interface{}{attributes, decoded} {
+		var actual testDevice
+		if err := DecodeStruct(attributes, &actual); err != nil {
+			t.Fatal(err)
+		}
+		expected := device
+		expected.secret, expected.Ignored = "", ""
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected %+v, got %+v", expected, actual)
+		}
+	}
+}
+
+func TestEncodeDecodeStructEmbeddedPointer(t *testing.T) {
+	type user struct {
+		*TestMeta
+		*testResource
+		UserName string `scim:"userName"`
+	}
+
+	s, err := FromStruct(user{}, "urn:example:schemas:User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Attributes) != 2 || s.Attributes[0].Name() != "externalId" || s.Attributes[1].Name() != "userName" {
+		t.Errorf("expected the promoted externalId and userName attributes, got %v", s.Attributes)
+	}
+
+	attributes, err := EncodeStruct(user{UserName: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(map[string]interface{}{"userName": "a"}, attributes) {
+		t.Errorf("expected the fields of the nil pointer to be omitted, got %v", attributes)
+	}
+
+	var actual user
+	if err := DecodeStruct(map[string]interface{}{"userName": "a"}, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.TestMeta != nil {
+		t.Errorf("expected the embedded pointer not to be allocated, got %v", actual.TestMeta)
+	}
+	if err := DecodeStruct(map[string]interface{}{"externalId": "b", "userName": "a"}, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.TestMeta == nil || actual.ExternalID != "b" || actual.UserName != "a" {
+		t.Errorf("unexpected user: %+v", actual)
+	}
+
+	attributes, err = EncodeStruct(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(map[string]interface{}{"externalId": "b", "userName": "a"}, attributes) {
+		t.Errorf("unexpected attributes: %v", attributes)
+	}
+}
+
+func TestDecodeStructInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		attributes map[string]interface{}
+	}{
+		{"string", map[string]interface{}{"serialNumber": 1}},
+		{"list", map[string]interface{}{"tags": "managed"}},
+		{"overflow", map[string]interface{}{"ports": []interface{}{map[string]interface{}{"number": 256.0}}}},
+		{"negative", map[string]interface{}{"ports": []interface{}{map[string]interface{}{"number": json.Number("-1")}}}},
+		{"fraction", map[string]interface{}{"ports": []interface{}{map[string]interface{}{"number": 1.5}}}},
+		{"binary", map[string]interface{}{"firmware": "not base64"}},
+		{"date time", map[string]interface{}{"registered": "yesterday"}},
+		{"duplicate", map[string]interface{}{"serialNumber": "a", "serialnumber": "b"}},
+		{"duplicate null", map[string]interface{}{"serialNumber": "a", "SERIALNUMBER": nil}},
+		{"duplicate sub-attribute", map[string]interface{}{"owner": map[string]interface{}{"value": "a", "Value": "b"}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var device testDevice
+			if err := DecodeStruct(test.attributes, &device); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	if err := DecodeStruct(map[string]interface{}{}, testDevice{}); err == nil {
+		t.Error("expected an error for a non-pointer")
+	}
+}
+
+func TestFromStruct(t *testing.T) {
+	s, err := FromStruct(&testDevice{}, "urn:example:schemas:Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != "urn:example:schemas:Device" || s.Name.Value() != "testDevice" {
+		t.Errorf("unexpected schema: %s, %s", s.ID, s.Name.Value())
+	}
+
+	var names []string
+	for _, a := range s.Attributes {
+		names = append(names, a.Name())
+	}
+	expected := []string{"registered", "serialNumber", "displayName", "active", "ports", "owner", "firmware", "tags", "weight"}
+	if !reflect.DeepEqual(expected, names) {
+		t.Fatalf("expected attributes %v, got %v", expected, names)
+	}
+
+	for _, test := range []struct {
+		attribute CoreAttribute
+		expected  string
+	}{
+		{s.Attributes[0], `{"caseExact":false,"description":"","multiValued":false,"mutability":"readOnly","name":"registered","required":false,"returned":"always","type":"dateTime","uniqueness":"none"}`},
+		{s.Attributes[1], `{"caseExact":true,"description":"","multiValued":false,"mutability":"readWrite","name":"serialNumber","required":true,"returned":"default","type":"string","uniqueness":"server"}`},
+		{s.Attributes[3], `{"description":"","multiValued":false,"mutability":"readWrite","name":"active","required":false,"returned":"default","type":"boolean"}`},
+		{s.Attributes[4], `{"description":"","multiValued":true,"mutability":"readWrite","name":"ports","required":false,"returned":"default","subAttributes":[{"caseExact":false,"description":"","multiValued":false,"mutability":"readWrite","name":"number","required":false,"returned":"default","type":"integer","uniqueness":"none"},{"caseExact":false,"description":"","multiValued":false,"mutability":"readWrite","name":"kind","required":false,"returned":"default","type":"string","uniqueness":"none"}],"type":"complex"}`},
+		{s.Attributes[5].SubAttributes()[1], `{"caseExact":true,"description":"","multiValued":false,"mutability":"readWrite","name":"$ref","referenceTypes":["User","Group"],"required":false,"returned":"default","type":"reference","uniqueness":"none"}`},
+		{s.Attributes[6], `{"caseExact":true,"description":"","multiValued":false,"mutability":"readWrite","name":"firmware","required":false,"returned":"request","type":"binary","uniqueness":"none"}`},
+		{s.Attributes[7], `{"canonicalValues":["managed","shared"],"caseExact":false,"description":"","multiValued":true,"mutability":"readWrite","name":"tags","required":false,"returned":"default","type":"string","uniqueness":"none"}`},
+		{s.Attributes[8], `{"caseExact":false,"description":"","multiValued":false,"mutability":"readWrite","name":"weight","required":false,"returned":"default","type":"decimal","uniqueness":"none"}`},
+	} {
+		raw, _ := json.Marshal(test.attribute.getRawAttributes())
+		if string(raw) != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, raw)
+		}
+	}
+}
+
+func TestFromStructInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		v    interface{}
+		path string
+	}{
+		{"invalid name", struct {
+			A string `scim:"_a"`
+		}{}, "_a"},
+		{"duplicate name", struct {
+			A string `scim:"a"`
+			B string `scim:"A"`
+		}{}, "A"},
+		{"unsupported type", struct {
+			A map[string]string `scim:"a"`
+		}{}, "a"},
+		{"nested complex", struct {
+			A struct{ B struct{ C string } } `scim:"a"`
+		}{}, "a.b"},
+		{"unknown option", struct {
+			A string `scim:"a,unique"`
+		}{}, "a"},
+		{"unknown keyword", struct {
+			A string `scim:"a,mutability=readonly"`
+		}{}, "a"},
+		{"reference type", struct {
+			A int `scim:"a,type=reference"`
+		}{}, "a"},
+		{"reference types", struct {
+			A string `scim:"a,referenceTypes=User"`
+		}{}, "a"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := FromStruct(test.v, "urn:example")
+			var definitionErr *DefinitionError
+			if !errors.As(err, &definitionErr) {
+				t.Fatalf("expected a definition error, got %v", err)
+			}
+			if definitionErr.Path != test.path {
+				t.Errorf("expected path %s, got %s", test.path, definitionErr.Path)
+			}
+		})
+	}
+
+	if _, err := FromStruct("", "urn:example"); err == nil {
+		t.Error("expected an error for a non-struct")
+	}
+}
@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elimity-com/scim/optional"
+)
+
+// unmarshalAttribute converts the json representation of the attribute definition at the given path to a core
+// attribute. Characteristics that are omitted get their default value, the type defaults to "string".
+func unmarshalAttribute(path string, data json.RawMessage, isSubAttribute bool) (CoreAttribute, error) {
+	var raw rawAttribute
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return CoreAttribute{}, &DefinitionError{Path: path, Reason: err.Error()}
+	}
+
+	typ := attributeDataTypeString
+	if raw.Type != nil {
+		typ = *raw.Type
+	}
+
+	var reason string
+	switch {
+	// The "$ref" sub-attribute holds the reference of a multi-valued attribute value, e.g., "members.$ref".
+	case !validAttributeName(raw.Name) && !(isSubAttribute && raw.Name == "$ref"):
+		reason = fmt.Sprintf("invalid attribute name %q", raw.Name)
+	case isSubAttribute && typ == attributeDataTypeComplex:
+		reason = "a sub-attribute can not be complex"
+	case len(raw.SubAttributes) != 0 && typ != attributeDataTypeComplex:
+		reason = "only complex attributes can have sub-attributes"
+	case len(raw.ReferenceTypes) != 0 && typ != attributeDataTypeReference:
+		reason = "only reference attributes can have reference types"
+	}
+	if reason != "" {
+		return CoreAttribute{}, &DefinitionError{Path: path, Reason: reason}
+	}
+
+	attribute := CoreAttribute{
+		canonicalValues: raw.CanonicalValues,
+		caseExact:       raw.CaseExact,
+		multiValued:     raw.MultiValued,
+		mutability:      raw.Mutability,
+		name:            raw.Name,
+		referenceTypes:  raw.ReferenceTypes,
+		required:        raw.Required,
+		returned:        raw.Returned,
+		typ:             typ,
+		uniqueness:      raw.Uniqueness,
+	}
+	if raw.Description != nil {
+		attribute.description = optional.NewString(*raw.Description)
+	}
+	if len(raw.SubAttributes) != 0 {
+		subAttributes, err := unmarshalAttributes(path+".subAttributes", raw.SubAttributes, true)
+		if err != nil {
+			return CoreAttribute{}, err
+		}
+		attribute.subAttributes = subAttributes
+	}
+	return attribute, nil
+}
+
+// unmarshalAttributes converts the json representations of the attribute definitions at the given path to core
+// attributes. Attribute names are case insensitive, so they must be unique regardless of their case.
+func unmarshalAttributes(path string, data []json.RawMessage, isSubAttribute bool) (Attributes, error) {
+	attributes := make(Attributes, 0, len(data))
+	names := make(map[string]bool)
+	for i, raw := range data {
+		attributePath := fmt.Sprintf("%s[%d]", path, i)
+		attribute, err := unmarshalAttribute(attributePath, raw, isSubAttribute)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.ToLower(attribute.name)
+		if names[name] {
+			return nil, &DefinitionError{
+				Path:   attributePath,
+				Reason: fmt.Sprintf("duplicate attribute name %q", attribute.name),
+			}
+		}
+		names[name] = true
+		attributes = append(attributes, attribute)
+	}
+	return attributes, nil
+}
+
+// rawAttribute is the json representation of an attribute definition.
+// https://tools.ietf.org/html/rfc7643#section-7
+type rawAttribute struct {
+	CanonicalValues []string                 `json:"canonicalValues"`
+	CaseExact       bool                     `json:"caseExact"`
+	Description     *string                  `json:"description"`
+	MultiValued     bool                     `json:"multiValued"`
+	Mutability      attributeMutability      `json:"mutability"`
+	Name            string                   `json:"name"`
+	ReferenceTypes  []AttributeReferenceType `json:"referenceTypes"`
+	Required        bool                     `json:"required"`
+	Returned        attributeReturned        `json:"returned"`
+	SubAttributes   []json.RawMessage        `json:"subAttributes"`
+	Type            *attributeType           `json:"type"`
+	Uniqueness      attributeUniqueness      `json:"uniqueness"`
+}
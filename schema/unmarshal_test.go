@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestJSONUnmarshalling(t *testing.T) {
+	for _, file := range []string{
+		"schema_test.json",
+		"user_schema.json",
+		"group_schema.json",
+		"enterprise_user_schema.json",
+	} {
+		t.Run(file, func(t *testing.T) {
+			expectedJSON, err := ioutil.ReadFile(fmt.Sprintf("./testdata/%s", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var s Schema
+			if err := json.Unmarshal(expectedJSON, &s); err != nil {
+				t.Fatal(err)
+			}
+			actualJSON, err := s.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			normalizedActual, err := normalizeJSON(actualJSON)
+			normalizedExpected, expectedErr := normalizeJSON(expectedJSON)
+			if err != nil || expectedErr != nil {
+				t.Fatal("failed to normalize test JSON")
+			}
+			if normalizedActual != normalizedExpected {
+				t.Errorf("schema did not round-trip. want %s, got %s", normalizedExpected, normalizedActual)
+			}
+		})
+	}
+}
+
+func TestJSONUnmarshallingDefaults(t *testing.T) {
+	var s Schema
+	if err := json.Unmarshal([]byte(`{
+		"id": "urn:example:schemas:Device",
+		"attributes": [
+			{"name": "serialNumber", "required": true, "uniqueness": "server"},
+			{"name": "owner", "type": "reference", "referenceTypes": ["User"]},
+			{"name": "ports", "type": "complex", "multiValued": true, "subAttributes": [
+				{"name": "number", "type": "integer", "mutability": "immutable"},
+				{"name": "kind", "canonicalValues": ["usb", "hdmi"], "returned": "always"}
+			]}
+		]
+	}`), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	serialNumber := s.Attributes[0]
+	if serialNumber.AttributeType() != "string" || serialNumber.Mutability() != "readWrite" ||
+		serialNumber.Returned() != "default" || serialNumber.Uniqueness() != "server" || !serialNumber.Required() {
+		t.Errorf("unexpected characteristics: %v", serialNumber.getRawAttributes())
+	}
+	if s.Attributes[1].ReferenceTypes()[0] != "User" {
+		t.Errorf("unexpected reference types: %v", s.Attributes[1].ReferenceTypes())
+	}
+
+	ports := s.Attributes[2].SubAttributes()
+	if len(ports) != 2 || ports[0].Mutability() != "immutable" || ports[1].CanonicalValues()[1] != "hdmi" {
+		t.Errorf("unexpected sub-attributes: %v", s.Attributes[2].getRawAttributes())
+	}
+
+	if _, scimErr := s.Validate(map[string]interface{}{
+		"serialNumber": "A1",
+		"ports":        []interface{}{map[string]interface{}{"number": 1, "kind": "usb"}},
+	}); scimErr != nil {
+		t.Error(scimErr)
+	}
+	if _, scimErr := s.Validate(map[string]interface{}{
+		"ports": []interface{}{map[string]interface{}{"number": "1"}},
+	}); scimErr == nil {
+		t.Error("invalid resource expected")
+	}
+}
+
+func TestJSONUnmarshallingInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		schema string
+		path   string
+	}{
+		{"no id", `{"attributes": []}`, "id"},
+		{"invalid name", `{"id": "urn:example", "attributes": [{"name": "_invalid"}]}`, "attributes[0]"},
+		{"unknown type", `{"id": "urn:example", "attributes": [{"name": "a", "type": "float"}]}`, "attributes[0]"},
+		{"unknown mutability", `{"id": "urn:example", "attributes": [{"name": "a", "mutability": "readonly"}]}`, "attributes[0]"},
+		{"invalid characteristic", `{"id": "urn:example", "attributes": [{"name": "a", "required": "true"}]}`, "attributes[0]"},
+		{"duplicate name", `{"id": "urn:example", "attributes": [{"name": "a"}, {"name": "A"}]}`, "attributes[1]"},
+		{
+			"duplicate sub-attribute name",
+			`{"id": "urn:example", "attributes": [{"name": "a", "type": "complex", "subAttributes": [{"name": "b"}, {"name": "b"}]}]}`,
+			"attributes[0].subAttributes[1]",
+		},
+		{
+			"nested complex",
+			`{"id": "urn:example", "attributes": [{"name": "a", "type": "complex", "subAttributes": [{"name": "b", "type": "complex"}]}]}`,
+			"attributes[0].subAttributes[0]",
+		},
+		{
+			"simple with sub-attributes",
+			`{"id": "urn:example", "attributes": [{"name": "a", "subAttributes": [{"name": "b"}]}]}`,
+			"attributes[0]",
+		},
+		{
+			"reference types",
+			`{"id": "urn:example", "attributes": [{"name": "a", "referenceTypes": ["User"]}]}`,
+			"attributes[0]",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var s Schema
+			err := json.Unmarshal([]byte(test.schema), &s)
+			var definitionErr *DefinitionError
+			if !errors.As(err, &definitionErr) {
+				t.Fatalf("expected a definition error, got %v", err)
+			}
+			if definitionErr.Path != test.path {
+				t.Errorf("expected path %s, got %s", test.path, definitionErr.Path)
+			}
+		})
+	}
+}
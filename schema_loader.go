@@ -0,0 +1,137 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elimity-com/scim/schema"
+)
+
+// loadSchema returns the schema of the given extension. Schemas that are loaded dynamically are loaded once per
+// request, subsequent calls with the same request return the schema that was loaded first.
+func loadSchema(r *http.Request, extension SchemaExtension) schema.Schema {
+	if !extension.LoadDynamically {
+		return extension.Schema
+	}
+	loaded, ok := r.Context().Value(requestSchemasKey{}).(*requestSchemas)
+	if !ok {
+		return extension.SchemaLoader.LoadSchema(r)
+	}
+	return loaded.load(r, extension)
+}
+
+// withRequestSchemas returns a shallow copy of the given request of which the context holds the schemas that are
+// loaded while serving the request. Requests of which the context already holds them, e.g., the operations of a bulk
+// request, are returned as is.
+func withRequestSchemas(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(requestSchemasKey{}).(*requestSchemas); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestSchemasKey{}, &requestSchemas{
+		schemas: make(map[string]schema.Schema),
+	}))
+}
+
+// KeyedSchemaLoader is a schema loader that identifies the schema it loads for a request by a key, so the schema can
+// be cached by a SchemaCache.
+type KeyedSchemaLoader interface {
+	SchemaLoader
+	// SchemaKey returns the key of the schema that is loaded for the given request, e.g., the tenant and the version
+	// of its configuration. Requests with the same key must load the same schema. Including a version in the key
+	// makes sure changed schemas are loaded again without invalidating the cache.
+	SchemaKey(r *http.Request) string
+}
+
+// SchemaCache is a schema loader that caches the schemas of another loader by the keys of that loader. It is safe for
+// concurrent use.
+type SchemaCache struct {
+	// hits and misses are accessed atomically, as the first fields they are 64-bit aligned on 32-bit platforms.
+	hits   uint64
+	misses uint64
+
+	loader  KeyedSchemaLoader
+	schemas *cache
+}
+
+// NewSchemaCache returns a schema loader that caches the schemas that are loaded by the given loader for the given
+// duration. Schemas are cached until they are invalidated if the duration is zero.
+func NewSchemaCache(loader KeyedSchemaLoader, ttl time.Duration) *SchemaCache {
+	return &SchemaCache{
+		loader:  loader,
+		schemas: newCache(ttl),
+	}
+}
+
+// Invalidate removes the schema with the given key from the cache, so it is loaded again on the next request.
+func (c *SchemaCache) Invalidate(key string) {
+	c.schemas.invalidate(key)
+}
+
+// InvalidateAll removes all schemas from the cache.
+func (c *SchemaCache) InvalidateAll() {
+	c.schemas.invalidateAll()
+}
+
+// LoadSchema returns the cached schema with the key of the given request, or loads it with the underlying loader if
+// it is not cached or expired.
+func (c *SchemaCache) LoadSchema(r *http.Request) schema.Schema {
+	key := c.loader.SchemaKey(r)
+	if cached, ok := c.schemas.get(key); ok {
+		atomic.AddUint64(&c.hits, 1)
+		return cached.(schema.Schema)
+	}
+
+	atomic.AddUint64(&c.misses, 1)
+	s := c.loader.LoadSchema(r)
+	c.schemas.set(key, s)
+	return s
+}
+
+// Stats returns the number of cache hits and misses since the cache was created.
+func (c *SchemaCache) Stats() SchemaCacheStats {
+	return SchemaCacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+// SchemaCacheStats are the statistics of a schema cache.
+type SchemaCacheStats struct {
+	// Hits is the number of schemas that were returned from the cache.
+	Hits uint64
+	// Misses is the number of schemas that were loaded by the underlying loader.
+	Misses uint64
+}
+
+// HitRate returns the fraction of schemas that were returned from the cache, or zero if no schemas were loaded.
+func (s SchemaCacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// requestSchemas are the schemas that are loaded dynamically while serving a request, by the ids of their extensions.
+type requestSchemas struct {
+	mu      sync.Mutex
+	schemas map[string]schema.Schema
+}
+
+// load returns the loaded schema of the given extension, or loads it if it was not loaded yet.
+func (s *requestSchemas) load(r *http.Request, extension SchemaExtension) schema.Schema {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if loaded, ok := s.schemas[extension.Schema.ID]; ok {
+		return loaded
+	}
+	loaded := extension.SchemaLoader.LoadSchema(r)
+	s.schemas[extension.Schema.ID] = loaded
+	return loaded
+}
+
+// requestSchemasKey is the context key of the schemas that are loaded while serving a request.
+type requestSchemasKey struct{}
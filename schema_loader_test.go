@@ -0,0 +1,109 @@
+package scim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/elimity-com/scim/schema"
+)
+
+func TestSchemaCache(t *testing.T) {
+	loader := &testSchemaLoader{
+		schema: getUserExtensionSchema(),
+		key:    "acme/1",
+	}
+	now := time.Unix(0, 0)
+	cache := NewSchemaCache(loader, time.Minute)
+	cache.schemas.now = func() time.Time { return now }
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	for _, d := range []time.Duration{0, 30 * time.Second, 2 * time.Minute} {
+		now = now.Add(d)
+		assertEqual(t, loader.schema.ID, cache.LoadSchema(r).ID)
+	}
+	assertEqual(t, 2, loader.loads)
+
+	// A new version of the schema is loaded without invalidating the cache.
+	loader.key = "acme/2"
+	cache.LoadSchema(r)
+	assertEqual(t, 3, loader.loads)
+	cache.Invalidate("acme/2")
+	cache.LoadSchema(r)
+	cache.LoadSchema(r)
+	assertEqual(t, 4, loader.loads)
+
+	stats := cache.Stats()
+	assertEqual(t, SchemaCacheStats{Hits: 2, Misses: 4}, stats)
+	assertEqual(t, 1.0/3, stats.HitRate())
+	assertEqual(t, 0.0, SchemaCacheStats{}.HitRate())
+}
+
+func TestSchemaCacheAlignment(t *testing.T) {
+	// The counters are accessed atomically, which requires them to be 64-bit aligned.
+	var c SchemaCache
+	assertTrue(t, unsafe.Offsetof(c.hits)%8 == 0)
+	assertTrue(t, unsafe.Offsetof(c.misses)%8 == 0)
+}
+
+func TestServerSchemaLoader(t *testing.T) {
+	loader := &testSchemaLoader{
+		schema: getUserExtensionSchema(),
+	}
+	s := newTestServer()
+	s.Config.SupportBulk = true
+	s.ResourceTypes[1].SchemaExtensions[0].LoadDynamically = true
+	s.ResourceTypes[1].SchemaExtensions[0].SchemaLoader = loader
+
+	for _, test := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"patch", http.MethodPatch, "/v2/EnterpriseUsers/0001", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [
+				{"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber", "value": "1"},
+				{"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:organization", "value": "acme"}
+			]
+		}`, http.StatusOK},
+		{"schemas", http.MethodGet, "/v2/Schemas", "", http.StatusOK},
+		{"bulk", http.MethodPost, "/v2/Bulk", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [
+				{"method": "POST", "path": "/EnterpriseUsers", "bulkId": "1", "data": {"userName": "a"}},
+				{"method": "POST", "path": "/EnterpriseUsers", "bulkId": "2", "data": {"userName": "b"}}
+			]
+		}`, http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			loader.loads = 0
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+			assertEqualStatusCode(t, test.status, rr.Code)
+			// Schemas are loaded once per request.
+			assertEqual(t, 1, loader.loads)
+		})
+	}
+}
+
+// testSchemaLoader loads the given schema with the given key and counts the number of times the schema is loaded.
+type testSchemaLoader struct {
+	schema schema.Schema
+	key    string
+	loads  int
+}
+
+func (l *testSchemaLoader) LoadSchema(_ *http.Request) schema.Schema {
+	l.loads++
+	return l.schema
+}
+
+func (l *testSchemaLoader) SchemaKey(_ *http.Request) string {
+	return l.key
+}
@@ -0,0 +1,74 @@
+package scim
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/elimity-com/scim/errors"
+)
+
+// parseSearchRequest parses the body of an HTTP POST request to a "/.search" endpoint. The parameters of the search
+// request are returned as if they were passed as query parameters.
+func parseSearchRequest(r *http.Request) (url.Values, *errors.ScimError) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, &errors.ScimErrorInvalidSyntax
+	}
+
+	var req searchRequest
+	if err := unmarshal(data, &req); err != nil {
+		return nil, &errors.ScimErrorInvalidSyntax
+	}
+	return req.values(), nil
+}
+
+// searchRequest represents a query request sent in the body of an HTTP POST request, as defined in RFC 7644,
+// Section 3.4.3.
+type searchRequest struct {
+	Schemas []string
+	// Attributes is a list of attribute names to return in the response, overriding the default set of attributes.
+	Attributes []string
+	// ExcludedAttributes is a list of attribute names to exclude from the default set of attributes.
+	ExcludedAttributes []string
+	// Filter is the filter used to request a subset of the resources.
+	Filter string
+	// SortBy is the attribute whose value is used to order the returned responses.
+	SortBy string
+	// SortOrder is the order in which the "sortBy" parameter is applied.
+	SortOrder string
+	// StartIndex is the 1-based index of the first query result.
+	StartIndex *int
+	// Count is the desired maximum number of query results per page.
+	Count *int
+}
+
+// values returns the parameters of the search request in the same format as the query parameters of a GET request.
+func (req searchRequest) values() url.Values {
+	values := make(url.Values)
+	if len(req.Attributes) != 0 {
+		values.Set("attributes", strings.Join(req.Attributes, ","))
+	}
+	if len(req.ExcludedAttributes) != 0 {
+		values.Set("excludedAttributes", strings.Join(req.ExcludedAttributes, ","))
+	}
+	if req.Filter != "" {
+		// Filters passed as query parameter are unescaped, this makes sure that the filter is left unchanged.
+		values.Set("filter", url.QueryEscape(req.Filter))
+	}
+	if req.SortBy != "" {
+		values.Set("sortBy", req.SortBy)
+	}
+	if req.SortOrder != "" {
+		values.Set("sortOrder", req.SortOrder)
+	}
+	if req.StartIndex != nil {
+		values.Set("startIndex", strconv.Itoa(*req.StartIndex))
+	}
+	if req.Count != nil {
+		values.Set("count", strconv.Itoa(*req.Count))
+	}
+	return values
+}
@@ -0,0 +1,112 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerResourcesSearchHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v2/Users/.search", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],
+		"attributes": ["userName"],
+		"filter": "userName sw \"test\"",
+		"startIndex": 3,
+		"count": 5
+	}`))
+	rr := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+	var response listResponse
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assertEqual(t, 20, response.TotalResults)
+	assertEqual(t, 3, response.StartIndex)
+	assertEqual(t, 5, response.ItemsPerPage)
+	assertLen(t, response.Resources, 5)
+	for _, r := range response.Resources {
+		resource, ok := r.(map[string]interface{})
+		assertTypeOk(t, ok, "object")
+		assertNotNil(t, resource["userName"], "userName")
+		assertNil(t, resource["externalId"], "externalId")
+	}
+}
+
+func TestServerResourcesSearchHandlerInvalid(t *testing.T) {
+	for _, body := range []string{
+		`{`,
+		`{"count": "ten"}`,
+		`{"sortOrder": "random"}`,
+		`{"filter": "userName eq"}`,
+	} {
+		t.Run(body, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			newTestServer().ServeHTTP(rr, req)
+
+			assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
+
+func TestServerSearchHandler(t *testing.T) {
+	for _, test := range []struct {
+		name                 string
+		body                 string
+		expectedTotalResults int
+		expectedResources    int
+	}{
+		{
+			name:                 "paging across resource types",
+			body:                 `{"startIndex": 15, "count": 30}`,
+			expectedTotalResults: 60,
+			expectedResources:    30,
+		},
+		{
+			name:                 "last page",
+			body:                 `{"startIndex": 55, "count": 30}`,
+			expectedTotalResults: 60,
+			expectedResources:    6,
+		},
+		{
+			name:                 "count zero",
+			body:                 `{"count": 0}`,
+			expectedTotalResults: 60,
+			expectedResources:    0,
+		},
+		{
+			name:                 "filter on group attributes",
+			body:                 `{"filter": "members.value eq \"0001\""}`,
+			expectedTotalResults: 20,
+			expectedResources:    20,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/.search", strings.NewReader(test.body))
+			rr := httptest.NewRecorder()
+			newTestServer().ServeHTTP(rr, req)
+
+			assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+			var response listResponse
+			assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assertEqual(t, test.expectedTotalResults, response.TotalResults)
+			assertLen(t, response.Resources, test.expectedResources)
+		})
+	}
+}
+
+func TestServerSearchHandlerInvalidFilter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/.search", strings.NewReader(`{"filter": "unknown eq \"value\""}`))
+	rr := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+
+	var scimErr map[string]interface{}
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &scimErr))
+	assertEqual(t, "invalidFilter", scimErr["scimType"])
+}
@@ -1,9 +1,12 @@
 package scim
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"runtime/debug"
 	"strconv"
 	"strings"
 
@@ -13,12 +16,24 @@ import (
 )
 
 const (
-	defaultStartIndex = 1
-	fallbackCount     = 100
+	defaultStartIndex          = 1
+	fallbackCount              = 100
+	fallbackMaxBulkOperations  = 1000
+	fallbackMaxBulkPayloadSize = 1048576
 )
 
-func getFilter(r *http.Request) (filter.Expression, error) {
-	rawFilter := strings.TrimSpace(r.URL.Query().Get("filter"))
+func getAttributes(query url.Values, key string) []string {
+	var attributes []string
+	for _, attribute := range strings.Split(query.Get(key), ",") {
+		if attribute = strings.TrimSpace(attribute); attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes
+}
+
+func getFilter(query url.Values) (filter.Expression, error) {
+	rawFilter := strings.TrimSpace(query.Get("filter"))
 	decodedFilter, _ := url.QueryUnescape(rawFilter)
 	if decodedFilter != "" {
 		return filter.ParseFilter([]byte(decodedFilter))
@@ -26,8 +41,8 @@ func getFilter(r *http.Request) (filter.Expression, error) {
 	return nil, nil
 }
 
-func getIntQueryParam(r *http.Request, key string, def int) (int, error) {
-	strVal := r.URL.Query().Get(key)
+func getIntQueryParam(query url.Values, key string, def int) (int, error) {
+	strVal := query.Get(key)
 
 	if strVal == "" {
 		return def, nil
@@ -40,6 +55,29 @@ func getIntQueryParam(r *http.Request, key string, def int) (int, error) {
 	return 0, fmt.Errorf("invalid query parameter, \"%s\" must be an integer", key)
 }
 
+func getSortBy(query url.Values) (*filter.AttributePath, error) {
+	sortBy := strings.TrimSpace(query.Get("sortBy"))
+	if sortBy == "" {
+		return nil, nil
+	}
+	attrPath, err := filter.ParseAttrPath([]byte(sortBy))
+	if err != nil {
+		return nil, err
+	}
+	return &attrPath, nil
+}
+
+func getSortOrder(query url.Values) (SortOrder, error) {
+	switch sortOrder := strings.TrimSpace(query.Get("sortOrder")); {
+	case sortOrder == "" || strings.EqualFold(sortOrder, string(SortOrderAscending)):
+		return SortOrderAscending, nil
+	case strings.EqualFold(sortOrder, string(SortOrderDescending)):
+		return SortOrderDescending, nil
+	default:
+		return "", fmt.Errorf("invalid query parameter, \"sortOrder\" must be \"ascending\" or \"descending\"")
+	}
+}
+
 func parseIdentifier(path, endpoint string) (string, error) {
 	return url.PathUnescape(strings.TrimPrefix(path, endpoint+"/"))
 }
@@ -47,84 +85,79 @@ func parseIdentifier(path, endpoint string) (string, error) {
 // Server represents a SCIM server which implements the HTTP-based SCIM protocol that makes managing identities in multi-
 // domain scenarios easier to support via a standardized service.
 type Server struct {
+	// Compatibility switches known deviations of clients from the SCIM specification on or off. The server strictly
+	// follows the RFCs if it is the zero value.
+	Compatibility Compatibility
 	Config        ServiceProviderConfig
+	// ErrorReporter reports failures that occurred while serving a request, such as responses that could not be
+	// marshaled and panics of resource handlers. Failures are logged with the standard logger if it is nil.
+	ErrorReporter ErrorReporter
 	Prefix        string
-	ResourceTypes []ResourceType
+	// ResourceTypeLoader loads resource types for each request, which are served in addition to the resource types of
+	// the server (or of the tenant of the request). See ResourceTypeCache to cache them.
+	ResourceTypeLoader ResourceTypeLoader
+	ResourceTypes      []ResourceType
+	// SubjectResolver resolves the subject of the "/Me" endpoint. The endpoint is not implemented if it is nil.
+	SubjectResolver SubjectResolver
+	// TenantProvider provides the configuration of the tenants that are resolved by the tenant resolver. It is
+	// required if the server has a tenant resolver, otherwise every request fails with an internal server error.
+	TenantProvider TenantProvider
+	// TenantResolver resolves the tenant of each request, if the server serves multiple tenants. All endpoints are
+	// then served with the configuration and the resource types of the tenant, as provided by the tenant provider,
+	// instead of the configuration and the resource types of the server.
+	TenantResolver TenantResolver
 }
 
 // ServeHTTP dispatches the request to the handler whose pattern most closely matches the request URL.
 func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/scim+json")
+	r = withErrorContext(r)
+	defer s.recoverPanic(w, r)
+	r = withCompatibility(r, s.Compatibility)
+	r = withRequestSchemas(r)
 
-	path := strings.TrimPrefix(r.URL.Path, s.Prefix)
+	w.Header().Set("Content-Type", "application/scim+json")
 
-	switch {
-	case path == "/Me":
-		errorHandler(w, r, &errors.ScimError{
-			Status: http.StatusNotImplemented,
-		})
-		return
-	case path == "/Schemas" && r.Method == http.MethodGet:
-		s.schemasHandler(w, r)
-		return
-	case strings.HasPrefix(path, "/Schemas/") && r.Method == http.MethodGet:
-		s.schemaHandler(w, r, strings.TrimPrefix(path, "/Schemas/"))
-		return
-	case path == "/ResourceTypes" && r.Method == http.MethodGet:
-		s.resourceTypesHandler(w, r)
-		return
-	case strings.HasPrefix(path, "/ResourceTypes/") && r.Method == http.MethodGet:
-		s.resourceTypeHandler(w, r, strings.TrimPrefix(path, "/ResourceTypes/"))
-		return
-	case path == "/ServiceProviderConfig":
-		s.serviceProviderConfigHandler(w, r)
-		return
-	case path == "/":
-		// For Azure AD testing connectivity - it expects a 200 at the root
-		w.WriteHeader(200)
-		w.Write([]byte("OK"))
+	if s.TenantResolver != nil {
+		if s.TenantProvider == nil {
+			s.reportError(r, fmt.Errorf("server has a tenant resolver but no tenant provider"))
+			s.errorHandler(w, r, &errors.ScimErrorInternal)
+			return
+		}
+		tenantServer, r, err := s.tenantServer(r)
+		if err != nil {
+			scimErr := errors.CheckScimError(err, r.Method)
+			s.errorHandler(w, r, &scimErr)
+			return
+		}
+		tenantServer.serve(w, r)
 		return
 	}
+	s.serve(w, r)
+}
 
+// bulkTarget returns the resource type and the resource identifier targeted by a bulk operation with the given method
+// and path. Bulk operations can only target the endpoints of resource types, i.e. "/Users" for POST and "/Users/{id}"
+// for the other methods, so other endpoints such as "/Me", "/.search" and "/Bulk" are rejected.
+func (s Server) bulkTarget(method, path string) (ResourceType, string, bool) {
+	u, err := url.Parse(path)
+	if err != nil || !strings.HasPrefix(path, "/") {
+		return ResourceType{}, "", false
+	}
+	path = strings.TrimPrefix(u.Path, s.Prefix)
 	for _, resourceType := range s.ResourceTypes {
 		if path == resourceType.Endpoint {
-			switch r.Method {
-			case http.MethodPost:
-				s.resourcePostHandler(w, r, resourceType)
-				return
-			case http.MethodGet:
-				s.resourcesGetHandler(w, r, resourceType)
-				return
-			}
+			return resourceType, "", method == http.MethodPost
 		}
-
-		if strings.HasPrefix(path, resourceType.Endpoint+"/") {
-			id, err := parseIdentifier(path, resourceType.Endpoint)
-			if err != nil {
-				break
-			}
-
-			switch r.Method {
-			case http.MethodGet:
-				s.resourceGetHandler(w, r, id, resourceType)
-				return
-			case http.MethodPut:
-				s.resourcePutHandler(w, r, id, resourceType)
-				return
-			case http.MethodPatch:
-				s.resourcePatchHandler(w, r, id, resourceType)
-				return
-			case http.MethodDelete:
-				s.resourceDeleteHandler(w, r, id, resourceType)
-				return
-			}
+		if !strings.HasPrefix(path, resourceType.Endpoint+"/") {
+			continue
+		}
+		id, err := parseIdentifier(path, resourceType.Endpoint)
+		if err != nil || id == "" || strings.Contains(id, "/") || id == ".search" {
+			return ResourceType{}, "", false
 		}
+		return resourceType, id, method != http.MethodPost
 	}
-
-	errorHandler(w, r, &errors.ScimError{
-		Detail: "Specified endpoint does not exist.",
-		Status: http.StatusNotFound,
-	})
+	return ResourceType{}, "", false
 }
 
 // getSchema extracts the schemas from the resources types defined in the server with given id.
@@ -135,11 +168,7 @@ func (s Server) getSchema(id string, r *http.Request) schema.Schema {
 		}
 		for _, extension := range resourceType.SchemaExtensions {
 			if extension.Schema.ID == id {
-				if extension.LoadDynamically {
-					return extension.SchemaLoader.LoadSchema(r)
-				} else {
-					return extension.Schema
-				}
+				return loadSchema(r, extension)
 			}
 		}
 	}
@@ -157,11 +186,7 @@ func (s Server) getSchemas(r *http.Request) []schema.Schema {
 		ids = append(ids, resourceType.Schema.ID)
 		for _, extension := range resourceType.SchemaExtensions {
 			if !contains(ids, extension.Schema.ID) {
-				if extension.LoadDynamically {
-					schemas = append(schemas, extension.SchemaLoader.LoadSchema(r))
-				} else {
-					schemas = append(schemas, extension.Schema)
-				}
+				schemas = append(schemas, loadSchema(r, extension))
 			}
 			ids = append(ids, extension.Schema.ID)
 		}
@@ -169,11 +194,30 @@ func (s Server) getSchemas(r *http.Request) []schema.Schema {
 	return schemas
 }
 
+// location returns the absolute URI of the resource of the given resource type with the given identifier, based on the
+// scheme and host of the given request.
+func (s Server) location(r *http.Request, resourceType ResourceType, id string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s%s/%s", scheme, r.Host, s.Prefix, resourceType.Endpoint, url.PathEscape(id))
+}
+
 func (s Server) parseRequestParams(r *http.Request) (ListRequestParams, *errors.ScimError) {
+	query := r.URL.Query()
+	if r.Method == http.MethodPost {
+		// The parameters of a search request are sent in the request body instead of the query.
+		var scimErr *errors.ScimError
+		if query, scimErr = parseSearchRequest(r); scimErr != nil {
+			return ListRequestParams{}, scimErr
+		}
+	}
+
 	invalidParams := make([]string, 0)
 
 	defaultCount := s.Config.getItemsPerPage()
-	count, countErr := getIntQueryParam(r, "count", defaultCount)
+	count, countErr := getIntQueryParam(query, "count", defaultCount)
 	if countErr != nil {
 		invalidParams = append(invalidParams, "count")
 	}
@@ -186,7 +230,7 @@ func (s Server) parseRequestParams(r *http.Request) (ListRequestParams, *errors.
 		count = 0
 	}
 
-	startIndex, indexErr := getIntQueryParam(r, "startIndex", defaultStartIndex)
+	startIndex, indexErr := getIntQueryParam(query, "startIndex", defaultStartIndex)
 	if indexErr != nil {
 		invalidParams = append(invalidParams, "startIndex")
 	}
@@ -194,19 +238,270 @@ func (s Server) parseRequestParams(r *http.Request) (ListRequestParams, *errors.
 		startIndex = defaultStartIndex
 	}
 
-	if len(invalidParams) > 1 {
+	sortBy, sortByErr := getSortBy(query)
+	if sortByErr != nil {
+		invalidParams = append(invalidParams, "sortBy")
+	}
+
+	sortOrder, sortOrderErr := getSortOrder(query)
+	if sortOrderErr != nil {
+		invalidParams = append(invalidParams, "sortOrder")
+	}
+
+	if len(invalidParams) != 0 {
 		scimErr := errors.ScimErrorBadParams(invalidParams)
 		return ListRequestParams{}, &scimErr
 	}
 
-	filterExpr, filterExprErr := getFilter(r)
+	filterExpr, filterExprErr := getFilter(query)
 	if filterExprErr != nil {
 		return ListRequestParams{}, &errors.ScimErrorInvalidFilter
 	}
 
 	return ListRequestParams{
-		Count:      count,
-		Filter:     filterExpr,
-		StartIndex: startIndex,
+		Attributes:         getAttributes(query, "attributes"),
+		Count:              count,
+		ExcludedAttributes: getAttributes(query, "excludedAttributes"),
+		Filter:             filterExpr,
+		SortBy:             sortBy,
+		SortOrder:          sortOrder,
+		StartIndex:         startIndex,
 	}, nil
 }
+
+// processBulkOperation dispatches the given operation to the handler of the resource type it targets.
+func (s Server) processBulkOperation(r *http.Request, op bulkOperation, resolved map[string]string) (bulkOperationResponse, string) {
+	switch op.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return bulkErrorResponse(op, errors.ScimError{
+			ScimType: errors.ScimErrorInvalidSyntax.ScimType,
+			Detail:   errors.ScimErrorInvalidSyntax.Detail + " Unsupported bulk operation method: " + op.Method,
+			Status:   errors.ScimErrorInvalidSyntax.Status,
+		}), ""
+	}
+	if op.Method == http.MethodPost && op.BulkID == "" {
+		return bulkErrorResponse(op, errors.ScimError{
+			ScimType: errors.ScimErrorInvalidSyntax.ScimType,
+			Detail:   errors.ScimErrorInvalidSyntax.Detail + " A bulk operation with method POST requires a bulkId.",
+			Status:   errors.ScimErrorInvalidSyntax.Status,
+		}), ""
+	}
+
+	path, data := op.resolve(resolved)
+	resourceType, id, ok := s.bulkTarget(op.Method, path)
+	if !ok {
+		return bulkErrorResponse(op, errors.ScimError{
+			ScimType: errors.ScimErrorInvalidSyntax.ScimType,
+			Detail:   errors.ScimErrorInvalidSyntax.Detail + " Invalid bulk operation path: " + op.Path,
+			Status:   errors.ScimErrorInvalidSyntax.Status,
+		}), ""
+	}
+
+	var body bytes.Buffer
+	if data != nil {
+		if err := json.NewEncoder(&body).Encode(data); err != nil {
+			return bulkErrorResponse(op, errors.ScimErrorInvalidSyntax), ""
+		}
+	}
+
+	req, err := http.NewRequestWithContext(withBulkOperation(r).Context(), op.Method, path, &body)
+	if err != nil {
+		return bulkErrorResponse(op, errors.ScimError{
+			ScimType: errors.ScimErrorInvalidSyntax.ScimType,
+			Detail:   errors.ScimErrorInvalidSyntax.Detail + " Invalid bulk operation path: " + op.Path,
+			Status:   errors.ScimErrorInvalidSyntax.Status,
+		}), ""
+	}
+	req.Host, req.TLS = r.Host, r.TLS
+	req.Header = r.Header.Clone()
+	req.Header.Del("Content-Length")
+	if op.Version != "" {
+		req.Header.Set("If-Match", op.Version)
+	}
+
+	rw := newBulkResponseWriter()
+	s.ServeHTTP(rw, req)
+
+	response := bulkOperationResponse{
+		Method:  op.Method,
+		BulkID:  op.BulkID,
+		Version: rw.header.Get("Etag"),
+		Status:  fmt.Sprint(rw.status),
+	}
+	if rw.status >= http.StatusBadRequest {
+		response.Response = rw.body.Bytes()
+		return response, ""
+	}
+
+	if op.Method == http.MethodPost {
+		var resource struct {
+			ID string
+		}
+		if rw.body.Len() != 0 {
+			_ = json.Unmarshal(rw.body.Bytes(), &resource)
+		}
+		id = resource.ID
+	}
+	if id != "" {
+		response.Location = s.location(r, resourceType, id)
+	}
+	return response, id
+}
+
+// recoverPanic recovers a panic that occurred while serving the given request, e.g., in the callbacks of a resource
+// handler. The panic is reported and an internal server error is returned to the client.
+func (s Server) recoverPanic(w http.ResponseWriter, r *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		// Aborts the response without logging a stack trace, as documented by net/http.
+		panic(v)
+	}
+	s.reportError(r, &PanicError{
+		Value: v,
+		Stack: debug.Stack(),
+	})
+	s.errorHandler(w, r, &errors.ScimErrorInternal)
+}
+
+// reportError reports the given error that occurred while serving the given request, including the context of the
+// request.
+func (s Server) reportError(r *http.Request, err error) {
+	report := ErrorReport{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Err:    err,
+	}
+	if c, ok := r.Context().Value(errorContextKey{}).(*errorContext); ok {
+		report.ResourceType = c.resourceType
+		report.ID = c.id
+	}
+
+	var reporter ErrorReporter = logErrorReporter{}
+	if s.ErrorReporter != nil {
+		reporter = s.ErrorReporter
+	}
+	reporter.ReportError(r, report)
+}
+
+// serve dispatches the given request, which is prepared by ServeHTTP, to the handler of the endpoint it targets.
+func (s Server) serve(w http.ResponseWriter, r *http.Request) {
+	if s.ResourceTypeLoader != nil {
+		resourceTypes, err := s.ResourceTypeLoader.LoadResourceTypes(r)
+		if err != nil {
+			scimErr := errors.CheckScimError(err, r.Method)
+			s.errorHandler(w, r, &scimErr)
+			return
+		}
+		s.ResourceTypes = append(append([]ResourceType(nil), s.ResourceTypes...), resourceTypes...)
+		// The resource types are loaded once, bulk operations are served with the same resource types.
+		s.ResourceTypeLoader = nil
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, s.Prefix)
+
+	switch {
+	case path == "/Me":
+		s.meHandler(w, r)
+		return
+	case path == "/.search" && r.Method == http.MethodPost:
+		s.searchHandler(w, r)
+		return
+	case path == "/Bulk" && r.Method == http.MethodPost:
+		s.bulkHandler(w, r)
+		return
+	case path == "/Schemas" && r.Method == http.MethodGet:
+		s.schemasHandler(w, r)
+		return
+	case strings.HasPrefix(path, "/Schemas/") && r.Method == http.MethodGet:
+		s.schemaHandler(w, r, strings.TrimPrefix(path, "/Schemas/"))
+		return
+	case path == "/ResourceTypes" && r.Method == http.MethodGet:
+		s.resourceTypesHandler(w, r)
+		return
+	case strings.HasPrefix(path, "/ResourceTypes/") && r.Method == http.MethodGet:
+		s.resourceTypeHandler(w, r, strings.TrimPrefix(path, "/ResourceTypes/"))
+		return
+	case path == "/ServiceProviderConfig":
+		s.serviceProviderConfigHandler(w, r)
+		return
+	case path == "/" && s.Compatibility.RootHealthCheck:
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte("OK")); err != nil {
+			s.reportError(r, fmt.Errorf("failed writing response: %w", err))
+		}
+		return
+	}
+
+	for _, resourceType := range s.ResourceTypes {
+		if path == resourceType.Endpoint {
+			setErrorContext(r, resourceType.Name, "")
+			switch r.Method {
+			case http.MethodPost:
+				s.resourcePostHandler(w, r, resourceType)
+				return
+			case http.MethodGet:
+				s.resourcesGetHandler(w, r, resourceType)
+				return
+			}
+		}
+
+		if path == resourceType.Endpoint+"/.search" && r.Method == http.MethodPost {
+			setErrorContext(r, resourceType.Name, "")
+			s.resourcesGetHandler(w, r, resourceType)
+			return
+		}
+
+		if strings.HasPrefix(path, resourceType.Endpoint+"/") {
+			id, err := parseIdentifier(path, resourceType.Endpoint)
+			if err != nil {
+				break
+			}
+
+			setErrorContext(r, resourceType.Name, id)
+			switch r.Method {
+			case http.MethodGet:
+				s.resourceGetHandler(w, r, id, resourceType)
+				return
+			case http.MethodPut:
+				s.resourcePutHandler(w, r, id, resourceType)
+				return
+			case http.MethodPatch:
+				s.resourcePatchHandler(w, r, id, resourceType)
+				return
+			case http.MethodDelete:
+				s.resourceDeleteHandler(w, r, id, resourceType)
+				return
+			}
+		}
+	}
+
+	s.errorHandler(w, r, &errors.ScimError{
+		Detail: "Specified endpoint does not exist.",
+		Status: http.StatusNotFound,
+	})
+}
+
+// tenantServer returns a copy of the server that serves the tenant of the given request, see TenantResolver, and the
+// request of which the context holds the tenant. The prefix of the copy includes the tenant if it is part of the path.
+func (s Server) tenantServer(r *http.Request) (Server, *http.Request, error) {
+	path := strings.TrimPrefix(r.URL.Path, s.Prefix)
+	id, remaining, err := s.TenantResolver.ResolveTenant(r, path)
+	if err != nil {
+		return s, r, err
+	}
+	tenant, err := s.TenantProvider.LoadTenant(r, id)
+	if err != nil {
+		return s, r, err
+	}
+
+	s.Config = tenant.Config
+	s.ResourceTypes = tenant.ResourceTypes
+	s.Prefix += strings.TrimSuffix(path, remaining)
+	// The tenant is resolved once, bulk operations are served by the copy as well.
+	s.TenantResolver = nil
+	return s, withTenant(r, id), nil
+}
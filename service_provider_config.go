@@ -45,12 +45,21 @@ type ServiceProviderConfig struct {
 	DocumentationURI optional.String
 	// AuthenticationSchemes is a multi-valued complex type that specifies supported authentication scheme properties.
 	AuthenticationSchemes []AuthenticationScheme
+	// MaxBulkOperations denotes the maximum number of operations in a bulk request. It defaults to 1000.
+	MaxBulkOperations int
+	// MaxBulkPayloadSize denotes the maximum payload size in bytes of a bulk request. It defaults to 1048576.
+	MaxBulkPayloadSize int
 	// MaxResults denotes the the integer value specifying the maximum number of resources returned in a response. It defaults to 100.
 	MaxResults int
+	// SupportBulk whether your SCIM implementation will support bulk requests.
+	SupportBulk bool
 	// SupportFiltering whether you SCIM implementation will support filtering.
 	SupportFiltering bool
 	// SupportPatch whether your SCIM implementation will support patch requests.
 	SupportPatch bool
+	// SupportSort whether your SCIM implementation will support sorting, i.e. whether the GetAll methods of the resource
+	// handlers apply the sortBy and sortOrder parameters (e.g. with SortResources).
+	SupportSort bool
 }
 
 // getItemsPerPage retrieves the configured default count. It falls back to 100 when not configured.
@@ -61,6 +70,24 @@ func (config ServiceProviderConfig) getItemsPerPage() int {
 	return config.MaxResults
 }
 
+// getMaxBulkOperations retrieves the configured maximum number of bulk operations. It falls back to 1000 when not
+// configured.
+func (config ServiceProviderConfig) getMaxBulkOperations() int {
+	if config.MaxBulkOperations < 1 {
+		return fallbackMaxBulkOperations
+	}
+	return config.MaxBulkOperations
+}
+
+// getMaxBulkPayloadSize retrieves the configured maximum bulk payload size. It falls back to 1048576 when not
+// configured.
+func (config ServiceProviderConfig) getMaxBulkPayloadSize() int {
+	if config.MaxBulkPayloadSize < 1 {
+		return fallbackMaxBulkPayloadSize
+	}
+	return config.MaxBulkPayloadSize
+}
+
 func (config ServiceProviderConfig) getRaw() map[string]interface{} {
 	return map[string]interface{}{
 		"schemas":          []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
@@ -69,9 +96,9 @@ func (config ServiceProviderConfig) getRaw() map[string]interface{} {
 			"supported": config.SupportPatch,
 		},
 		"bulk": map[string]interface{}{
-			"supported":      false,
-			"maxOperations":  1000,
-			"maxPayloadSize": 1048576,
+			"supported":      config.SupportBulk,
+			"maxOperations":  config.getMaxBulkOperations(),
+			"maxPayloadSize": config.getMaxBulkPayloadSize(),
 		},
 		"filter": map[string]interface{}{
 			"supported":  config.SupportFiltering,
@@ -81,10 +108,10 @@ func (config ServiceProviderConfig) getRaw() map[string]interface{} {
 			"supported": false,
 		},
 		"sort": map[string]bool{
-			"supported": false,
+			"supported": config.SupportSort,
 		},
 		"etag": map[string]bool{
-			"supported": false,
+			"supported": true,
 		},
 		"authenticationSchemes": config.getRawAuthenticationSchemes(),
 	}
@@ -0,0 +1,264 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	datetime "github.com/di-wu/xsd-datetime"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// SortResources orders the given resources based on the "sortBy" and "sortOrder" parameters following the rules
+// defined in RFC 7644, Section 3.4.2.3. Given schema and extensions are used to determine the type of the attribute.
+// - strings are compared case insensitive, unless the attribute is case exact;
+// - multi-valued attributes are sorted by their primary value, if any, or else the first value in the list;
+// - resources without a value are ordered last if ascending and first if descending.
+//
+// Resources are left untouched if no "sortBy" parameter is present.
+func SortResources(resources []Resource, params ListRequestParams, s schema.Schema, extensions ...schema.Schema) {
+	if params.SortBy == nil {
+		return
+	}
+
+	attr, uri, ok := sortAttribute(*params.SortBy, s, extensions...)
+	if !ok {
+		return
+	}
+
+	keys := make([]interface{}, len(resources))
+	for i, r := range resources {
+		keys[i] = sortValue(r, *params.SortBy, attr, uri)
+	}
+
+	indices := make([]int, len(resources))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		c := compareSortValues(keys[indices[i]], keys[indices[j]], attr)
+		if params.SortOrder == SortOrderDescending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	sorted := make([]Resource, len(resources))
+	for i, index := range indices {
+		sorted[i] = resources[index]
+	}
+	copy(resources, sorted)
+}
+
+// compareSortValues compares the given values based on the type of the attribute. Absent values are considered to
+// be greater than any other value, so they end up last in ascending order and first in descending order.
+func compareSortValues(a, b interface{}, attr schema.CoreAttribute) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	switch attr.AttributeType() {
+	case "boolean":
+		x, xOk := a.(bool)
+		y, yOk := b.(bool)
+		if xOk && yOk {
+			switch {
+			case x == y:
+				return 0
+			case !x:
+				return -1
+			default:
+				return 1
+			}
+		}
+	case "dateTime":
+		x, xOk := sortTime(a)
+		y, yOk := sortTime(b)
+		if xOk && yOk {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			default:
+				return 0
+			}
+		}
+	case "decimal", "integer":
+		x, xOk := sortNumber(a)
+		y, yOk := sortNumber(b)
+		if xOk && yOk {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	x, y := fmt.Sprint(a), fmt.Sprint(b)
+	if !attr.CaseExact() {
+		x, y = strings.ToLower(x), strings.ToLower(y)
+	}
+	return strings.Compare(x, y)
+}
+
+// sortAttribute returns the attribute and the URI of the schema to which the given attribute path applies.
+func sortAttribute(path filter.AttributePath, s schema.Schema, extensions ...schema.Schema) (schema.CoreAttribute, string, bool) {
+	common := schema.Schema{ID: s.ID, Attributes: schema.CommonAttributes()}
+	for _, ref := range append([]schema.Schema{s, common}, extensions...) {
+		if uri := path.URI(); uri != "" && !strings.EqualFold(uri, ref.ID) {
+			continue
+		}
+		attr, ok := ref.Attributes.ContainsAttribute(path.AttributeName)
+		if !ok {
+			continue
+		}
+		if subAttrName := path.SubAttributeName(); subAttrName != "" {
+			subAttr, ok := attr.SubAttributes().ContainsAttribute(subAttrName)
+			if !ok {
+				return schema.CoreAttribute{}, "", false
+			}
+			return subAttr, ref.ID, true
+		}
+		if attr.HasSubAttributes() {
+			// Complex attributes are sorted by their "value" sub-attribute.
+			subAttr, ok := attr.SubAttributes().ContainsAttribute("value")
+			if !ok {
+				return schema.CoreAttribute{}, "", false
+			}
+			return subAttr, ref.ID, true
+		}
+		return attr, ref.ID, true
+	}
+	return schema.CoreAttribute{}, "", false
+}
+
+// sortLookup returns the value of the attribute with given (case insensitive) name.
+func sortLookup(attributes map[string]interface{}, name string) interface{} {
+	if v, ok := attributes[name]; ok {
+		return v
+	}
+	for k, v := range attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// sortMetaTime returns the given time, or nil if it is not present.
+func sortMetaTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// sortNumber converts the given value to a float, if it is a number.
+func sortNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// sortTime converts the given value to a time, if it is a dateTime.
+func sortTime(value interface{}) (time.Time, bool) {
+	switch t := value.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, true
+	case string:
+		date, err := datetime.Parse(t)
+		return date, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// sortValue returns the value of the resource that the given attribute path refers to.
+func sortValue(r Resource, path filter.AttributePath, attr schema.CoreAttribute, uri string) interface{} {
+	attributes := map[string]interface{}(r.Attributes)
+	switch name := strings.ToLower(path.AttributeName); {
+	case name == schema.CommonAttributeID:
+		return r.ID
+	case name == schema.CommonAttributeExternalID && r.ExternalID.Present():
+		return r.ExternalID.Value()
+	case name == schema.CommonAttributeMeta:
+		switch strings.ToLower(path.SubAttributeName()) {
+		case "created":
+			return sortMetaTime(r.Meta.Created)
+		case "lastmodified":
+			return sortMetaTime(r.Meta.LastModified)
+		case "version":
+			if r.Meta.Version != "" {
+				return r.Meta.Version
+			}
+		}
+		return nil
+	}
+
+	// Attributes of extensions are nested within an object named after the schema.
+	for k, v := range attributes {
+		if strings.EqualFold(k, uri) {
+			if extension, ok := v.(map[string]interface{}); ok {
+				attributes = extension
+			}
+			break
+		}
+	}
+
+	value := sortLookup(attributes, path.AttributeName)
+	if values, ok := value.([]interface{}); ok {
+		// Multi-valued attributes are sorted by their primary value, or else the first value in the list.
+		value = nil
+		for i, v := range values {
+			if m, ok := v.(map[string]interface{}); ok && sortLookup(m, "primary") == true || i == 0 {
+				value = v
+			}
+		}
+	}
+	if m, ok := value.(map[string]interface{}); ok {
+		return sortLookup(m, attr.Name())
+	}
+	return value
+}
+
+// SortOrder is the order in which the "sortBy" parameter is applied.
+type SortOrder string
+
+const (
+	// SortOrderAscending orders the resources in ascending order. This is the default value.
+	SortOrderAscending SortOrder = "ascending"
+	// SortOrderDescending orders the resources in descending order.
+	SortOrderDescending SortOrder = "descending"
+)
@@ -0,0 +1,165 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestServerResourcesGetHandlerInvalidSort(t *testing.T) {
+	for _, query := range []string{
+		"sortBy=unknown",
+		"sortBy=name.unknown",
+		"sortBy=userName&sortOrder=random",
+	} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/Users?"+query, nil)
+			rr := httptest.NewRecorder()
+			newTestServer().ServeHTTP(rr, req)
+
+			assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
+
+func TestServerResourcesGetHandlerSort(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/Users?sortBy=userName&sortOrder=Descending", nil)
+	rr := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rr, req)
+
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+}
+
+func TestServerServiceProviderConfigHandlerSort(t *testing.T) {
+	for _, supported := range []bool{false, true} {
+		s := newTestServer()
+		s.Config.SupportSort = supported
+
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ServiceProviderConfig", nil))
+		assertEqualStatusCode(t, http.StatusOK, rr.Code)
+
+		var config map[string]interface{}
+		assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &config))
+		assertEqual(t, supported, config["sort"].(map[string]interface{})["supported"])
+	}
+}
+
+func TestSortResources(t *testing.T) {
+	s := schema.CoreUserSchema()
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	resources := []Resource{
+		{
+			ID: "a",
+			Attributes: ResourceAttributes{
+				"userName": "bob",
+				"emails": []interface{}{
+					map[string]interface{}{"value": "z@example.com"},
+					map[string]interface{}{"value": "b@example.com", "primary": true},
+				},
+			},
+			Meta: Meta{Created: &created},
+		},
+		{
+			ID: "b",
+			Attributes: ResourceAttributes{
+				"userName": "Alice",
+				"emails": []interface{}{
+					map[string]interface{}{"value": "c@example.com"},
+				},
+			},
+		},
+		{
+			ID:         "c",
+			Attributes: ResourceAttributes{},
+		},
+		{
+			ID: "d",
+			Attributes: ResourceAttributes{
+				"userName": "carol",
+				"emails": []interface{}{
+					map[string]interface{}{"value": "a@example.com"},
+				},
+			},
+			Meta: Meta{Created: &[]time.Time{created.Add(-time.Hour)}[0]},
+		},
+	}
+
+	for _, test := range []struct {
+		sortBy    string
+		sortOrder SortOrder
+		expected  []string
+	}{
+		{"userName", SortOrderAscending, []string{"b", "a", "d", "c"}},
+		{"userName", SortOrderDescending, []string{"c", "d", "a", "b"}},
+		{"emails", SortOrderAscending, []string{"d", "a", "b", "c"}},
+		{"emails.value", SortOrderDescending, []string{"c", "b", "a", "d"}},
+		{"meta.created", SortOrderAscending, []string{"d", "a", "b", "c"}},
+		{"id", SortOrderDescending, []string{"d", "c", "b", "a"}},
+	} {
+		t.Run(test.sortBy+" "+string(test.sortOrder), func(t *testing.T) {
+			sortBy, err := filter.ParseAttrPath([]byte(test.sortBy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sorted := append([]Resource{}, resources...)
+			SortResources(sorted, ListRequestParams{
+				SortBy:    &sortBy,
+				SortOrder: test.sortOrder,
+			}, s)
+
+			ids := make([]string, len(sorted))
+			for i, r := range sorted {
+				ids[i] = r.ID
+			}
+			assertEqualStrings(t, test.expected, ids)
+		})
+	}
+}
+
+func TestSortResourcesCaseExact(t *testing.T) {
+	s := schema.Schema{
+		ID: "urn:ietf:params:scim:schemas:core:2.0:Test",
+		Attributes: []schema.CoreAttribute{
+			schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+				CaseExact: true,
+				Name:      "code",
+			})),
+			schema.SimpleCoreAttribute(schema.SimpleNumberParams(schema.NumberParams{
+				Name: "rank",
+				Type: schema.AttributeTypeInteger(),
+			})),
+		},
+	}
+	resources := []Resource{
+		{ID: "0", Attributes: ResourceAttributes{"code": "b", "rank": json.Number("10")}},
+		{ID: "1", Attributes: ResourceAttributes{"code": "B", "rank": json.Number("9")}},
+		{ID: "2", Attributes: ResourceAttributes{"code": "a", "rank": 2}},
+	}
+
+	for _, test := range []struct {
+		sortBy   string
+		expected []string
+	}{
+		{"code", []string{"1", "2", "0"}},
+		{"rank", []string{"2", "1", "0"}},
+	} {
+		t.Run(test.sortBy, func(t *testing.T) {
+			sortBy, _ := filter.ParseAttrPath([]byte(test.sortBy))
+			sorted := append([]Resource{}, resources...)
+			SortResources(sorted, ListRequestParams{SortBy: &sortBy}, s)
+
+			ids := make([]string, len(sorted))
+			for i, r := range sorted {
+				ids[i] = r.ID
+			}
+			assertEqualStrings(t, test.expected, ids)
+		})
+	}
+}
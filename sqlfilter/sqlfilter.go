@@ -0,0 +1,433 @@
+// Package sqlfilter translates SCIM filter expressions into parameterized SQL WHERE clauses.
+//
+// The attribute types and the case sensitivity of the attributes are taken from the schemas of the resource type, e.g.
+// `userName eq "Bjensen"` is translated to `LOWER(users.user_name) = ?` with argument "bjensen", since the user name
+// is not case exact. Multi-valued attributes are expected to be stored in their own tables and are translated into
+// EXISTS subqueries.
+//
+// Attributes without a value are stored as NULL. Since SQL comparisons with NULL are unknown rather than false, the
+// clauses are written so that such attributes do not equal any value, e.g. `title ne "Tour Guide"` and
+// `not (title eq "Tour Guide")` both match resources without a title.
+package sqlfilter
+
+import (
+	"fmt"
+	"strings"
+
+	datetime "github.com/di-wu/xsd-datetime"
+	f "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+// likeEscape is the escape character of LIKE patterns. A backslash is not used, since MySQL treats it as an escape
+// character within string literals, which makes `ESCAPE '\'` a syntax error.
+const likeEscape = "!"
+
+// Dollar formats the n-th parameter (starting from 1) as used by PostgreSQL, e.g. $1.
+func Dollar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// Question formats parameters as used by MySQL and SQLite, i.e. ?.
+func Question(int) string {
+	return "?"
+}
+
+// compareValue converts the compare value of the given expression to an argument that matches the type of the given
+// attribute.
+func compareValue(attr schema.CoreAttribute, e *filter.AttributeExpression) (interface{}, error) {
+	invalid := func(reason string) error {
+		return &f.ExpressionError{
+			Expression: e.String(),
+			Reason:     reason,
+		}
+	}
+	switch typ := attr.AttributeType(); typ {
+	case "binary", "reference", "string":
+		value, ok := e.CompareValue.(string)
+		if !ok {
+			return nil, invalid(fmt.Sprintf("a %s attribute needs to be compared to a string", typ))
+		}
+		return value, nil
+	case "dateTime":
+		value, ok := e.CompareValue.(string)
+		if !ok {
+			return nil, invalid("a dateTime attribute needs to be compared to a string")
+		}
+		date, err := datetime.Parse(value)
+		if err != nil {
+			return nil, invalid("a dateTime attribute needs to be compared to a dateTime")
+		}
+		return date, nil
+	case "boolean":
+		value, ok := e.CompareValue.(bool)
+		if !ok {
+			return nil, invalid("a boolean attribute needs to be compared to a boolean")
+		}
+		return value, nil
+	case "decimal":
+		switch value := e.CompareValue.(type) {
+		case float64:
+			return value, nil
+		case int:
+			return float64(value), nil
+		}
+		return nil, invalid("a decimal attribute needs to be compared to a float/int")
+	case "integer":
+		value, ok := e.CompareValue.(int)
+		if !ok {
+			return nil, invalid("a integer attribute needs to be compared to a int")
+		}
+		return value, nil
+	default:
+		return nil, invalid(fmt.Sprintf("a %s attribute can not be compared to a value", typ))
+	}
+}
+
+// escapeLike escapes the wildcards within the given value, so it can be used in a LIKE pattern with likeEscape as
+// escape character.
+func escapeLike(value string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, `%`, likeEscape+`%`, `_`, likeEscape+`_`).Replace(value)
+}
+
+// present returns the condition under which the given column holds a value of the given attribute. Empty strings are
+// not present, see RFC 7644 section 3.4.2.2.
+func present(column string, attr schema.CoreAttribute) string {
+	switch attr.AttributeType() {
+	case "binary", "reference", "string":
+		return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", column, column)
+	default:
+		return fmt.Sprintf("%s IS NOT NULL", column)
+	}
+}
+
+// Table describes the table in which the values of a multi-valued attribute are stored, one value per row.
+type Table struct {
+	// Name is the name of the table, e.g. "emails".
+	Name string
+	// ForeignKey is the column of the table that refers to the resource, e.g. "emails.user_id".
+	ForeignKey string
+	// Reference is the column of the resource that the foreign key refers to, e.g. "users.id".
+	Reference string
+	// Columns maps the sub-attributes to the columns of the table, e.g. "type" to "emails.type". The values of a
+	// multi-valued attribute without sub-attributes are mapped with the key "value".
+	Columns map[string]string
+}
+
+// Translator translates filter expressions into parameterized SQL WHERE clauses for a single resource type.
+type Translator struct {
+	// Schema is the main schema of the resource type. The common attributes (i.e. id, externalId and meta) do not need
+	// to be part of the schema.
+	Schema schema.Schema
+	// Extensions are the schema extensions of the resource type.
+	Extensions []schema.Schema
+	// Columns maps the singular attributes to their columns. Attributes of the main schema are keyed by their name
+	// (e.g. "userName" or "name.familyName"), attributes of extensions are prefixed with the id of the extension
+	// (e.g. "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber").
+	Columns map[string]string
+	// Tables maps the multi-valued attributes to the tables in which their values are stored. The keys follow the same
+	// naming as the keys of Columns.
+	Tables map[string]Table
+	// Placeholder formats the parameters within the clause. Defaults to Question.
+	Placeholder func(n int) string
+}
+
+// Translate translates the given filter expression into a WHERE clause (without the WHERE keyword) and its arguments.
+// A *filter.PathError is returned if an attribute is unknown or not mapped to a column, a *filter.ExpressionError if
+// the compare value or operator does not match the type of the attribute.
+func (t Translator) Translate(e filter.Expression) (string, []interface{}, error) {
+	placeholder := t.Placeholder
+	if placeholder == nil {
+		placeholder = Question
+	}
+	main := t.Schema
+	main.Attributes = append(append(schema.Attributes{}, t.Schema.Attributes...), schema.CommonAttributes()...)
+	b := &builder{
+		refs:        append([]schema.Schema{main}, t.Extensions...),
+		columns:     t.Columns,
+		tables:      t.Tables,
+		placeholder: placeholder,
+	}
+	clause, err := b.expression(e)
+	if err != nil {
+		return "", nil, err
+	}
+	return clause, b.args, nil
+}
+
+// builder builds a WHERE clause and keeps track of its arguments.
+type builder struct {
+	// refs are the reference schemas, the first one being the main schema.
+	refs        []schema.Schema
+	columns     map[string]string
+	tables      map[string]Table
+	placeholder func(n int) string
+
+	args []interface{}
+}
+
+// attributeExpression translates an attribute expression. Expressions on multi-valued attributes are translated into
+// an EXISTS subquery on the table of the attribute.
+func (b *builder) attributeExpression(e *filter.AttributeExpression) (string, error) {
+	key, attr, err := b.resolve(e.AttributePath)
+	if err != nil {
+		return "", err
+	}
+
+	if attr.MultiValued() {
+		table, ok := b.tables[key]
+		if !ok {
+			return "", &f.PathError{
+				Path:   e.AttributePath.String(),
+				Reason: "no table is mapped to the multi-valued attribute",
+			}
+		}
+		// e.g. emails co "example.com" is equal to emails.value co "example.com"
+		subAttr, subAttrName := attr, "value"
+		if attr.HasSubAttributes() {
+			if name := e.AttributePath.SubAttributeName(); name != "" {
+				subAttrName = name
+			}
+			if subAttr, ok = attr.SubAttributes().ContainsAttribute(subAttrName); !ok {
+				return "", &f.PathError{
+					Path:   e.AttributePath.String(),
+					Reason: fmt.Sprintf("the attribute has no sub-attributes named: %s", subAttrName),
+				}
+			}
+			subAttrName = subAttr.Name()
+		}
+		column, ok := table.Columns[subAttrName]
+		if !ok {
+			return "", &f.PathError{
+				Path:   e.AttributePath.String(),
+				Reason: "no column is mapped to the sub-attribute",
+			}
+		}
+		// e.g. emails ne "bjensen@example.com" matches if none of the values equals the compare value.
+		if e.Operator == filter.NE && e.CompareValue != nil {
+			eq := *e
+			eq.Operator = filter.EQ
+			condition, err := b.compare(column, subAttr, &eq)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("NOT %s", b.exists(table, condition)), nil
+		}
+		condition, err := b.compare(column, subAttr, e)
+		if err != nil {
+			return "", err
+		}
+		return b.exists(table, condition), nil
+	}
+
+	// e.g. name.givenName
+	//           ^________
+	if subAttrName := e.AttributePath.SubAttributeName(); subAttrName != "" {
+		subAttr, ok := attr.SubAttributes().ContainsAttribute(subAttrName)
+		if !attr.HasSubAttributes() || !ok {
+			return "", &f.PathError{
+				Path:   e.AttributePath.String(),
+				Reason: fmt.Sprintf("the attribute has no sub-attributes named: %s", subAttrName),
+			}
+		}
+		key = fmt.Sprintf("%s.%s", key, subAttr.Name())
+		attr = subAttr
+	}
+	column, ok := b.columns[key]
+	if !ok {
+		return "", &f.PathError{
+			Path:   e.AttributePath.String(),
+			Reason: "no column is mapped to the attribute",
+		}
+	}
+	return b.compare(column, attr, e)
+}
+
+// compare translates the comparison of the given column with the compare value of the given expression.
+func (b *builder) compare(column string, attr schema.CoreAttribute, e *filter.AttributeExpression) (string, error) {
+	if e.Operator == filter.PR {
+		return present(column, attr), nil
+	}
+	if e.CompareValue == nil {
+		switch e.Operator {
+		case filter.EQ:
+			return fmt.Sprintf("%s IS NULL", column), nil
+		case filter.NE:
+			return present(column, attr), nil
+		default:
+			return "", &f.ExpressionError{
+				Expression: e.String(),
+				Reason:     fmt.Sprintf("can not use op %q on null", e.Operator),
+			}
+		}
+	}
+
+	value, err := compareValue(attr, e)
+	if err != nil {
+		return "", err
+	}
+	typ := attr.AttributeType()
+	null := fmt.Sprintf("%s IS NULL", column)
+	if s, ok := value.(string); ok && typ != "binary" && !attr.CaseExact() {
+		column = fmt.Sprintf("LOWER(%s)", column)
+		value = strings.ToLower(s)
+	}
+
+	switch op := e.Operator; op {
+	case filter.EQ:
+		return fmt.Sprintf("%s = %s", column, b.param(value)), nil
+	case filter.NE:
+		return fmt.Sprintf("(%s OR %s <> %s)", null, column, b.param(value)), nil
+	case filter.CO, filter.SW, filter.EW:
+		s, ok := value.(string)
+		if !ok {
+			return "", &f.ExpressionError{
+				Expression: e.String(),
+				Reason:     fmt.Sprintf("can not use op %q on %s values", op, typ),
+			}
+		}
+		pattern := escapeLike(s)
+		if op != filter.SW {
+			pattern = "%" + pattern
+		}
+		if op != filter.EW {
+			pattern += "%"
+		}
+		return fmt.Sprintf("%s LIKE %s ESCAPE '%s'", column, b.param(pattern), likeEscape), nil
+	case filter.GT, filter.GE, filter.LT, filter.LE:
+		if typ == "binary" || typ == "boolean" {
+			return "", &f.ExpressionError{
+				Expression: e.String(),
+				Reason:     fmt.Sprintf("can not use op %q on %s values", op, typ),
+			}
+		}
+		operator := map[filter.CompareOperator]string{
+			filter.GT: ">",
+			filter.GE: ">=",
+			filter.LT: "<",
+			filter.LE: "<=",
+		}[op]
+		return fmt.Sprintf("%s %s %s", column, operator, b.param(value)), nil
+	default:
+		return "", &f.ExpressionError{
+			Expression: e.String(),
+			Reason:     fmt.Sprintf("unknown operator: %s", op),
+		}
+	}
+}
+
+// exists wraps the given condition in a subquery on the given table.
+func (b *builder) exists(table Table, condition string) string {
+	return fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM %s WHERE %s = %s AND %s)",
+		table.Name, table.ForeignKey, table.Reference, condition,
+	)
+}
+
+// expression translates the given expression.
+func (b *builder) expression(e filter.Expression) (string, error) {
+	switch e := e.(type) {
+	case *filter.ValuePath:
+		return b.valuePath(e)
+	case *filter.AttributeExpression:
+		return b.attributeExpression(e)
+	case *filter.LogicalExpression:
+		left, err := b.expression(e.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := b.expression(e.Right)
+		if err != nil {
+			return "", err
+		}
+		switch e.Operator {
+		case filter.AND:
+			return fmt.Sprintf("(%s AND %s)", left, right), nil
+		case filter.OR:
+			return fmt.Sprintf("(%s OR %s)", left, right), nil
+		default:
+			return "", &f.ExpressionError{
+				Expression: e.String(),
+				Reason:     fmt.Sprintf("unknown logical operator: %s", e.Operator),
+			}
+		}
+	case *filter.NotExpression:
+		clause, err := b.expression(e.Expression)
+		if err != nil {
+			return "", err
+		}
+		// The clause is unknown if it compares a NULL value, in which case its negation would be unknown as well.
+		return fmt.Sprintf("NOT COALESCE(%s, FALSE)", clause), nil
+	default:
+		return "", &f.ExpressionError{
+			Expression: fmt.Sprint(e),
+			Reason:     "unknown expression type",
+		}
+	}
+}
+
+// param adds the given argument and returns its placeholder.
+func (b *builder) param(value interface{}) string {
+	b.args = append(b.args, value)
+	return b.placeholder(len(b.args))
+}
+
+// resolve returns the key (in Columns or Tables) and the attribute to which the given attribute path applies. The
+// sub-attribute of the path is not resolved.
+func (b *builder) resolve(attrPath filter.AttributePath) (string, schema.CoreAttribute, error) {
+	for i, ref := range b.refs {
+		if uri := attrPath.URI(); uri != "" && !strings.EqualFold(uri, ref.ID) {
+			continue
+		}
+		attr, ok := ref.Attributes.ContainsAttribute(attrPath.AttributeName)
+		if !ok {
+			continue
+		}
+		if i == 0 {
+			return attr.Name(), attr, nil
+		}
+		return fmt.Sprintf("%s:%s", ref.ID, attr.Name()), attr, nil
+	}
+	return "", schema.CoreAttribute{}, &f.PathError{
+		Path:   attrPath.String(),
+		Reason: "could not find an attribute that matches the attribute path",
+	}
+}
+
+// valuePath translates a value path into an EXISTS subquery on the table of the multi-valued attribute, in which the
+// value filter is applied to the sub-attributes.
+func (b *builder) valuePath(e *filter.ValuePath) (string, error) {
+	key, attr, err := b.resolve(e.AttributePath)
+	if err != nil {
+		return "", err
+	}
+	if !attr.MultiValued() || !attr.HasSubAttributes() {
+		return "", &f.PathError{
+			Path:   e.AttributePath.String(),
+			Reason: "value path filters can only be applied to multi-valued complex attributes",
+		}
+	}
+	table, ok := b.tables[key]
+	if !ok {
+		return "", &f.PathError{
+			Path:   e.AttributePath.String(),
+			Reason: "no table is mapped to the multi-valued attribute",
+		}
+	}
+
+	sub := &builder{
+		refs: []schema.Schema{{
+			Attributes: attr.SubAttributes(),
+		}},
+		columns:     table.Columns,
+		placeholder: b.placeholder,
+		args:        b.args,
+	}
+	condition, err := sub.expression(e.ValueFilter)
+	if err != nil {
+		return "", err
+	}
+	b.args = sub.args
+	return b.exists(table, condition), nil
+}
@@ -0,0 +1,323 @@
+package sqlfilter
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	f "github.com/elimity-com/scim/filter"
+	"github.com/elimity-com/scim/schema"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestTranslator_Translate(t *testing.T) {
+	for _, test := range []struct {
+		filter   string
+		clause   string
+		args     []interface{}
+		postgres string
+	}{
+		{
+			filter: `userName eq "Bjensen"`,
+			clause: `LOWER(users.user_name) = ?`,
+			args:   []interface{}{"bjensen"},
+		},
+		{
+			filter: `id ne "0001"`,
+			clause: `(users.id IS NULL OR users.id <> ?)`,
+			args:   []interface{}{"0001"},
+		},
+		{
+			filter: `name.familyName co "O'Mal_ley"`,
+			clause: `LOWER(users.family_name) LIKE ? ESCAPE '!'`,
+			args:   []interface{}{`%o'mal!_ley%`},
+		},
+		{
+			filter: `userName sw "J"`,
+			clause: `LOWER(users.user_name) LIKE ? ESCAPE '!'`,
+			args:   []interface{}{"j%"},
+		},
+		{
+			filter: `title ne "Tour Guide"`,
+			clause: `(users.title IS NULL OR LOWER(users.title) <> ?)`,
+			args:   []interface{}{"tour guide"},
+		},
+		{
+			filter: `URN:IETF:PARAMS:SCIM:SCHEMAS:CORE:2.0:USER:userName eq "Bjensen"`,
+			clause: `LOWER(users.user_name) = ?`,
+			args:   []interface{}{"bjensen"},
+		},
+		{
+			filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName ew "100%"`,
+			clause: `LOWER(users.user_name) LIKE ? ESCAPE '!'`,
+			args:   []interface{}{`%100!%`},
+		},
+		{
+			filter: `title pr`,
+			clause: `(users.title IS NOT NULL AND users.title <> '')`,
+		},
+		{
+			filter: `title ne null`,
+			clause: `(users.title IS NOT NULL AND users.title <> '')`,
+		},
+		{
+			filter: `meta.lastModified pr`,
+			clause: `users.last_modified IS NOT NULL`,
+		},
+		{
+			filter: `title eq null`,
+			clause: `users.title IS NULL`,
+		},
+		{
+			filter: `active eq true`,
+			clause: `users.active = ?`,
+			args:   []interface{}{true},
+		},
+		{
+			filter: `meta.lastModified gt "2011-05-13T04:42:34Z"`,
+			clause: `users.last_modified > ?`,
+			args:   []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		},
+		{
+			filter: `employeeNumber ge "100" and urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber le "200"`,
+			clause: `(LOWER(users.employee_number) >= ? AND LOWER(users.employee_number) <= ?)`,
+			args:   []interface{}{"100", "200"},
+		},
+		{
+			filter:   `title pr and (userType eq "Employee" or not (userType lt "Intern"))`,
+			clause:   `((users.title IS NOT NULL AND users.title <> '') AND (LOWER(users.user_type) = ? OR NOT COALESCE(LOWER(users.user_type) < ?, FALSE)))`,
+			args:     []interface{}{"employee", "intern"},
+			postgres: `((users.title IS NOT NULL AND users.title <> '') AND (LOWER(users.user_type) = $1 OR NOT COALESCE(LOWER(users.user_type) < $2, FALSE)))`,
+		},
+		{
+			filter: `emails co "example.com"`,
+			clause: `EXISTS (SELECT 1 FROM emails WHERE emails.user_id = users.id AND LOWER(emails.value) LIKE ? ESCAPE '!')`,
+			args:   []interface{}{"%example.com%"},
+		},
+		{
+			filter: `emails ne "bjensen@example.com"`,
+			clause: `NOT EXISTS (SELECT 1 FROM emails WHERE emails.user_id = users.id AND LOWER(emails.value) = ?)`,
+			args:   []interface{}{"bjensen@example.com"},
+		},
+		{
+			filter: `emails.type eq "work"`,
+			clause: `EXISTS (SELECT 1 FROM emails WHERE emails.user_id = users.id AND LOWER(emails.type) = ?)`,
+			args:   []interface{}{"work"},
+		},
+		{
+			filter:   `userType eq "Employee" and emails[type eq "work" and value co "@example.com"]`,
+			clause:   `(LOWER(users.user_type) = ? AND EXISTS (SELECT 1 FROM emails WHERE emails.user_id = users.id AND (LOWER(emails.type) = ? AND LOWER(emails.value) LIKE ? ESCAPE '!')))`,
+			args:     []interface{}{"employee", "work", "%@example.com%"},
+			postgres: `(LOWER(users.user_type) = $1 AND EXISTS (SELECT 1 FROM emails WHERE emails.user_id = users.id AND (LOWER(emails.type) = $2 AND LOWER(emails.value) LIKE $3 ESCAPE '!')))`,
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			exp, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			translator := newTestTranslator()
+			clause, args, err := translator.Translate(exp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clause != test.clause {
+				t.Errorf("expected clause %s, got %s", test.clause, clause)
+			}
+			if fmt.Sprint(args) != fmt.Sprint(test.args) {
+				t.Errorf("expected arguments %v, got %v", test.args, args)
+			}
+
+			if test.postgres != "" {
+				translator.Placeholder = Dollar
+				clause, _, err := translator.Translate(exp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if clause != test.postgres {
+					t.Errorf("expected clause %s, got %s", test.postgres, clause)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslator_TranslateDialects(t *testing.T) {
+	exp, err := filter.ParseFilter([]byte(`name.familyName co "a\\b!c%d_e"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		dialect     string
+		placeholder func(n int) string
+		clause      string
+	}{
+		{"MySQL", Question, `LOWER(users.family_name) LIKE ? ESCAPE '!'`},
+		{"SQLite", Question, `LOWER(users.family_name) LIKE ? ESCAPE '!'`},
+		{"PostgreSQL", Dollar, `LOWER(users.family_name) LIKE $1 ESCAPE '!'`},
+	} {
+		t.Run(test.dialect, func(t *testing.T) {
+			translator := newTestTranslator()
+			translator.Placeholder = test.placeholder
+			clause, args, err := translator.Translate(exp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The clause has no backslashes, which MySQL would interpret as escape characters within string literals.
+			if clause != test.clause {
+				t.Errorf("expected clause %s, got %s", test.clause, clause)
+			}
+			// The backslash of the value is not an escape character of the pattern, so it is matched as is.
+			if expected := `%a\\b!!c!%d!_e%`; fmt.Sprint(args) != fmt.Sprint([]interface{}{expected}) {
+				t.Errorf("expected arguments %v, got %v", []interface{}{expected}, args)
+			}
+		})
+	}
+}
+
+func TestTranslator_TranslateSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	// The driver needs cgo, without it every query fails.
+	if err := db.Ping(); err != nil {
+		t.Skipf("SQLite is not available: %v", err)
+	}
+
+	for _, statement := range []string{
+		`CREATE TABLE users (id TEXT PRIMARY KEY, user_name TEXT, family_name TEXT, title TEXT, user_type TEXT,
+			active BOOLEAN, last_modified DATETIME, employee_number TEXT)`,
+		`CREATE TABLE emails (user_id TEXT, value TEXT, type TEXT)`,
+		`INSERT INTO users (id, user_name, title, user_type, active, employee_number)
+			VALUES ('0001', 'bjensen', 'Tour Guide', 'Employee', TRUE, '100')`,
+		`INSERT INTO users (id, user_name, family_name, active) VALUES ('0002', 'jsmith', 'O''Malley', FALSE)`,
+		`INSERT INTO users (id, user_name, title, user_type) VALUES ('0003', 'alice', '', 'Intern')`,
+		`INSERT INTO emails (user_id, value, type) VALUES ('0001', 'bjensen@example.com', 'work')`,
+		`INSERT INTO emails (user_id, value, type) VALUES ('0001', 'babs@jensen.org', 'home')`,
+		`INSERT INTO emails (user_id, value, type) VALUES ('0003', 'alice@example.com', NULL)`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		filter   string
+		expected []string
+	}{
+		{`userName eq "BJensen"`, []string{"0001"}},
+		{`title ne "Tour Guide"`, []string{"0002", "0003"}},
+		{`not (title eq "Tour Guide")`, []string{"0002", "0003"}},
+		{`not (title eq "Tour Guide" or userType eq "Intern")`, []string{"0002"}},
+		{`title pr`, []string{"0001"}},
+		{`active eq true`, []string{"0001"}},
+		{`name.familyName co "'mal"`, []string{"0002"}},
+		{`emails ne "bjensen@example.com"`, []string{"0002", "0003"}},
+		{`not (emails co "example.com")`, []string{"0002"}},
+		{`emails[type ne "work"]`, []string{"0001", "0003"}},
+		{`emails[not (type eq "home")]`, []string{"0001", "0003"}},
+		{`URN:IETF:PARAMS:SCIM:SCHEMAS:EXTENSION:ENTERPRISE:2.0:USER:employeeNumber eq "100"`, []string{"0001"}},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			exp, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			clause, args, err := newTestTranslator().Translate(exp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := db.Query("SELECT id FROM users WHERE "+clause+" ORDER BY id", args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = rows.Close() }()
+			var ids []string
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(test.expected) {
+				t.Errorf("expected %v, got %v for %s", test.expected, ids, clause)
+			}
+		})
+	}
+}
+
+func TestTranslator_TranslateErrors(t *testing.T) {
+	for _, test := range []struct {
+		filter string
+		path   bool
+	}{
+		{filter: `invalid eq "value"`, path: true},
+		{filter: `nickName eq "Babs"`, path: true},
+		{filter: `name.invalid eq "value"`, path: true},
+		{filter: `name[givenName eq "Barbara"]`, path: true},
+		{filter: `ims[type eq "xmpp"]`, path: true},
+		{filter: `emails[display eq "work"]`, path: true},
+		{filter: `active eq "true"`},
+		{filter: `active gt true`},
+		{filter: `meta.lastModified co "2011"`},
+		{filter: `meta.lastModified gt "yesterday"`},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			exp, err := filter.ParseFilter([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = newTestTranslator().Translate(exp)
+
+			var (
+				pathErr       *f.PathError
+				expressionErr *f.ExpressionError
+			)
+			if test.path && !errors.As(err, &pathErr) {
+				t.Errorf("expected a path error, got %v", err)
+			}
+			if !test.path && !errors.As(err, &expressionErr) {
+				t.Errorf("expected an expression error, got %v", err)
+			}
+		})
+	}
+}
+
+func newTestTranslator() Translator {
+	return Translator{
+		Schema:     schema.CoreUserSchema(),
+		Extensions: []schema.Schema{schema.ExtensionEnterpriseUser()},
+		Columns: map[string]string{
+			"id":                "users.id",
+			"userName":          "users.user_name",
+			"name.familyName":   "users.family_name",
+			"title":             "users.title",
+			"userType":          "users.user_type",
+			"active":            "users.active",
+			"meta.lastModified": "users.last_modified",
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber": "users.employee_number",
+		},
+		Tables: map[string]Table{
+			"emails": {
+				Name:       "emails",
+				ForeignKey: "emails.user_id",
+				Reference:  "users.id",
+				Columns: map[string]string{
+					"value": "emails.value",
+					"type":  "emails.type",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,145 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elimity-com/scim/errors"
+)
+
+// TenantFromContext returns the identifier of the tenant of the request with the given context, as resolved by the
+// tenant resolver of the server. It is empty for servers that serve a single tenant.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// withTenant returns a shallow copy of the given request of which the context holds the given tenant.
+func withTenant(r *http.Request, tenant string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant))
+}
+
+// HeaderTenantResolver is a tenant resolver that takes the tenant from the request header with the given name, e.g.,
+// "X-Tenant". Requests without the header are rejected.
+type HeaderTenantResolver string
+
+// ResolveTenant returns the value of the header and the given path.
+func (h HeaderTenantResolver) ResolveTenant(r *http.Request, path string) (string, string, error) {
+	tenant := strings.TrimSpace(r.Header.Get(string(h)))
+	if tenant == "" {
+		return "", "", errors.ScimErrorBadRequest(fmt.Sprintf("Missing tenant header: %s.", string(h)))
+	}
+	return tenant, path, nil
+}
+
+// HostTenantResolver is a tenant resolver that takes the tenant from the first label of the host of the request, e.g.,
+// "acme" for "acme.scim.example.com". Requests for hosts without subdomain, or for IP addresses, are rejected.
+type HostTenantResolver struct{}
+
+// ResolveTenant returns the first label of the host and the given path.
+func (HostTenantResolver) ResolveTenant(r *http.Request, path string) (string, string, error) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 || labels[0] == "" || net.ParseIP(host) != nil {
+		return "", "", errors.ScimError{
+			Detail: "Specified endpoint does not exist.",
+			Status: http.StatusNotFound,
+		}
+	}
+	return labels[0], path, nil
+}
+
+// PathTenantResolver is a tenant resolver that takes the tenant from the first segment of the path that follows the
+// prefix of the server, e.g., "acme" for "/v2/acme/Users" if the prefix is "/v2".
+type PathTenantResolver struct{}
+
+// ResolveTenant returns the first segment of the given path and the remainder of the path.
+func (PathTenantResolver) ResolveTenant(_ *http.Request, path string) (string, string, error) {
+	segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if segments[0] == "" {
+		return "", "", errors.ScimError{
+			Detail: "Specified endpoint does not exist.",
+			Status: http.StatusNotFound,
+		}
+	}
+	if len(segments) == 1 {
+		return segments[0], "", nil
+	}
+	return segments[0], "/" + segments[1], nil
+}
+
+// Tenant is the configuration of a tenant of a server that serves multiple tenants. The schemas of the tenant are the
+// schemas (and schema extensions) of its resource types.
+type Tenant struct {
+	Config        ServiceProviderConfig
+	ResourceTypes []ResourceType
+}
+
+// TenantCache is a tenant provider that caches the tenants of another provider. Failed loads are not cached. It is
+// safe for concurrent use.
+type TenantCache struct {
+	provider TenantProvider
+	tenants  *cache
+}
+
+// NewTenantCache returns a tenant provider that caches the tenants that are loaded by the given provider for the given
+// duration. Tenants are cached until they are invalidated if the duration is zero.
+func NewTenantCache(provider TenantProvider, ttl time.Duration) *TenantCache {
+	return &TenantCache{
+		provider: provider,
+		tenants:  newCache(ttl),
+	}
+}
+
+// Invalidate removes the tenant with the given identifier from the cache, so its configuration is loaded again on the
+// next request.
+func (c *TenantCache) Invalidate(tenant string) {
+	c.tenants.invalidate(tenant)
+}
+
+// InvalidateAll removes all tenants from the cache.
+func (c *TenantCache) InvalidateAll() {
+	c.tenants.invalidateAll()
+}
+
+// LoadTenant returns the cached tenant with the given identifier, or loads it with the underlying provider if it is not
+// cached or expired.
+func (c *TenantCache) LoadTenant(r *http.Request, tenant string) (Tenant, error) {
+	if cached, ok := c.tenants.get(tenant); ok {
+		return cached.(Tenant), nil
+	}
+
+	t, err := c.provider.LoadTenant(r, tenant)
+	if err != nil {
+		return Tenant{}, err
+	}
+	c.tenants.set(tenant, t)
+	return t, nil
+}
+
+// TenantProvider provides the configuration of the tenants of a server.
+type TenantProvider interface {
+	// LoadTenant returns the configuration of the tenant with the given identifier. An error should be returned if the
+	// tenant does not exist, e.g., a SCIM error with status 404.
+	LoadTenant(r *http.Request, tenant string) (Tenant, error)
+}
+
+// TenantResolver resolves the tenant a request is served for. The server serves the request with the configuration
+// of the tenant, which is loaded by the tenant provider of the server.
+type TenantResolver interface {
+	// ResolveTenant returns the identifier of the tenant of the given request and the path that remains after the
+	// tenant is removed from the given path. The given path is the path of the request without the prefix of the
+	// server. Resolvers that do not take the tenant from the path return the given path as is.
+	ResolveTenant(r *http.Request, path string) (tenant string, remaining string, err error)
+}
+
+// tenantKey is the context key of the tenant of a request.
+type tenantKey struct{}
@@ -0,0 +1,220 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/optional"
+)
+
+func TestServerTenants(t *testing.T) {
+	users := newTestServer().ResourceTypes[0]
+	groups := newTestServer().ResourceTypes[2]
+	provider := &testTenantProvider{
+		tenants: map[string]Tenant{
+			"acme": {
+				Config: ServiceProviderConfig{
+					DocumentationURI: optional.NewString("https://acme.example.com"),
+					SupportBulk:      true,
+				},
+				ResourceTypes: []ResourceType{users},
+			},
+			"globex": {
+				ResourceTypes: []ResourceType{groups},
+			},
+		},
+	}
+	cache := NewTenantCache(provider, 0)
+	s := Server{
+		Prefix:         "/v2",
+		TenantProvider: cache,
+		TenantResolver: PathTenantResolver{},
+	}
+
+	for _, test := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"get", http.MethodGet, "/v2/acme/Users/0001", "", http.StatusOK},
+		{"other tenant", http.MethodGet, "/v2/globex/Users/0001", "", http.StatusNotFound},
+		{"unknown tenant", http.MethodGet, "/v2/initech/Users/0001", "", http.StatusNotFound},
+		{"no tenant", http.MethodGet, "/v2/", "", http.StatusNotFound},
+		{"bulk", http.MethodPost, "/v2/acme/Bulk", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [{"method": "POST", "path": "/Users", "bulkId": "1", "data": {"userName": "test"}}]
+		}`, http.StatusOK},
+		{"bulk not supported", http.MethodPost, "/v2/globex/Bulk", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [{"method": "POST", "path": "/Groups", "bulkId": "1", "data": {"displayName": "test"}}]
+		}`, http.StatusNotImplemented},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+			assertEqualStatusCode(t, test.status, rr.Code)
+		})
+	}
+
+	// The operations of a bulk request are served for the tenant of the request.
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v2/acme/Bulk", strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+		"Operations": [{"method": "POST", "path": "/Users", "bulkId": "1", "data": {"userName": "test"}}]
+	}`)))
+	assertTrue(t, strings.Contains(rr.Body.String(), `"status":"201"`))
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v2/acme/Users", strings.NewReader(`{"userName": "test"}`)))
+	assertEqualStatusCode(t, http.StatusCreated, rr.Code)
+	assertStringStartsWith(t, "http://example.com/v2/acme/Users/", rr.Header().Get("Location"))
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v2/globex/ResourceTypes", nil))
+	var resourceTypes map[string]interface{}
+	assertUnmarshalNoError(t, json.Unmarshal(rr.Body.Bytes(), &resourceTypes))
+	assertEqual(t, float64(1), resourceTypes["totalResults"])
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v2/acme/ServiceProviderConfig", nil))
+	assertTrue(t, strings.Contains(rr.Body.String(), "https://acme.example.com"))
+
+	// Tenants are loaded once, until they are invalidated.
+	assertEqual(t, 1, provider.loads["acme"])
+	cache.Invalidate("acme")
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/acme/Users/0001", nil))
+	assertEqual(t, 2, provider.loads["acme"])
+}
+
+func TestServerTenantsRequestInfo(t *testing.T) {
+	handler := &testContextResourceHandler{
+		handler: newTestResourceHandler(),
+	}
+	users := newTestServer().ResourceTypes[0]
+	users.Handler = nil
+	users.ContextHandler = handler
+	s := Server{
+		TenantProvider: &testTenantProvider{
+			tenants: map[string]Tenant{"acme": {ResourceTypes: []ResourceType{users}}},
+		},
+		TenantResolver: HeaderTenantResolver("X-Tenant"),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/Users/0001", nil)
+	req.Header.Set("X-Tenant", "acme")
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	assertEqualStatusCode(t, http.StatusOK, rr.Code)
+	assertLen(t, handler.infos, 1)
+	assertEqual(t, "acme", handler.infos[0].Tenant)
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/Users/0001", nil))
+	assertEqualStatusCode(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestServerTenantsWithoutProvider(t *testing.T) {
+	reporter := &testErrorReporter{}
+	s := Server{
+		ErrorReporter:  reporter,
+		TenantResolver: PathTenantResolver{},
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/acme/Users", nil))
+	assertEqualStatusCode(t, http.StatusInternalServerError, rr.Code)
+	assertLen(t, reporter.reports, 1)
+}
+
+func TestTenantCacheExpiration(t *testing.T) {
+	provider := &testTenantProvider{
+		tenants: map[string]Tenant{"acme": {}},
+	}
+	now := time.Unix(0, 0)
+	cache := NewTenantCache(provider, time.Minute)
+	cache.tenants.now = func() time.Time { return now }
+
+	for _, d := range []time.Duration{0, 30 * time.Second, 2 * time.Minute} {
+		now = now.Add(d)
+		_, err := cache.LoadTenant(nil, "acme")
+		assertTrue(t, err == nil)
+	}
+	assertEqual(t, 2, provider.loads["acme"])
+
+	_, err := cache.LoadTenant(nil, "initech")
+	assertTrue(t, err != nil)
+	cache.InvalidateAll()
+	_, _ = cache.LoadTenant(nil, "acme")
+	assertEqual(t, 3, provider.loads["acme"])
+}
+
+func TestTenantResolvers(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		resolver  TenantResolver
+		host      string
+		header    string
+		path      string
+		tenant    string
+		remaining string
+	}{
+		{"header", HeaderTenantResolver("X-Tenant"), "scim.example.com", "acme", "/Users", "acme", "/Users"},
+		{"host", HostTenantResolver{}, "acme.scim.example.com:8080", "", "/Users", "acme", "/Users"},
+		{"path", PathTenantResolver{}, "scim.example.com", "", "/acme/Users/0001", "acme", "/Users/0001"},
+		{"missing header", HeaderTenantResolver("X-Tenant"), "scim.example.com", "", "/Users", "", ""},
+		{"missing subdomain", HostTenantResolver{}, "example.com", "", "/Users", "", ""},
+		{"ipv4", HostTenantResolver{}, "10.0.0.1", "", "/Users", "", ""},
+		{"ipv4 with port", HostTenantResolver{}, "10.0.0.1:8080", "", "/Users", "", ""},
+		{"ipv6", HostTenantResolver{}, "[::ffff:10.0.0.1]", "", "/Users", "", ""},
+		{"ipv6 with port", HostTenantResolver{}, "[::ffff:10.0.0.1]:8080", "", "/Users", "", ""},
+		{"missing segment", PathTenantResolver{}, "scim.example.com", "", "/", "", ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = test.host
+			if test.header != "" {
+				r.Header.Set("X-Tenant", test.header)
+			}
+
+			tenant, remaining, err := test.resolver.ResolveTenant(r, test.path)
+			if test.tenant == "" {
+				if _, ok := err.(errors.ScimError); !ok {
+					t.Fatalf("expected a scim error, got %v", err)
+				}
+				return
+			}
+			assertTrue(t, err == nil)
+			assertEqual(t, test.tenant, tenant)
+			assertEqual(t, test.remaining, remaining)
+		})
+	}
+}
+
+// testTenantProvider provides the given tenants and counts the number of times each tenant is loaded.
+type testTenantProvider struct {
+	tenants map[string]Tenant
+	loads   map[string]int
+}
+
+func (p *testTenantProvider) LoadTenant(_ *http.Request, tenant string) (Tenant, error) {
+	if p.loads == nil {
+		p.loads = make(map[string]int)
+	}
+	p.loads[tenant]++
+
+	t, ok := p.tenants[tenant]
+	if !ok {
+		return Tenant{}, errors.ScimError{
+			Detail: "Tenant " + tenant + " not found.",
+			Status: http.StatusNotFound,
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,152 @@
+package scim
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/elimity-com/scim/schema"
+)
+
+// equalUniqueValue reports whether the given singular values are equal. Strings are compared case insensitive, unless
+// caseExact is true.
+func equalUniqueValue(v, w interface{}, caseExact bool) bool {
+	if v, ok := v.(string); ok {
+		if w, ok := w.(string); ok {
+			if caseExact {
+				return v == w
+			}
+			return strings.EqualFold(v, w)
+		}
+	}
+	return reflect.DeepEqual(v, w)
+}
+
+// touchesUniqueAttribute reports whether the given PATCH operation may modify one of the given unique attributes. An
+// operation without path may modify the attributes, and the extensions, that are named by the keys of its value.
+func touchesUniqueAttribute(op PatchOperation, attributes []UniqueAttribute) bool {
+	var names []string
+	if op.Path != nil {
+		path := op.Path.AttributePath
+		names = append(names, path.AttributeName)
+		if path.URIPrefix != nil {
+			names = append(names, path.URI()+":"+path.AttributeName)
+		}
+	} else if value, ok := op.Value.(map[string]interface{}); ok {
+		for k := range value {
+			names = append(names, k)
+		}
+	}
+
+	for _, attr := range attributes {
+		for _, name := range names {
+			if strings.EqualFold(name, attr.Attribute.Name()) || strings.EqualFold(name, attr.Path()) ||
+				attr.Extension != "" && strings.EqualFold(name, attr.Extension) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UniqueAttribute is an attribute of which the uniqueness is "server" or "global", which means that two resources can
+// not have the same value for the attribute.
+type UniqueAttribute struct {
+	// Extension is the id of the schema extension the attribute belongs to. It is empty for attributes of the main
+	// schema of the resource type.
+	Extension string
+	// Attribute is the definition of the attribute.
+	Attribute schema.CoreAttribute
+}
+
+// UniqueAttributes returns the attributes of the given schema and schema extensions with a "server" or "global"
+// uniqueness. Only top-level attributes are returned, the uniqueness of sub-attributes is not checked.
+func UniqueAttributes(s schema.Schema, extensions ...schema.Schema) []UniqueAttribute {
+	var attributes []UniqueAttribute
+	for _, attr := range s.Attributes {
+		if attr.Uniqueness() != "none" {
+			attributes = append(attributes, UniqueAttribute{Attribute: attr})
+		}
+	}
+	for _, extension := range extensions {
+		for _, attr := range extension.Attributes {
+			if attr.Uniqueness() != "none" {
+				attributes = append(attributes, UniqueAttribute{
+					Extension: extension.ID,
+					Attribute: attr,
+				})
+			}
+		}
+	}
+	return attributes
+}
+
+// Equal reports whether the given values of the attribute are equal. Strings are compared case insensitive, unless
+// the attribute is case exact. Values of multi-valued attributes are equal if they have a value in common.
+func (a UniqueAttribute) Equal(v, w interface{}) bool {
+	vs, vok := v.([]interface{})
+	ws, wok := w.([]interface{})
+	if !a.Attribute.MultiValued() || !vok || !wok {
+		return equalUniqueValue(v, w, a.Attribute.CaseExact())
+	}
+	for _, v := range vs {
+		for _, w := range ws {
+			if equalUniqueValue(v, w, a.Attribute.CaseExact()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Path returns the attribute path of the attribute, e.g. "userName" or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber".
+func (a UniqueAttribute) Path() string {
+	if a.Extension == "" {
+		return a.Attribute.Name()
+	}
+	return a.Extension + ":" + a.Attribute.Name()
+}
+
+// Value returns the value of the attribute within the given resource attributes. Attributes of extensions are nested
+// within an object named after the extension's id. Attribute names are case insensitive.
+func (a UniqueAttribute) Value(attributes ResourceAttributes) (interface{}, bool) {
+	lookup := func(m map[string]interface{}, name string) interface{} {
+		if v, ok := m[name]; ok {
+			return v
+		}
+		for k, v := range m {
+			if strings.EqualFold(k, name) {
+				return v
+			}
+		}
+		return nil
+	}
+
+	m := map[string]interface{}(attributes)
+	if a.Extension != "" {
+		extension, ok := lookup(m, a.Extension).(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = extension
+	}
+	value := lookup(m, a.Attribute.Name())
+	if value == nil || isEmptyPatchValue(value) {
+		return nil, false
+	}
+	return value, true
+}
+
+// UniquenessChecker is an optional interface that can be implemented by a ResourceHandler to enforce the uniqueness of
+// attributes. If a handler implements this interface, the server looks up the value of every unique attribute, see
+// UniqueAttributes, before a resource is created, replaced or patched. Patched resources are only checked if one of
+// the operations targets a unique attribute and the request can be applied with ApplyPatch. A 409 uniqueness error naming the attribute is
+// returned if the value is already in use by another resource. A ContextResourceHandler implements
+// ContextUniquenessChecker instead.
+type UniquenessChecker interface {
+	// LookupUnique returns the identifiers of the resources that have the given value for the given attribute. Values
+	// should be compared with UniqueAttribute.Equal, so the "caseExact" characteristic of the attribute is respected.
+	// The scope of the lookup of attributes with a "global" uniqueness is up to the handler.
+	LookupUnique(r *http.Request, attribute UniqueAttribute, value interface{}) ([]string, error)
+}
@@ -0,0 +1,178 @@
+package scim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elimity-com/scim/errors"
+	"github.com/elimity-com/scim/optional"
+	"github.com/elimity-com/scim/schema"
+	"github.com/scim2/filter-parser/v2"
+)
+
+func TestServerUniqueness(t *testing.T) {
+	s := newTestServer()
+	s.ResourceTypes[0].Handler = testUniquenessHandler{
+		testResourceHandler: s.ResourceTypes[0].Handler.(testResourceHandler),
+	}
+
+	for _, test := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"create", http.MethodPost, "/v2/Users", `{"userName": "TEST01"}`, http.StatusConflict},
+		{"create unique", http.MethodPost, "/v2/Users", `{"userName": "test99"}`, http.StatusCreated},
+		{"replace", http.MethodPut, "/v2/Users/0002", `{"userName": "Test01"}`, http.StatusConflict},
+		{"replace itself", http.MethodPut, "/v2/Users/0001", `{"userName": "TEST01"}`, http.StatusOK},
+		{"patch", http.MethodPatch, "/v2/Users/0003", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "userName", "value": "test99"}]
+		}`, http.StatusConflict},
+		{"patch unique", http.MethodPatch, "/v2/Users/0003", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "userName", "value": "test98"}]
+		}`, http.StatusOK},
+		{"patch not applicable", http.MethodPatch, "/v2/Users/0003", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [
+				{"op": "replace", "path": "userName", "value": "test01"},
+				{"op": "replace", "path": "emails[value eq \"test97\"].display", "value": "Test"}
+			]
+		}`, http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+			assertEqualStatusCode(t, test.status, rr.Code)
+			if test.status == http.StatusConflict {
+				var scimErr errors.ScimError
+				assertUnmarshalNoError(t, scimErr.UnmarshalJSON(rr.Body.Bytes()))
+				expected := errors.ScimErrorNotUnique("userName")
+				assertEqualSCIMErrors(t, &expected, &scimErr)
+			}
+		})
+	}
+}
+
+func TestServerUniquenessExtension(t *testing.T) {
+	extension := schema.Schema{
+		ID:   "urn:ietf:params:scim:schemas:extension:badge:2.0:User",
+		Name: optional.NewString("Badge"),
+		Attributes: []schema.CoreAttribute{
+			schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+				CaseExact:  true,
+				Name:       "badgeId",
+				Uniqueness: schema.AttributeUniquenessGlobal(),
+			})),
+		},
+	}
+	s := Server{
+		ResourceTypes: []ResourceType{{
+			ID:               optional.NewString("User"),
+			Name:             "User",
+			Endpoint:         "/Users",
+			Schema:           getUserSchema(),
+			SchemaExtensions: []SchemaExtension{{Schema: extension}},
+			Handler: testUniquenessHandler{
+				testResourceHandler: testResourceHandler{data: map[string]testData{}},
+			},
+		}},
+	}
+
+	for _, test := range []struct {
+		userName string
+		badgeID  string
+		status   int
+	}{
+		{"alice", "B1", http.StatusCreated},
+		{"bob", "b1", http.StatusCreated},
+		{"carol", "B1", http.StatusConflict},
+	} {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/Users", strings.NewReader(`{
+			"userName": "`+test.userName+`",
+			"urn:ietf:params:scim:schemas:extension:badge:2.0:User": {"badgeId": "`+test.badgeID+`"}
+		}`)))
+		assertEqualStatusCode(t, test.status, rr.Code)
+		if test.status == http.StatusConflict {
+			assertTrue(t, strings.Contains(rr.Body.String(), extension.ID+":badgeId"))
+		}
+	}
+}
+
+func TestTouchesUniqueAttribute(t *testing.T) {
+	extension := "urn:ietf:params:scim:schemas:extension:badge:2.0:User"
+	attributes := []UniqueAttribute{
+		{Attribute: schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{Name: "userName"}))},
+		{Extension: extension, Attribute: schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+			Name: "badgeId",
+		}))},
+	}
+
+	for _, test := range []struct {
+		path    string
+		value   interface{}
+		touches bool
+	}{
+		{"UserName", "test", true},
+		{"urn:ietf:params:scim:schemas:core:2.0:User:userName", "test", true},
+		{extension + ":badgeId", "B1", true},
+		{extension, map[string]interface{}{"badgeId": "B1"}, true},
+		{"displayName", "test", false},
+		{`emails[value eq "test"].display`, "test", false},
+		{"", map[string]interface{}{"userName": "test"}, true},
+		{"", map[string]interface{}{extension: map[string]interface{}{}}, true},
+		{"", map[string]interface{}{"displayName": "test"}, false},
+	} {
+		op := PatchOperation{Op: PatchOperationReplace, Value: test.value}
+		if test.path != "" {
+			path, err := filter.ParsePath([]byte(test.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			op.Path = &path
+		}
+		if touchesUniqueAttribute(op, attributes) != test.touches {
+			t.Errorf("expected %v for %q with value %v", test.touches, test.path, test.value)
+		}
+	}
+}
+
+func TestUniqueAttributeEqual(t *testing.T) {
+	emails := UniqueAttribute{
+		Attribute: schema.SimpleCoreAttribute(schema.SimpleStringParams(schema.StringParams{
+			MultiValued: true,
+			Name:        "emails",
+			Uniqueness:  schema.AttributeUniquenessServer(),
+		})),
+	}
+	assertTrue(t, emails.Equal([]interface{}{"a@example.com", "b@example.com"}, []interface{}{"B@example.com"}))
+	assertTrue(t, !emails.Equal([]interface{}{"a@example.com"}, []interface{}{"b@example.com"}))
+	assertEqual(t, "emails", emails.Path())
+
+	value, ok := emails.Value(ResourceAttributes{"Emails": []interface{}{"a@example.com"}})
+	assertTrue(t, ok)
+	assertLen(t, value.([]interface{}), 1)
+	_, ok = emails.Value(ResourceAttributes{"emails": []interface{}{}})
+	assertTrue(t, !ok)
+}
+
+// testUniquenessHandler looks up unique values within the resources of the wrapped handler.
+type testUniquenessHandler struct {
+	testResourceHandler
+}
+
+func (h testUniquenessHandler) LookupUnique(_ *http.Request, attribute UniqueAttribute, value interface{}) ([]string, error) {
+	var ids []string
+	for id, data := range h.data {
+		if v, ok := attribute.Value(data.resourceAttributes); ok && attribute.Equal(value, v) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}